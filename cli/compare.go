@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"org.subh/api-term/pkgs/api/client"
+	"org.subh/api-term/pkgs/render"
+	"org.subh/api-term/pkgs/respdiff"
+)
+
+// Baseline is a response the current one is compared with: a pinned earlier
+// response, or the same request sent to another base URL
+type Baseline struct {
+	Label    string
+	Response *client.Response
+	Rendered *render.Result
+}
+
+// pinResponse keeps the current response as the baseline for diffs
+func (h *MainHandler) pinResponse() {
+	h.Baseline = &Baseline{
+		Label:    fmt.Sprintf("%s %s pinned at %s", h.LastCall.Method, h.LastCall.Path, time.Now().Format("15:04:05")),
+		Response: h.Response,
+		Rendered: h.Rendered,
+	}
+	h.showResponse()
+	h.Output.Title = "Response (pinned, d to diff later responses against it)"
+}
+
+// compareWith sends the last request to baseURL and shows the diff between
+// that response and the current one
+func (h *MainHandler) compareWith(baseURL string) {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if baseURL == "" || h.LastCall == nil {
+		return
+	}
+	for _, ep := range h.Endpoints {
+		if ep.Method != h.LastCall.Method || ep.Path != h.LastCall.Path {
+			continue
+		}
+		if ep.GRPC != "" || ep.GraphQL != "" {
+			h.Output.Title = "Response (gRPC and GraphQL calls can only be compared with a pinned response)"
+			return
+		}
+		resp, err := client.Invoke(baseURL, ep, h.LastCall.Params, h.LastCall.Headers, h.LastCall.Body, h.LastCall.ContentType)
+		if err != nil {
+			h.Output.Title = "Response (compare failed: " + err.Error() + ")"
+			return
+		}
+		h.Baseline = &Baseline{
+			Label:    baseURL,
+			Response: resp,
+			Rendered: render.Body(resp.Header.Get("Content-Type"), []byte(resp.Body)),
+		}
+		h.ShowCompare = true
+		h.Output.SelectedRow = 0
+		h.showResponse()
+		return
+	}
+}
+
+// compareRows renders the diff between the baseline and the current
+// response: changed paths for JSON bodies, side-by-side lines otherwise
+func (h *MainHandler) compareRows() []string {
+	old, cur := h.Baseline, h.Response
+	rows := []string{"Comparing " + old.Label + " (left/old) with the current response (right/new)"}
+	if old.Response.StatusCode != cur.StatusCode {
+		rows = append(rows, fmt.Sprintf("[Status %d → %d](fg:yellow)", old.Response.StatusCode, cur.StatusCode))
+	} else {
+		rows = append(rows, fmt.Sprintf("Status %d on both", cur.StatusCode))
+	}
+
+	if old.Rendered.JSON != "" && h.Rendered.JSON != "" {
+		var a, b interface{}
+		if json.Unmarshal([]byte(old.Rendered.JSON), &a) == nil && json.Unmarshal([]byte(h.Rendered.JSON), &b) == nil {
+			result := respdiff.JSON(a, b, h.DiffIgnore)
+			summary := fmt.Sprintf("%d %s", len(result.Changes), plural(len(result.Changes), "change"))
+			if result.Ignored > 0 {
+				summary += fmt.Sprintf(", %d ignored", result.Ignored)
+			}
+			rows = append(rows, summary, "")
+			if len(result.Changes) == 0 {
+				rows = append(rows, "[Bodies are equal](fg:green)")
+			}
+			for _, c := range result.Changes {
+				switch c.Kind {
+				case respdiff.Changed:
+					rows = append(rows, fmt.Sprintf("[~](fg:yellow) %s: %s → %s", c.Path, respdiff.Summary(c.Old, 60), respdiff.Summary(c.New, 60)))
+				case respdiff.Added:
+					rows = append(rows, fmt.Sprintf("[+](fg:green) %s: %s", c.Path, respdiff.Summary(c.New, 120)))
+				case respdiff.Removed:
+					rows = append(rows, fmt.Sprintf("[-](fg:red) %s: %s", c.Path, respdiff.Summary(c.Old, 120)))
+				}
+			}
+			return rows
+		}
+	}
+
+	lines := respdiff.Text(old.Rendered.Text, h.Rendered.Text)
+	changed := 0
+	for _, l := range lines {
+		if l.Kind != ' ' {
+			changed++
+		}
+	}
+	rows = append(rows, fmt.Sprintf("%d %s differ", changed, plural(changed, "line")), "")
+	width := h.Output.Inner.Dx()
+	if width < 20 {
+		width = 80
+	}
+	col := (width - 5) / 2
+	for _, l := range lines {
+		marker := " "
+		switch l.Kind {
+		case '~':
+			marker = "[~](fg:yellow)"
+		case '-':
+			marker = "[-](fg:red)"
+		case '+':
+			marker = "[+](fg:green)"
+		}
+		left := runewidth.FillRight(runewidth.Truncate(expandTabs(l.Old), col, "…"), col)
+		right := runewidth.Truncate(expandTabs(l.New), col, "…")
+		rows = append(rows, marker+" "+left+" │ "+right)
+	}
+	return rows
+}
+
+func expandTabs(s string) string {
+	return strings.ReplaceAll(s, "\t", "    ")
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+
+	ui "github.com/gizak/termui/v3"
+	"org.subh/api-term/pkgs/api/model"
+	"org.subh/api-term/pkgs/api/parser"
+)
+
+// selectedEndpoint returns the endpoint selected in the list, or nil when no
+// endpoint could be loaded
+func (h *MainHandler) selectedEndpoint() *model.Endpoint {
+	if h.List.SelectedRow < 0 || h.List.SelectedRow >= len(h.Endpoints) {
+		return nil
+	}
+	return h.Endpoints[h.List.SelectedRow]
+}
+
+// diagnosticsTitle counts the errors and warnings
+func diagnosticsTitle(diags []parser.Diagnostic) string {
+	errors := 0
+	for _, d := range diags {
+		if d.Severity == parser.SeverityError {
+			errors++
+		}
+	}
+	warnings := len(diags) - errors
+	return fmt.Sprintf("Diagnostics · %d %s, %d %s (L to close)", errors, plural(errors, "error"), warnings, plural(warnings, "warning"))
+}
+
+// diagnosticRows lists each problem with its source, JSON pointer and
+// message, or says that the specs loaded cleanly
+func diagnosticRows(diags []parser.Diagnostic, endpoints int) []string {
+	if len(diags) == 0 {
+		return []string{
+			"[No problems found loading the specs](fg:green)",
+			fmt.Sprintf("%d %s loaded", endpoints, plural(endpoints, "endpoint")),
+		}
+	}
+	var rows []string
+	if endpoints == 0 {
+		rows = append(rows, "[No endpoints could be loaded. Check the --file and --url flags.](fg:red)", "")
+	}
+	for _, d := range diags {
+		label := "[ERROR](fg:red)"
+		if d.Severity == parser.SeverityWarning {
+			label = "[WARN](fg:yellow)"
+		}
+		rows = append(rows, label+" "+d.Source)
+		if d.Pointer != "" {
+			rows = append(rows, "  at #"+d.Pointer)
+		}
+		rows = append(rows, "  "+d.Message, "")
+	}
+	return rows
+}
+
+// toggleDiagnostics shows or hides the diagnostics pane in place of the
+// Response pane
+func (h *MainHandler) toggleDiagnostics(show bool) {
+	h.ShowDiagnostics = show
+	if show {
+		h.ShowDrift, h.ShowDiff, h.ShowHistory = false, false, false
+		h.DiagnosticsWidget.Title = diagnosticsTitle(h.Diagnostics)
+		h.DiagnosticsWidget.Rows = diagnosticRows(h.Diagnostics, len(h.Endpoints))
+		h.DiagnosticsWidget.SelectedRow = 0
+		h.FocusMode = "diagnostics"
+		h.List.TitleStyle = ui.NewStyle(ui.ColorWhite)
+		h.List.BorderStyle.Fg = ui.ColorWhite
+	} else {
+		h.FocusMode = "list"
+		h.List.TitleStyle = ui.NewStyle(ui.ColorYellow)
+		h.List.BorderStyle.Fg = ui.ColorYellow
+	}
+}
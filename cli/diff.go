@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"org.subh/api-term/pkgs/api/parser"
+	"org.subh/api-term/pkgs/specdiff"
+)
+
+// runDiff implements `api-term diff old.yaml new.yaml` and returns the exit code
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fs.String("format", "text", "output format: text or json")
+	breakingOnly := fs.Bool("breaking-only", false, "only list breaking changes")
+	failOnBreaking := fs.Bool("fail-on-breaking", false, "exit with status 1 when breaking changes are found")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: api-term diff [flags] old.yaml new.yaml")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	oldDoc, diags, err := parser.LoadDocument(fs.Arg(0))
+	printDiagnostics(diags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load %s: %v\n", fs.Arg(0), err)
+		return 2
+	}
+	newDoc, diags, err := parser.LoadDocument(fs.Arg(1))
+	printDiagnostics(diags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load %s: %v\n", fs.Arg(1), err)
+		return 2
+	}
+
+	result := specdiff.Compare(oldDoc, newDoc)
+	if *breakingOnly {
+		var breaking []specdiff.Change
+		for _, c := range result.Changes {
+			if c.Breaking {
+				breaking = append(breaking, c)
+			}
+		}
+		result.Changes = breaking
+	}
+
+	switch *format {
+	case "text":
+		for _, line := range formatDiffRows(result) {
+			fmt.Println(line)
+		}
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(result)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown format: %s\n", *format)
+		return 2
+	}
+
+	if *failOnBreaking && result.Breaking() > 0 {
+		return 1
+	}
+	return 0
+}
+
+func formatDiffRows(result *specdiff.Result) []string {
+	if len(result.Changes) == 0 {
+		return []string{"No changes"}
+	}
+	rows := make([]string, 0, len(result.Changes)+2)
+	for _, c := range result.Changes {
+		rows = append(rows, c.String())
+	}
+	return append(rows, "", fmt.Sprintf("%d changes, %d breaking", len(result.Changes), result.Breaking()))
+}
+
+// diffMarker returns the endpoint list prefix for an operation's diff status
+func diffMarker(status string) string {
+	switch status {
+	case "added":
+		return "[+](fg:green) "
+	case "breaking":
+		return "[!](fg:red) "
+	case "changed":
+		return "[~](fg:cyan) "
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"org.subh/api-term/pkgs/api/client"
+	"org.subh/api-term/pkgs/api/model"
+	"org.subh/api-term/pkgs/api/parser"
+	"org.subh/api-term/pkgs/config"
+	"org.subh/api-term/pkgs/drift"
+	"org.subh/api-term/pkgs/proxy"
+)
+
+// runDrift implements `api-term drift cassette.json...`, comparing recorded
+// traffic with the spec, and returns the exit code
+func runDrift(args []string) int {
+	fs := flag.NewFlagSet("drift", flag.ExitOnError)
+	fileFlag := fs.String("file", config.DefaultOpenAPIFile, "path to OpenAPI file")
+	var urlFlags stringSlice
+	fs.Var(&urlFlags, "url", "URL to OpenAPI spec (can be repeated)")
+	format := fs.String("format", "md", "report format: md or json")
+	outFile := fs.String("out", "", "write the report to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: api-term drift [flags] cassette.json...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	docs, diags := parser.LoadDocuments([]string{*fileFlag}, urlFlags)
+	printDiagnostics(diags)
+	if len(docs) == 0 {
+		fmt.Fprintln(os.Stderr, "No spec could be loaded")
+		return 2
+	}
+	collector := drift.NewCollector(docs...)
+	for _, path := range fs.Args() {
+		cassette, err := proxy.LoadCassette(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load cassette %s: %v\n", path, err)
+			return 2
+		}
+		observeCassette(collector, cassette)
+	}
+
+	var out io.Writer = os.Stdout
+	if *outFile != "" {
+		f, err := os.Create(*outFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create %s: %v\n", *outFile, err)
+			return 2
+		}
+		defer f.Close()
+		out = f
+	}
+
+	report := collector.Report()
+	var err error
+	switch *format {
+	case "md":
+		err = report.WriteMarkdown(out)
+	case "json":
+		err = report.WriteJSON(out)
+	default:
+		err = fmt.Errorf("unknown report format: %s", *format)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+		return 2
+	}
+	return 0
+}
+
+// observeCassette feeds the recorded responses to the drift collector
+func observeCassette(collector *drift.Collector, cassette *proxy.Cassette) {
+	for _, ex := range cassette.Exchanges {
+		collector.Observe(ex.Request.Method, ex.Request.Path, ex.Response.Status, ex.Response.Header, ex.Response.Body)
+	}
+}
+
+// loadDriftHistory reads the responses of earlier sessions, or starts an
+// empty history when the file doesn't exist yet
+func loadDriftHistory(path string) (*proxy.Cassette, error) {
+	cassette, err := proxy.LoadCassette(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &proxy.Cassette{}, nil
+	}
+	return cassette, err
+}
+
+// recordDriftHistory appends a response to the drift history file. The path
+// is kept as the spec template, as the live collector sees it, since the
+// base URL may add a prefix the spec doesn't know about.
+func (h *MainHandler) recordDriftHistory(ep *model.Endpoint, headerValues map[string]string, body string, resp *client.Response) {
+	if h.DriftHistory == nil {
+		return
+	}
+	ex := &proxy.Exchange{
+		Operation:  strings.ToUpper(ep.Method) + " " + ep.Path,
+		Request:    proxy.RecordedRequest{Method: strings.ToUpper(ep.Method), Path: ep.Path, Body: body, Header: http.Header{}},
+		Response:   proxy.RecordedResponse{Status: resp.StatusCode, Header: resp.Header, Body: resp.Body},
+		RecordedAt: time.Now().Add(-resp.Duration),
+		Duration:   resp.Duration,
+	}
+	for k, v := range headerValues {
+		ex.Request.Header.Set(k, v)
+	}
+	h.DriftHistory.Record(ex)
+	h.DriftHistoryErr = h.DriftHistory.Save(h.Config.DriftHistory)
+}
+
+// driftRows is the drift report, with a note on where its responses came from
+func (h *MainHandler) driftRows() []string {
+	rows := h.Drift.Report().MarkdownLines()
+	if h.DriftHistory == nil {
+		return append([]string{"[Responses from this session only (--drift-history keeps them across sessions)](fg:cyan)", ""}, rows...)
+	}
+	note := fmt.Sprintf("[%d responses from %s](fg:cyan)", len(h.DriftHistory.Exchanges), h.Config.DriftHistory)
+	if h.DriftHistoryErr != nil {
+		note = "[Failed to save " + h.Config.DriftHistory + ": " + h.DriftHistoryErr.Error() + "](fg:red)"
+	}
+	return append([]string{note, ""}, rows...)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	ui "github.com/gizak/termui/v3"
+	"org.subh/api-term/pkgs/api/client"
+	"org.subh/api-term/pkgs/api/model"
+	"org.subh/api-term/pkgs/graphql"
+)
+
+// graphqlURL returns where GraphQL requests go: the --graphql URL, or its
+// path under the Base URL when it has no host
+func (h *MainHandler) graphqlURL() string {
+	u := h.Config.GraphQLURL
+	if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+		return u
+	}
+	return strings.TrimRight(h.BaseURL, "/") + "/" + strings.TrimLeft(u, "/")
+}
+
+// introspect loads the GraphQL schema with the current headers and lists
+// its operations in place of the previous ones
+func (h *MainHandler) introspect() error {
+	body, err := graphql.Request(graphql.IntrospectionQuery, "")
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do("POST", h.graphqlURL(), h.headerValues(), body, "application/json")
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("introspection returned status %d", resp.StatusCode)
+	}
+	schema, err := graphql.Parse([]byte(resp.Body))
+	if err != nil {
+		return err
+	}
+	h.GraphQL = schema
+	h.GraphQLQuery, h.GraphQLFor = "", nil
+
+	var endpoints []*model.Endpoint
+	var rows []string
+	for i, ep := range h.Endpoints {
+		if ep.GraphQL == "" {
+			endpoints = append(endpoints, ep)
+			rows = append(rows, h.List.Rows[i])
+		}
+	}
+	// GraphQL operations go before the webhooks, which stay last
+	at := len(endpoints)
+	for i, ep := range endpoints {
+		if ep.Webhook != "" {
+			at = i
+			break
+		}
+	}
+	var ops []*model.Endpoint
+	var opRows []string
+	for _, op := range schema.Operations() {
+		ep := &model.Endpoint{Method: "POST", Path: op.Field.Name, GraphQL: op.Type}
+		ops = append(ops, ep)
+		opRows = append(opRows, formatEndpointRow(ep))
+	}
+	h.Endpoints = append(endpoints[:at:at], append(ops, endpoints[at:]...)...)
+	h.List.Rows = append(rows[:at:at], append(opRows, rows[at:]...)...)
+	if h.List.SelectedRow >= len(h.List.Rows) {
+		h.List.SelectedRow = max(len(h.List.Rows)-1, 0)
+	}
+	return nil
+}
+
+// showIntrospection runs introspection and reports the outcome in the
+// Response pane
+func (h *MainHandler) showIntrospection() {
+	h.Response, h.Rendered = nil, nil
+	h.Output.SelectedRow = 0
+	if err := h.introspect(); err != nil {
+		h.Output.Title = "GraphQL (introspection failed)"
+		h.Output.Rows = []string{
+			"Introspection of " + h.graphqlURL() + " failed: " + err.Error(),
+			"Set headers with H and press I to retry",
+		}
+		h.Output.BorderStyle.Fg = ui.ColorRed
+		return
+	}
+	h.Output.Title = "GraphQL"
+	h.Output.Rows = []string{fmt.Sprintf("Loaded %d operations from %s", len(h.GraphQL.Operations()), h.graphqlURL())}
+	h.Output.BorderStyle.Fg = ui.ColorGreen
+}
+
+// graphqlOperation returns the schema operation behind ep
+func (h *MainHandler) graphqlOperation(ep *model.Endpoint) (graphql.Operation, bool) {
+	if h.GraphQL == nil {
+		return graphql.Operation{}, false
+	}
+	for _, op := range h.GraphQL.Operations() {
+		if op.Type == ep.GraphQL && op.Field.Name == ep.Path {
+			return op, true
+		}
+	}
+	return graphql.Operation{}, false
+}
+
+// graphqlQuery returns the query edited for ep, or a generated skeleton
+func (h *MainHandler) graphqlQuery(ep *model.Endpoint) string {
+	if h.GraphQLFor == ep && h.GraphQLQuery != "" {
+		return h.GraphQLQuery
+	}
+	if op, ok := h.graphqlOperation(ep); ok {
+		return h.GraphQL.Query(op)
+	}
+	return ""
+}
+
+// graphqlVariables returns placeholder variables for the required arguments
+// of ep, or "" when ep is not a GraphQL operation
+func (h *MainHandler) graphqlVariables(ep *model.Endpoint) string {
+	if op, ok := h.graphqlOperation(ep); ok {
+		return h.GraphQL.Variables(op)
+	}
+	return ""
+}
+
+// invokeGraphQL sends the query for ep with the body as its variables
+// through the HTTP client. Subscriptions ask for an event stream, which
+// servers implementing GraphQL over SSE answer with.
+func (h *MainHandler) invokeGraphQL(ep *model.Endpoint, headerValues map[string]string) {
+	variables := h.BodyInput
+	if strings.TrimSpace(variables) == "" {
+		variables = h.graphqlVariables(ep)
+	}
+	body, err := graphql.Request(h.graphqlQuery(ep), variables)
+	if err != nil {
+		h.showResult(ep, nil, headerValues, h.BodyInput, "application/json", nil, err)
+		return
+	}
+	if ep.GraphQL == "subscription" {
+		if _, ok := headerValues["Accept"]; !ok {
+			headerValues["Accept"] = "text/event-stream"
+		}
+	}
+	h.invoke(ep, nil, headerValues, body, "application/json")
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"org.subh/api-term/pkgs/api/client"
+	"org.subh/api-term/pkgs/api/model"
+	"org.subh/api-term/pkgs/grpcclient"
+)
+
+// grpcTimeout bounds reflection and calls that don't stream responses
+const grpcTimeout = 30 * time.Second
+
+// grpcEndpoints lists the methods of c as endpoints, with the full method
+// name as the path
+func grpcEndpoints(c *grpcclient.Client) []*model.Endpoint {
+	var endpoints []*model.Endpoint
+	for _, m := range c.Methods() {
+		endpoints = append(endpoints, &model.Endpoint{
+			Method: "GRPC",
+			Path:   m.FullName(),
+			GRPC:   m.Kind(),
+		})
+	}
+	return endpoints
+}
+
+// grpcTemplate returns a request message template for ep, or "" when ep is
+// not a gRPC method
+func (h *MainHandler) grpcTemplate(ep *model.Endpoint) string {
+	if h.GRPC == nil || ep.GRPC == "" {
+		return ""
+	}
+	m := h.GRPC.Method(ep.Path)
+	if m == nil {
+		return ""
+	}
+	return h.GRPC.Template(m)
+}
+
+// invokeGRPC calls the gRPC method behind ep with the body as its request
+// message and the headers as metadata. Streaming responses are shown like
+// NDJSON streams, one event per message.
+func (h *MainHandler) invokeGRPC(ep *model.Endpoint, headerValues map[string]string, body string) {
+	h.stopStream()
+	h.closeSession()
+	m := h.GRPC.Method(ep.Path)
+	resp := &client.Response{
+		Header: http.Header{"Content-Type": {"application/json"}},
+		URL:    h.GRPC.Target + ep.Path,
+	}
+	start := time.Now()
+
+	if m.Desc.IsStreamingServer() {
+		ctx, cancel := context.WithCancel(context.Background())
+		reader, writer := io.Pipe()
+		resp.StatusCode = http.StatusOK
+		resp.Header.Set("Content-Type", "application/x-ndjson")
+		go func() {
+			_, _, err := h.GRPC.Call(ctx, m, body, headerValues, func(msg string) {
+				var line bytes.Buffer
+				if json.Compact(&line, []byte(msg)) == nil {
+					line.WriteByte('\n')
+					writer.Write(line.Bytes())
+				}
+			})
+			writer.CloseWithError(err)
+		}()
+		h.startStream(ep, nil, headerValues, body, "application/json", resp, reader, cancel)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), grpcTimeout)
+	defer cancel()
+	var messages []string
+	header, trailer, err := h.GRPC.Call(ctx, m, body, headerValues, func(msg string) {
+		messages = append(messages, msg)
+	})
+	resp.Duration = time.Since(start)
+	addMetadata(resp.Header, header)
+	addMetadata(resp.Header, trailer)
+	st, isStatus := status.FromError(err)
+	if err != nil && !isStatus {
+		h.showResult(ep, nil, headerValues, body, "application/json", nil, err)
+		return
+	}
+	resp.StatusCode = grpcclient.HTTPStatus(st.Code())
+	resp.Header.Set("Grpc-Status", st.Code().String())
+	switch {
+	case err != nil:
+		data, _ := json.MarshalIndent(map[string]string{"code": st.Code().String(), "message": st.Message()}, "", "  ")
+		resp.Body = string(data)
+	case len(messages) == 1:
+		resp.Body = messages[0]
+	default:
+		resp.Body = "[" + strings.Join(messages, ",\n") + "]"
+	}
+	h.showResult(ep, nil, headerValues, body, "application/json", resp, nil)
+}
+
+// addMetadata adds gRPC response metadata to the response headers
+func addMetadata(header http.Header, md metadata.MD) {
+	for k, values := range md {
+		for _, v := range values {
+			header.Add(k, v)
+		}
+	}
+}
@@ -4,18 +4,39 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	ui "github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
-	"google.golang.org/genai"
 	"org.subh/api-term/pkgs/ai"
 	"org.subh/api-term/pkgs/api/client"
 	"org.subh/api-term/pkgs/api/model"
 	"org.subh/api-term/pkgs/api/parser"
 	"org.subh/api-term/pkgs/config"
+	"org.subh/api-term/pkgs/drift"
+	"org.subh/api-term/pkgs/graphql"
+	"org.subh/api-term/pkgs/grpcclient"
+	"org.subh/api-term/pkgs/jsontree"
+	"org.subh/api-term/pkgs/paginate"
+	"org.subh/api-term/pkgs/proxy"
+	"org.subh/api-term/pkgs/query"
+	"org.subh/api-term/pkgs/redact"
+	"org.subh/api-term/pkgs/render"
+	"org.subh/api-term/pkgs/respdiff"
+	"org.subh/api-term/pkgs/specdiff"
+	"org.subh/api-term/pkgs/stream"
+	"org.subh/api-term/pkgs/textsearch"
 	"org.subh/api-term/pkgs/tui"
+	"org.subh/api-term/pkgs/wsclient"
 )
 
 // stringSlice implements flag.Value for multiple flags
@@ -31,6 +52,21 @@ func (s *stringSlice) Set(value string) error {
 }
 
 func formatEndpointRow(ep *model.Endpoint) string {
+	if ep.GraphQL != "" {
+		return "[GRAPHQL](fg:magenta) " + ep.GraphQL + " " + ep.Path
+	}
+	if ep.GRPC != "" {
+		if ep.GRPC == "unary" {
+			return "[GRPC](fg:blue) " + ep.Path
+		}
+		return "[GRPC](fg:blue) " + ep.Path + " (" + ep.GRPC + ")"
+	}
+	if ep.Webhook != "" {
+		return "[WEBHOOK](fg:magenta) " + ep.Method + " " + ep.Webhook
+	}
+	if ep.WebSocket {
+		return ep.Method + " " + ep.Path + " (WebSocket)"
+	}
 	var queryParams []string
 	for _, p := range ep.Parameters {
 		if p.In == "query" {
@@ -45,6 +81,7 @@ func formatEndpointRow(ep *model.Endpoint) string {
 
 type MainHandler struct {
 	Endpoints         []*model.Endpoint
+	Docs              []*openapi3.T
 	GlobalQueryParams map[string]string
 	Config            *config.Config
 
@@ -74,21 +111,149 @@ type MainHandler struct {
 	TermWidth        int
 	TermHeight       int
 
+	// Response State
+	Response *client.Response
+	Rendered *render.Result
+	ShowTree bool
+	Tree     *jsontree.Tree
+	// Filter is the jq/JSONPath expression applied to the body while
+	// ShowFiltered is on; the original body is kept for toggling back
+	Filter       string
+	ShowFiltered bool
+	Filtered     string
+	FilterErr    string
+	QueryBar     *widgets.Paragraph
+
+	// Search State: Search is highlighted in the Response pane and n/N
+	// move between its Matches
+	Search      *regexp.Regexp
+	SearchQuery string
+	SearchFrom  int
+	Matches     []textsearch.Match
+	MatchIndex  int
+
+	// Response Diff State
+	Baseline    *Baseline
+	ShowCompare bool
+	DiffIgnore  *respdiff.Ignore
+
+	// Pagination State: Pages are the requests of the pages visited since
+	// the last invoke, the current page last
+	Pagination   paginate.Config
+	PageRule     *paginate.Rule
+	PageEndpoint *model.Endpoint
+	Pages        []*paginate.Request
+	AllPages     string
+	Fetching     bool
+
+	// Stream State: Events of a streaming response are shown as they
+	// arrive until the stream ends or StreamCancel stops it
+	StreamAll    bool
+	ShowStream   bool
+	Events       []streamEvent
+	StreamCancel context.CancelFunc
+	StreamStatus string
+
+	// WebSocket State: the session log replaces the Response pane while
+	// ShowSession is set, and Frame is the last frame sent
+	Session         *wsclient.Session
+	SessionEndpoint *model.Endpoint
+	ShowSession     bool
+	Frame           string
+
+	// GraphQL State: the schema from --graphql introspection, and the query
+	// edited with Q for GraphQLFor
+	GraphQL      *graphql.Schema
+	GraphQLQuery string
+	GraphQLFor   *model.Endpoint
+
+	// GRPC is the connection to the --grpc server, whose methods are listed
+	// as GRPC endpoints
+	GRPC *grpcclient.Client
+
 	// Gemini State
 	ShowGemini   bool
 	GeminiZoomed bool
 	GeminiWidget *widgets.List
 	GeminiInput  *widgets.Paragraph
-	GeminiChat   *genai.Chat
+	GeminiChat   ai.Session
+	GeminiCancel context.CancelFunc
 	GeminiQuery  string
 	GeminiCtx    context.Context
+	LastCall     *ai.CallContext
+	Composer     *ai.Composer
+	Proposal     []*ai.ProposedRequest
+	Redactor     *redact.Redactor
+	PendingSend  func()
+
+	// Transcript State
+	Assistant     ai.Assistant
+	Transcript    *ai.Transcript
+	Transcripts   *ai.TranscriptStore
+	ShowHistory   bool
+	HistoryWidget *widgets.List
+	HistoryItems  []*ai.Transcript
+
+	// Diagnostics State: problems found loading the specs
+	ShowDiagnostics   bool
+	Diagnostics       []parser.Diagnostic
+	DiagnosticsWidget *widgets.List
+
+	// Drift State
+	ShowDrift   bool
+	Drift       *drift.Collector
+	DriftWidget *widgets.List
+	// DriftHistory holds the responses of earlier sessions when
+	// --drift-history is set; DriftHistoryErr is the last failure to save it
+	DriftHistory    *proxy.Cassette
+	DriftHistoryErr error
+
+	// Spec Diff State
+	ShowDiff   bool
+	DiffWidget *widgets.List
+
+	// mu is held while handling an event or drawing, so streams and
+	// WebSocket sessions can update the Response pane from their readers
+	mu sync.Mutex
 }
 
-func NewMainHandler(cfg *config.Config, endpoints []*model.Endpoint) *MainHandler {
+func NewMainHandler(cfg *config.Config, endpoints []*model.Endpoint, docs []*openapi3.T, diags []parser.Diagnostic) *MainHandler {
+	diffWidget := widgets.NewList()
+	diffWidget.Title = "Spec Diff (V to close)"
+	diffWidget.Rows = []string{"Start with --diff-against old.yaml to compare specs"}
+	diffWidget.WrapText = true
+	diffWidget.TextStyle = ui.NewStyle(ui.ColorWhite)
+	diffWidget.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorYellow)
+	diffWidget.BorderStyle.Fg = ui.ColorYellow
+
+	diffStatus := map[string]string{}
+	if cfg.DiffAgainst != "" && len(docs) > 0 {
+		oldDoc, oldDiags, err := parser.LoadDocument(cfg.DiffAgainst)
+		diags = append(diags, oldDiags...)
+		if err != nil {
+			diffWidget.Rows = []string{"[Failed to load " + cfg.DiffAgainst + ": " + err.Error() + "](fg:red)"}
+		} else {
+			result := specdiff.Compare(oldDoc, docs[0])
+			diffWidget.Title = "Spec Diff against " + cfg.DiffAgainst + " (V to close)"
+			diffWidget.Rows = formatDiffRows(result)
+			diffStatus = result.OperationStatus()
+		}
+	}
+
 	list := widgets.NewList()
 	list.Title = "API Endpoints (j/k to scroll, ENTER to select)"
+	webhooks := 0
 	for _, ep := range endpoints {
-		list.Rows = append(list.Rows, formatEndpointRow(ep))
+		if ep.Webhook != "" {
+			webhooks++
+		}
+		list.Rows = append(list.Rows, diffMarker(diffStatus[ep.Method+" "+ep.Path])+formatEndpointRow(ep))
+	}
+	if webhooks > 0 {
+		list.Title += fmt.Sprintf(" · %d webhooks below", webhooks)
+	}
+	if len(endpoints) == 0 {
+		list.Title = "API Endpoints · none loaded (L for diagnostics)"
 	}
 	list.SelectedRow = 0
 	list.TextStyle = ui.NewStyle(ui.ColorYellow)
@@ -97,7 +262,7 @@ func NewMainHandler(cfg *config.Config, endpoints []*model.Endpoint) *MainHandle
 	list.BorderStyle.Fg = ui.ColorYellow
 
 	output := widgets.NewList()
-	output.Title = "Response (Tab/r to focus, j/k to scroll)"
+	output.Title = "Response (Tab/r to focus, j/k to scroll, t for tree)"
 	output.Rows = []string{"Press ENTER to invoke endpoint"}
 	output.WrapText = true
 	output.TextStyle = ui.NewStyle(ui.ColorWhite)
@@ -139,20 +304,44 @@ func NewMainHandler(cfg *config.Config, endpoints []*model.Endpoint) *MainHandle
 	  k / <Up>     Scroll Up
 	  Enter        Select Endpoint / Invoke
 	  i            Focus Input
+	  t            Toggle the JSON tree view (Response focused)
+	               Enter/Space fold, Left/Right, +/- all, p/c copy pointer/value
+	  f            Filter the response with jq or JSONPath (Response focused)
+	  F            Toggle between the filtered and original response
+	  s            Save the response body to a file (Response focused)
+	  /            Search the response (regex); n/N next/previous match
+	  :            Jump to a line of the response
+	  P            Pin the response as the diff baseline
+	  E            Send the request to another base URL and diff
+	  d            Toggle the diff against the pinned/other response
+	  ] / [        Next/previous page of a paginated response
+	  S            Stream every response line by line (x stops a stream)
+	  W            Open a WebSocket session (Enter on /ws endpoints)
+	  m / X        Send a WebSocket frame / close the session
+	  Q            Edit the GraphQL query of the selected operation
+	  I            Re-run GraphQL introspection with the current headers
+	  }            Fetch all pages (up to a limit) into one list
 	  b            Edit Base URL
 	  H            Edit Headers
-	  B            Edit Body
+	  B            Edit Body (a message template for gRPC methods)
 	  C            Edit Content-Type
-	  g            Toggle Gemini Insights (Tab to focus Gemini/Output)
-	  G            Chat with Gemini
-	  Z            Zoom/Fullscreen Gemini Insights
+	  g            Toggle AI Insights (Tab to focus AI/Output)
+	  G            Chat with the AI assistant
+	  A            Describe requests for the AI to compose (y/n to confirm)
+	  Z            Zoom/Fullscreen AI Insights
+	  x            Stop the response stream or the AI answer
+	  e            Export the AI conversation to Markdown (AI focused)
+	  T            Browse saved AI conversations
+	  D            Toggle Spec Drift Report (e to export)
+	  V            Toggle Spec Diff (--diff-against)
+	  L            Toggle the diagnostics of loading the specs
 	  ? / h        Toggle Help
 	  q / <C-c>    Quit
 	`
 	help.BorderStyle.Fg = ui.ColorYellow
 
 	geminiWidget := widgets.NewList()
-	geminiWidget.Title = "Gemini Insights"
+	geminiWidget.Title = "AI Insights (" + aiProviderName(cfg) + ")"
 	geminiWidget.Rows = []string{}
 	geminiWidget.WrapText = true
 	geminiWidget.TextStyle = ui.NewStyle(ui.ColorCyan)
@@ -160,12 +349,41 @@ func NewMainHandler(cfg *config.Config, endpoints []*model.Endpoint) *MainHandle
 	geminiWidget.BorderStyle.Fg = ui.ColorCyan
 
 	geminiInput := widgets.NewParagraph()
-	geminiInput.Title = "Gemini Chat (Press 'G' to ask)"
+	geminiInput.Title = "AI Chat (Press 'G' to ask)"
 	geminiInput.Text = ""
 	geminiInput.BorderStyle.Fg = ui.ColorMagenta
 
+	diagnosticsWidget := widgets.NewList()
+	diagnosticsWidget.WrapText = true
+	diagnosticsWidget.TextStyle = ui.NewStyle(ui.ColorWhite)
+	diagnosticsWidget.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorYellow)
+	diagnosticsWidget.BorderStyle.Fg = ui.ColorYellow
+
+	driftWidget := widgets.NewList()
+	driftWidget.Title = "Spec Drift (D to close, e to export)"
+	driftWidget.Rows = []string{}
+	driftWidget.WrapText = true
+	driftWidget.TextStyle = ui.NewStyle(ui.ColorWhite)
+	driftWidget.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorYellow)
+	driftWidget.BorderStyle.Fg = ui.ColorYellow
+
+	historyWidget := widgets.NewList()
+	historyWidget.Title = "AI Transcripts (Enter to reopen, e to export, T to close)"
+	historyWidget.Rows = []string{}
+	historyWidget.WrapText = false
+	historyWidget.TextStyle = ui.NewStyle(ui.ColorWhite)
+	historyWidget.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorCyan)
+	historyWidget.BorderStyle.Fg = ui.ColorCyan
+
+	queryBar := widgets.NewParagraph()
+	queryBar.Title = "Filter (jq or JSONPath, Enter to keep)"
+	queryBar.BorderStyle.Fg = ui.ColorYellow
+
 	h := &MainHandler{
 		Endpoints:         endpoints,
+		Docs:              docs,
+		Composer:          ai.NewComposer(endpoints, docs),
+		Redactor:          redact.Default(),
 		GlobalQueryParams: cfg.GlobalQueryParams,
 		Config:            cfg,
 		List:              list,
@@ -184,6 +402,15 @@ func NewMainHandler(cfg *config.Config, endpoints []*model.Endpoint) *MainHandle
 		InputValues:       make(map[string]string),
 		HeaderValues:      make(map[string]string),
 		GeminiCtx:         context.Background(),
+		Drift:             drift.NewCollector(docs...),
+		DriftWidget:       driftWidget,
+		Diagnostics:       diags,
+		DiagnosticsWidget: diagnosticsWidget,
+		DiffWidget:        diffWidget,
+		Transcripts:       ai.NewTranscriptStore(cfg.TranscriptDir),
+		HistoryWidget:     historyWidget,
+		QueryBar:          queryBar,
+		DiffIgnore:        respdiff.NewIgnore(cfg.DiffIgnore),
 	}
 
 	return h
@@ -215,14 +442,14 @@ func (h *MainHandler) updateLayout() {
 		h.Input.SetRect(0, 0, 0, 0)
 		h.BodyWidget.SetRect(0, 0, 0, 0)
 		h.ContentTypeWidget.SetRect(0, 0, 0, 0)
+		h.QueryBar.SetRect(0, 0, 0, 0)
 		h.Help.SetRect(termWidth/4, termHeight/4, 3*termWidth/4, 3*termHeight/4)
 		return
 	}
 
 	// Determine if we need to show body widget
 	showBody := false
-	if len(h.Endpoints) > 0 && h.List.SelectedRow < len(h.Endpoints) {
-		currEp := h.Endpoints[h.List.SelectedRow]
+	if currEp := h.selectedEndpoint(); currEp != nil {
 		if strings.EqualFold(currEp.Method, "POST") || strings.EqualFold(currEp.Method, "PUT") {
 			showBody = true
 		}
@@ -281,6 +508,37 @@ func (h *MainHandler) updateLayout() {
 			h.GeminiWidget.SetRect(0, 0, 0, 0)
 			h.GeminiInput.SetRect(0, 0, 0, 0)
 		}
+
+		// Drift report and spec diff take the place of the Response pane
+		r := h.Output.GetRect()
+		if h.ShowDrift {
+			h.DriftWidget.SetRect(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+		} else {
+			h.DriftWidget.SetRect(0, 0, 0, 0)
+		}
+		if h.ShowDiff {
+			h.DiffWidget.SetRect(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+		} else {
+			h.DiffWidget.SetRect(0, 0, 0, 0)
+		}
+		if h.ShowHistory {
+			h.HistoryWidget.SetRect(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+		} else {
+			h.HistoryWidget.SetRect(0, 0, 0, 0)
+		}
+		if h.ShowDiagnostics {
+			h.DiagnosticsWidget.SetRect(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+		} else {
+			h.DiagnosticsWidget.SetRect(0, 0, 0, 0)
+		}
+
+		// The filter bar sits on top of the Response pane
+		if h.showQueryBar() && r.Dy() > 6 {
+			h.QueryBar.SetRect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+3)
+			h.Output.SetRect(r.Min.X, r.Min.Y+3, r.Max.X, r.Max.Y)
+		} else {
+			h.QueryBar.SetRect(0, 0, 0, 0)
+		}
 	} else {
 		// Extreme fallback
 		h.List.SetRect(0, 0, termWidth, 1)
@@ -291,6 +549,12 @@ func (h *MainHandler) updateLayout() {
 }
 
 func (h *MainHandler) Render() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.render()
+}
+
+func (h *MainHandler) render() {
 	if h.ShowHelp {
 		ui.Render(h.Help)
 	} else if h.GeminiZoomed {
@@ -298,11 +562,13 @@ func (h *MainHandler) Render() {
 		ui.Render(h.GeminiWidget, h.GeminiInput)
 	} else {
 		// Update input title based on selected endpoint
-		currEp := h.Endpoints[h.List.SelectedRow]
+		currEp := h.selectedEndpoint()
 		var requiredParams []string
-		for _, p := range currEp.Parameters {
-			if p.Required {
-				requiredParams = append(requiredParams, p.Name+" ("+p.In+")")
+		if currEp != nil {
+			for _, p := range currEp.Parameters {
+				if p.Required {
+					requiredParams = append(requiredParams, p.Name+" ("+p.In+")")
+				}
 			}
 		}
 		if len(requiredParams) > 0 {
@@ -316,57 +582,798 @@ func (h *MainHandler) Render() {
 		h.updateLayout()
 		ui.Render(h.List, h.Output, h.BaseURLWidget, h.HeadersWidget, h.Input)
 
-		if strings.EqualFold(currEp.Method, "POST") || strings.EqualFold(currEp.Method, "PUT") {
+		if currEp != nil && (strings.EqualFold(currEp.Method, "POST") || strings.EqualFold(currEp.Method, "PUT")) {
 			ui.Render(h.BodyWidget, h.ContentTypeWidget)
 		}
 
+		if h.showQueryBar() {
+			ui.Render(h.QueryBar)
+		}
+
 		if h.ShowGemini && !h.GeminiZoomed {
 			ui.Render(h.GeminiWidget, h.GeminiInput)
 		}
+
+		if h.ShowDrift {
+			ui.Render(h.DriftWidget)
+		}
+
+		if h.ShowDiff {
+			ui.Render(h.DiffWidget)
+		}
+
+		if h.ShowHistory {
+			ui.Render(h.HistoryWidget)
+		}
+
+		if h.ShowDiagnostics {
+			ui.Render(h.DiagnosticsWidget)
+		}
 	}
 }
 
 func (h *MainHandler) initGemini() {
 	if len(h.GeminiWidget.Rows) == 0 || h.GeminiChat == nil {
-		h.GeminiWidget.Rows = []string{"Initializing Gemini insights..."}
+		h.GeminiWidget.Rows = []string{"Initializing AI insights..."}
 		h.GeminiWidget.SelectedRow = 0
 		ui.Render(h.GeminiWidget)
 
 		go func() {
-			clientCtx := h.GeminiCtx
-			gClient, err := ai.NewGeminiClient(clientCtx)
-			if err != nil {
-				h.GeminiWidget.Rows = []string{"Failed to load Gemini API: " + err.Error()}
-				ui.Render(h.GeminiWidget)
+			if !h.startSession() {
 				return
 			}
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			h.GeminiWidget.Rows = []string{}
+
+			prompt := ai.InsightsPrompt(h.LastCall, strings.Join(h.Output.Rows, "\n"))
+			h.confirmSend(prompt, func() {
+				h.streamReply(prompt, "Initial Insights:", func() {
+					h.GeminiWidget.SelectedRow = 0
+					ui.Render(h.GeminiWidget)
+				})
+			})
+		}()
+	}
+}
 
-			chat, err := gClient.CreateChatSession(clientCtx, "gemini-2.5-flash")
-			if err != nil {
-				h.GeminiWidget.Rows = []string{"Failed to create chat: " + err.Error()}
-				ui.Render(h.GeminiWidget)
-				return
+// startSession connects to the configured AI provider, reporting failures in
+// the AI widget. It runs in the background and takes h.mu to store the session.
+func (h *MainHandler) startSession() bool {
+	var chat ai.Session
+	failure := ""
+	assistant, err := ai.NewAssistant(h.GeminiCtx, h.Config.AIProvider, h.Config.AIModel, h.Config.AIBaseURL)
+	if err != nil {
+		failure = "Failed to load AI provider: " + err.Error()
+	} else if chat, err = assistant.StartSession(h.GeminiCtx); err != nil {
+		failure = "Failed to create chat: " + err.Error()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if failure != "" {
+		h.GeminiWidget.Rows = []string{failure}
+		ui.Render(h.GeminiWidget)
+		return false
+	}
+	h.Assistant = assistant
+	h.GeminiChat = ai.Redacted(chat, h.Redactor)
+	h.Transcript = ai.NewTranscript(assistant.Name(), h.LastCall)
+	return true
+}
+
+// recordTurns adds a question and its answer to the current transcript and
+// saves it, so conversations survive new requests and restarts
+func (h *MainHandler) recordTurns(question, answer string) {
+	if h.Transcript == nil {
+		return
+	}
+	h.Transcript.Add(ai.RoleUser, h.Redactor.Message(question))
+	if answer != "" {
+		h.Transcript.Add(ai.RoleAssistant, answer)
+	}
+	if err := h.Transcripts.Save(h.Transcript); err != nil {
+		h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, "[Failed to save transcript: "+err.Error()+"](fg:red)")
+	}
+}
+
+// openTranscript shows a saved conversation in the AI pane and resumes it,
+// so follow-up questions continue where it left off. It runs in the
+// background and takes h.mu to update the pane.
+func (h *MainHandler) openTranscript(t *ai.Transcript) {
+	rows := []string{"[" + t.Title() + "](fg:yellow)", ""}
+	for _, turn := range t.Turns {
+		if turn.Role == ai.RoleAssistant {
+			rows = append(rows, "AI:", "")
+			rows = append(rows, strings.Split(turn.Text, "\n")...)
+			rows = append(rows, "")
+		} else if turn.IsInsightsPrompt() {
+			rows = append(rows, "Initial Insights:", "")
+		} else {
+			rows = append(rows, "You: "+turn.Text)
+		}
+	}
+	h.mu.Lock()
+	h.GeminiWidget.Rows = rows
+	h.GeminiWidget.SelectedRow = len(rows) - 1
+	h.GeminiChat = nil
+	h.Transcript = nil
+	ui.Render(h.GeminiWidget)
+	assistant := h.Assistant
+	h.mu.Unlock()
+
+	var chat ai.ToolSession
+	failure := ""
+	var err error
+	if assistant == nil {
+		if assistant, err = ai.NewAssistant(h.GeminiCtx, h.Config.AIProvider, h.Config.AIModel, h.Config.AIBaseURL); err != nil {
+			failure = "Failed to load AI provider: " + err.Error()
+		}
+	}
+	if failure == "" {
+		if chat, err = ai.ResumeRedacted(h.GeminiCtx, assistant, t.Turns, h.Redactor); err != nil {
+			failure = "Failed to resume chat: " + err.Error()
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if failure != "" {
+		h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, "["+failure+"](fg:red)")
+		ui.Render(h.GeminiWidget)
+		return
+	}
+	if h.Assistant == nil {
+		h.Assistant = assistant
+	}
+	h.GeminiChat = chat
+	h.Transcript = t
+}
+
+// loadHistory lists the saved transcripts, newest first
+func (h *MainHandler) loadHistory() {
+	h.HistoryWidget.Title = "AI Transcripts (Enter to reopen, e to export, T to close)"
+	h.HistoryWidget.SelectedRow = 0
+	items, err := h.Transcripts.List()
+	if err != nil {
+		h.HistoryItems = nil
+		h.HistoryWidget.Rows = []string{"[Failed to read " + h.Transcripts.Dir + ": " + err.Error() + "](fg:red)"}
+		return
+	}
+	h.HistoryItems = items
+	h.HistoryWidget.Rows = make([]string, 0, len(items))
+	for _, t := range items {
+		h.HistoryWidget.Rows = append(h.HistoryWidget.Rows, t.Title())
+	}
+	if len(items) == 0 {
+		h.HistoryWidget.Rows = []string{"No saved conversations in " + h.Transcripts.Dir}
+	}
+}
+
+// exportTranscript writes t as Markdown to the working directory
+func exportTranscript(t *ai.Transcript) string {
+	if t == nil {
+		return "[No conversation to export](fg:yellow)"
+	}
+	name := "transcript-" + t.ID + ".md"
+	f, err := os.Create(name)
+	if err != nil {
+		return "[Export failed: " + err.Error() + "](fg:red)"
+	}
+	defer f.Close()
+	if err := t.WriteMarkdown(f); err != nil {
+		return "[Export failed: " + err.Error() + "](fg:red)"
+	}
+	return "[Exported " + name + "](fg:green)"
+}
+
+// composeRequests asks the assistant to turn request into API calls and
+// shows them for confirmation with 'y' in the AI pane. It runs in the
+// background and takes h.mu once the session is started.
+func (h *MainHandler) composeRequests(request string) {
+	h.mu.Lock()
+	started := h.GeminiChat != nil
+	h.mu.Unlock()
+	if !started && !h.startSession() {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	session, ok := h.GeminiChat.(ai.ToolSession)
+	if !ok {
+		h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, "[This AI provider does not support composing requests](fg:red)", "")
+		ui.Render(h.GeminiWidget)
+		return
+	}
+	h.confirmSend(ai.ComposePrompt(request), func() { h.proposeRequests(session, request) })
+}
+
+// proposeRequests asks session for the calls in the background and lists
+// them; h.mu must be held
+func (h *MainHandler) proposeRequests(session ai.ToolSession, request string) {
+	ctx, cancel := context.WithCancel(h.GeminiCtx)
+	h.GeminiCancel = cancel
+
+	h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, "You: "+request, "Planning requests... (x to cancel)")
+	h.GeminiWidget.SelectedRow = len(h.GeminiWidget.Rows) - 1
+	ui.Render(h.GeminiWidget)
+
+	go func() {
+		proposals, text, err := h.Composer.Propose(ctx, session, request)
+		cancelled := ctx.Err() == context.Canceled
+		cancel()
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.GeminiCancel = nil
+		h.showProposals(request, proposals, text, cancelled, err)
+	}()
+}
+
+// showProposals lists the proposed calls, or why there are none; h.mu must
+// be held
+func (h *MainHandler) showProposals(request string, proposals []*ai.ProposedRequest, text string, cancelled bool, err error) {
+	answer := text
+	for i, p := range proposals {
+		answer += fmt.Sprintf("\n%d. %s", i+1, p)
+	}
+	h.recordTurns(request, strings.TrimSpace(answer))
+	rows := h.GeminiWidget.Rows[:len(h.GeminiWidget.Rows)-1] // remove Planning
+	if text != "" {
+		rows = append(rows, "AI:", "")
+		rows = append(rows, strings.Split(text, "\n")...)
+	}
+	switch {
+	case cancelled:
+		rows = append(rows, "[cancelled](fg:yellow)")
+	case err != nil:
+		rows = append(rows, "[AI Error: "+err.Error()+"](fg:red)")
+	case len(proposals) == 0:
+		rows = append(rows, "[No requests proposed](fg:yellow)")
+	default:
+		h.Proposal = proposals
+		h.focusAI()
+		if n := proposalProblems(proposals); n > 0 {
+			rows = append(rows, fmt.Sprintf("[Proposed requests with %d %s the spec rejects, so they can't be run (n to discard):](fg:red)", n, plural(n, "problem")))
+		} else {
+			rows = append(rows, "Proposed requests (y to run, n to discard):")
+		}
+		for i, p := range proposals {
+			rows = append(rows, fmt.Sprintf("%d. %s", i+1, p))
+			for _, problem := range p.Problems {
+				rows = append(rows, "   [! "+problem+"](fg:red)")
 			}
-			h.GeminiChat = chat
-			h.GeminiWidget.Rows = []string{"Thinking..."}
+		}
+	}
+	h.GeminiWidget.Rows = append(rows, "")
+	h.GeminiWidget.SelectedRow = len(h.GeminiWidget.Rows) - 1
+	ui.Render(h.GeminiWidget)
+}
+
+// confirmSend calls send right away, or when the redaction policy asks for a
+// preview, shows exactly what will leave the machine and waits for 'y' in
+// the AI pane
+func (h *MainHandler) confirmSend(message string, send func()) {
+	if !h.Redactor.Policy.Preview {
+		send()
+		return
+	}
+	name := aiProviderName(h.Config)
+	rows := []string{"[Preview of the message for " + name + " (y to send, n to cancel):](fg:yellow)", ""}
+	rows = append(rows, strings.Split(h.Redactor.Message(message), "\n")...)
+	h.GeminiWidget.Rows = append(append(h.GeminiWidget.Rows, rows...), "")
+	h.GeminiWidget.SelectedRow = len(h.GeminiWidget.Rows) - len(rows) - 1
+	h.PendingSend = send
+	h.focusAI()
+	ui.Render(h.GeminiWidget)
+}
+
+// focusAI moves the focus to the AI pane, where 'y' and 'n' answer a
+// pending preview or proposal
+func (h *MainHandler) focusAI() {
+	h.FocusMode = "gemini"
+	h.List.TitleStyle = ui.NewStyle(ui.ColorWhite)
+	h.List.BorderStyle.Fg = ui.ColorWhite
+	h.Output.TitleStyle = ui.NewStyle(ui.ColorWhite)
+	h.Output.BorderStyle.Fg = ui.ColorWhite
+	h.GeminiWidget.TitleStyle = ui.NewStyle(ui.ColorYellow)
+	h.GeminiWidget.BorderStyle.Fg = ui.ColorYellow
+}
+
+// proposalProblems counts the missing parameters and spec violations in
+// proposals; a proposal with any can't be run
+func proposalProblems(proposals []*ai.ProposedRequest) int {
+	n := 0
+	for _, p := range proposals {
+		n += len(p.Problems)
+	}
+	return n
+}
+
+// runProposal invokes the confirmed requests in order, filling references to
+// earlier responses, and stops at the first failure. It runs in the
+// background, taking h.mu for one request at a time so keys are handled
+// between them.
+func (h *MainHandler) runProposal(proposals []*ai.ProposedRequest) {
+	var bodies []string
+	for i, p := range proposals {
+		body, ok := h.runProposedRequest(i, p, bodies)
+		if !ok {
+			break
+		}
+		bodies = append(bodies, body)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, "")
+	h.GeminiWidget.SelectedRow = len(h.GeminiWidget.Rows) - 1
+	ui.Render(h.Output, h.GeminiWidget)
+}
+
+// runProposedRequest invokes the i-th proposed request with the bodies of
+// the earlier ones and returns its response body, or false if it failed
+func (h *MainHandler) runProposedRequest(i int, p *ai.ProposedRequest, bodies []string) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	params := map[string]string{}
+	for k, v := range h.GlobalQueryParams {
+		params[k] = v
+	}
+	var err error
+	resolve := func(v string) string {
+		if err == nil {
+			v, err = ai.ResolveStepRefs(v, bodies)
+		}
+		return v
+	}
+	for k, v := range p.Params {
+		params[k] = resolve(v)
+	}
+	headers := map[string]string{}
+	for k, v := range p.Headers {
+		headers[k] = resolve(v)
+	}
+	body := resolve(p.Body)
+	if err == nil {
+		err = p.CheckBody(body)
+	}
+	if err != nil {
+		h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, fmt.Sprintf("[%d. %s %s not run: %s](fg:red)", i+1, p.Endpoint.Method, p.Endpoint.Path, err.Error()))
+		return "", false
+	}
+
+	resp, err := h.invoke(p.Endpoint, params, headers, body, p.ContentType)
+	if err != nil {
+		h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, fmt.Sprintf("[%d. %s %s: %s](fg:red)", i+1, p.Endpoint.Method, p.Endpoint.Path, err.Error()))
+		return "", false
+	}
+	color := "green"
+	if resp.StatusCode >= 400 {
+		color = "red"
+	}
+	h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, fmt.Sprintf("[%d. %s %s -> %d](fg:%s)", i+1, p.Endpoint.Method, p.Endpoint.Path, resp.StatusCode, color))
+	h.GeminiWidget.SelectedRow = len(h.GeminiWidget.Rows) - 1
+	ui.Render(h.Output, h.GeminiWidget)
+	if resp.StatusCode >= 400 {
+		h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, "[Stopped after a failed request](fg:red)")
+		return "", false
+	}
+	return resp.Body, true
+}
+
+// streamReply sends prompt on the current AI session and renders the answer
+// token by token below title, in the background until it finishes or is
+// cancelled with 'x'. It then appends the token usage and latency and calls
+// done, if set. h.mu must be held; GeminiCancel is set before returning so a
+// second question waits for this answer.
+func (h *MainHandler) streamReply(prompt, title string, done func()) {
+	ctx, cancel := context.WithCancel(h.GeminiCtx)
+	h.GeminiCancel = cancel
+
+	base := append(h.GeminiWidget.Rows, title, "")
+	h.GeminiWidget.Rows = append(base, "Thinking... (x to cancel)")
+	h.GeminiWidget.SelectedRow = len(h.GeminiWidget.Rows) - 1
+	ui.Render(h.GeminiWidget)
+
+	chat := h.GeminiChat
+	go func() {
+		start := time.Now()
+		text := ""
+		reply, err := chat.Stream(ctx, prompt, func(token string) {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			text += token
+			h.GeminiWidget.Rows = append(base[:len(base):len(base)], strings.Split(text, "\n")...)
+			h.GeminiWidget.SelectedRow = len(h.GeminiWidget.Rows) - 1
 			ui.Render(h.GeminiWidget)
+		})
+		elapsed := time.Since(start).Round(10 * time.Millisecond)
+		cancelled := ctx.Err() == context.Canceled
+		cancel()
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.GeminiCancel = nil
+		h.recordTurns(prompt, text)
+
+		rows := append(base[:len(base):len(base)], strings.Split(text, "\n")...)
+		switch {
+		case cancelled:
+			rows = append(rows, fmt.Sprintf("[cancelled after %s](fg:yellow)", elapsed))
+		case err != nil:
+			rows = append(rows, "[AI Error: "+err.Error()+"](fg:red)")
+		default:
+			rows = append(rows, fmt.Sprintf("[%d prompt + %d completion tokens, %s](fg:white)", reply.Usage.PromptTokens, reply.Usage.CompletionTokens, elapsed))
+		}
+		h.GeminiWidget.Rows = append(rows, "")
+		h.GeminiWidget.SelectedRow = len(h.GeminiWidget.Rows) - 1
+		ui.Render(h.GeminiWidget)
+		if done != nil {
+			done()
+		}
+	}()
+}
+
+// invoke calls ep and shows the result in the Response pane, as the first
+// page when the response is paginated
+func (h *MainHandler) invoke(ep *model.Endpoint, inputValues, headerValues map[string]string, body, contentType string) (*client.Response, error) {
+	h.stopStream()
+	h.closeSession()
+	url, err := client.BuildURL(h.BaseURL, ep, inputValues)
+	if ep.GraphQL != "" {
+		url, err = h.graphqlURL(), nil
+	}
+	var fullResp *client.Response
+	if err == nil {
+		var reader io.ReadCloser
+		ctx, cancel := context.WithCancel(context.Background())
+		fullResp, reader, err = client.Open(ctx, ep.Method, url, headerValues, body, contentType)
+		if err == nil && (h.StreamAll || stream.IsStream(fullResp.Header.Get("Content-Type"))) {
+			h.startPaging(ep, inputValues, nil)
+			h.startStream(ep, inputValues, headerValues, body, contentType, fullResp, reader, cancel)
+			return fullResp, nil
+		}
+		if err == nil {
+			client.ReadBody(fullResp, reader)
+		}
+		cancel()
+	}
+	h.startPaging(ep, inputValues, fullResp)
+	h.showResult(ep, inputValues, headerValues, body, contentType, fullResp, err)
+	return fullResp, err
+}
 
-			outText := strings.Join(h.Output.Rows, "\n")
-			prompt := "Here is the API response:\n" + outText + "\nProvide interesting insights, then ask the user for any recommendations or follow up actions."
-			resp, err := chat.SendMessage(clientCtx, genai.Part{Text: prompt})
+// showResult shows a response in the Response pane. The call is recorded
+// for drift detection and as context for the AI assistant.
+func (h *MainHandler) showResult(ep *model.Endpoint, inputValues, headerValues map[string]string, body, contentType string, fullResp *client.Response, err error) {
+	var statusCode int
+	h.ShowStream, h.Events = false, nil
+	h.Response, h.Rendered = fullResp, nil
+	if fullResp != nil {
+		h.Rendered = render.Body(fullResp.Header.Get("Content-Type"), []byte(fullResp.Body))
+	}
+	h.applyFilter()
+	if err == nil {
+		statusCode = fullResp.StatusCode
+		if ep.Webhook == "" && ep.GRPC == "" && ep.GraphQL == "" {
+			h.Drift.Observe(ep.Method, ep.Path, fullResp.StatusCode, fullResp.Header, fullResp.Body)
+			h.recordDriftHistory(ep, headerValues, body, fullResp)
+		}
+	}
+	h.recordCall(ep, inputValues, headerValues, body, contentType, fullResp)
+	statusColor := "green" // default success
+	h.Output.BorderStyle.Fg = ui.ColorGreen
 
-			if err != nil {
-				h.GeminiWidget.Rows = []string{"Error from Gemini: " + err.Error()}
-			} else {
-				h.GeminiWidget.Rows = append([]string{"Initial Insights:"}, strings.Split(ai.FormatContent(resp), "\n")...)
+	if statusCode >= 400 {
+		statusColor = "red"
+		h.Output.BorderStyle.Fg = ui.ColorRed
+	}
+
+	if err != nil {
+		h.Output.Rows = []string{fmt.Sprintf("Error: %s", err.Error()), fmt.Sprintf("[Status: %d](fg:%s)", statusCode, statusColor)}
+		h.Output.BorderStyle.Fg = ui.ColorRed
+	} else {
+		h.showResponse()
+		h.Output.BorderStyle.Fg = ui.ColorGreen
+	}
+	h.Output.SelectedRow = 0
+}
+
+// recordCall keeps the call as context for the AI assistant and for
+// follow-up requests such as pages and comparisons
+func (h *MainHandler) recordCall(ep *model.Endpoint, inputValues, headerValues map[string]string, body, contentType string, fullResp *client.Response) {
+	h.LastCall = &ai.CallContext{
+		Method:      ep.Method,
+		Path:        ep.Path,
+		Operation:   h.findOperation(ep),
+		Params:      inputValues,
+		Headers:     headerValues,
+		Body:        body,
+		ContentType: contentType,
+		Response:    fullResp,
+		Redactor:    h.Redactor,
+	}
+}
+
+// responseHeaderRows are the status line and spacer above the body
+const responseHeaderRows = 2
+
+// showResponse fills the Response pane from the last response, as a
+// foldable tree when the tree view is on and the body is JSON
+func (h *MainHandler) showResponse() {
+	if h.Response == nil {
+		return
+	}
+	statusColor := "green"
+	if h.Response.StatusCode >= 400 {
+		statusColor = "red"
+	}
+	rows := []string{fmt.Sprintf("[Status: %d](fg:%s)", h.Response.StatusCode, statusColor), ""}
+	if h.ShowCompare && h.Baseline != nil {
+		h.Output.Title = "Response Diff (d for the response, P to pin, E to compare another base URL)"
+		h.Output.Rows = append(rows, h.compareRows()...)
+		h.applySearch()
+		return
+	}
+	if h.ShowStream {
+		h.Output.Title = h.streamTitle()
+		h.Output.Rows = append(rows, h.streamRows()...)
+		h.applySearch()
+		return
+	}
+	if h.ShowTree && h.Tree != nil {
+		h.Output.Title = "Response Tree (t for text, Enter fold, +/- all, p/c copy pointer/value)"
+		rows = append(rows, h.Tree.Rows()...)
+	} else {
+		h.Output.Title = "Response (Tab/r to focus, j/k to scroll, t for tree, f to filter, s to save)"
+		if h.Rendered.Format != render.FormatJSON {
+			h.Output.Title = "Response · " + h.Rendered.Title() + " (Tab/r to focus, j/k to scroll, s to save)"
+		}
+		rows = append(rows, splitLines(h.displayedBody())...)
+	}
+	if h.filterActive() {
+		h.Output.Title = "Filtered " + h.Output.Title + " - F for original"
+	} else if h.Filter != "" {
+		h.Output.Title += " - F for filtered"
+	}
+	h.Output.Title += h.pageTitle()
+	h.Output.Rows = rows
+	h.applySearch()
+}
+
+// applySearch highlights the search matches in the Response pane and shows
+// the match counter in its title
+func (h *MainHandler) applySearch() {
+	h.Matches = nil
+	if h.Search == nil {
+		return
+	}
+	h.Matches = textsearch.Find(h.Search, h.Output.Rows)
+	for i, row := range h.Output.Rows {
+		h.Output.Rows[i] = textsearch.Highlight(h.Search, row)
+	}
+	if len(h.Matches) == 0 {
+		h.Output.Title += " - no match for /" + h.SearchQuery + "/"
+		return
+	}
+	h.MatchIndex = min(max(h.MatchIndex, 0), len(h.Matches)-1)
+	h.Output.Title += fmt.Sprintf(" - match %d/%d, n/N next/prev", h.MatchIndex+1, len(h.Matches))
+}
+
+// gotoMatch selects match i, wrapping around at either end
+func (h *MainHandler) gotoMatch(i int) {
+	if len(h.Matches) == 0 {
+		return
+	}
+	h.MatchIndex = (i%len(h.Matches) + len(h.Matches)) % len(h.Matches)
+	h.showResponse()
+	h.Output.SelectedRow = h.Matches[h.MatchIndex].Row
+}
+
+// editSearch searches for the pattern being typed, jumping to the first
+// match at or below the row the search started from
+func (h *MainHandler) editSearch() {
+	h.SearchQuery = h.EditBuffer
+	h.Search = nil
+	h.QueryBar.Title = "Search (regex, smart case, Enter to keep)"
+	h.QueryBar.BorderStyle.Fg = ui.ColorYellow
+	if h.SearchQuery != "" {
+		re, err := textsearch.Compile(h.SearchQuery)
+		if err != nil {
+			h.QueryBar.Title = "Search: " + err.Error()
+			h.QueryBar.BorderStyle.Fg = ui.ColorRed
+		}
+		h.Search = re
+	}
+	h.MatchIndex = 0
+	h.showResponse()
+	h.Output.SelectedRow = h.SearchFrom
+	for i, m := range h.Matches {
+		if m.Row >= h.SearchFrom {
+			h.gotoMatch(i)
+			return
+		}
+	}
+	h.gotoMatch(0)
+}
+
+// gotoLine selects body line n of the Response pane, counted from 1
+func (h *MainHandler) gotoLine(text string) {
+	n, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || n < 1 {
+		h.Output.Title = fmt.Sprintf("Response (not a line number: %q)", text)
+		return
+	}
+	h.Output.SelectedRow = min(responseHeaderRows+n-1, len(h.Output.Rows)-1)
+}
+
+// showQueryBar reports whether the filter bar is shown above the response.
+// It also prompts for searches, line numbers and file names.
+func (h *MainHandler) showQueryBar() bool {
+	return isBarTarget(h.EditTarget) || (h.ShowFiltered && h.Filter != "")
+}
+
+func isBarTarget(target string) bool {
+	switch target {
+	case "query", "save", "search", "line", "compare", "pages", "frame", "graphql":
+		return true
+	}
+	return false
+}
+
+// editBar echoes the edit buffer in the bar above the Response pane, updating
+// filters and searches as they are typed
+func (h *MainHandler) editBar() {
+	h.QueryBar.Text = h.EditBuffer
+	switch h.EditTarget {
+	case "query":
+		h.editFilter()
+	case "search":
+		h.editSearch()
+	}
+}
+
+// resetQueryBar shows the filter in the bar again after another prompt
+func (h *MainHandler) resetQueryBar() {
+	h.QueryBar.Text = h.Filter
+	if h.FilterErr != "" {
+		h.QueryBar.Title = "Filter: " + h.FilterErr
+		h.QueryBar.BorderStyle.Fg = ui.ColorRed
+	} else {
+		h.QueryBar.Title = "Filter (jq or JSONPath, Enter to keep)"
+		h.QueryBar.BorderStyle.Fg = ui.ColorYellow
+	}
+}
+
+// filterActive reports whether the Response pane shows the filter result
+func (h *MainHandler) filterActive() bool {
+	return h.ShowFiltered && h.Filter != "" && h.FilterErr == ""
+}
+
+// displayedBody returns the filter result while a filter is active,
+// otherwise the body rendered for its content type
+func (h *MainHandler) displayedBody() string {
+	if h.filterActive() {
+		return h.Filtered
+	}
+	return h.Rendered.Text
+}
+
+// applyFilter evaluates the filter on the last response and rebuilds the
+// tree from whichever body is displayed
+func (h *MainHandler) applyFilter() {
+	h.Filtered, h.FilterErr = "", ""
+	h.Tree = nil
+	if h.Response == nil {
+		return
+	}
+	if h.Filter != "" {
+		if h.Rendered.JSON == "" {
+			h.FilterErr = h.Rendered.Format + " responses can't be filtered"
+		} else if out, err := query.Apply(h.Filter, []byte(h.Rendered.JSON)); err != nil {
+			h.FilterErr = err.Error()
+		} else {
+			h.Filtered = out
+		}
+	}
+	h.resetQueryBar()
+	source := h.Rendered.JSON
+	if h.filterActive() {
+		source = h.Filtered
+	}
+	if root, err := jsontree.Parse([]byte(source)); err == nil && root.IsContainer() {
+		h.Tree = jsontree.New(root, 2)
+	}
+}
+
+// saveBody writes the raw response body to path, as received, and restores
+// the filter bar
+func (h *MainHandler) saveBody(path string) {
+	if path != "" {
+		if err := os.WriteFile(path, []byte(h.Response.Body), 0o644); err != nil {
+			h.Output.Title = "Response (save failed: " + err.Error() + ")"
+		} else {
+			h.Output.Title = fmt.Sprintf("Response (saved %s to %s)", render.Size(len(h.Response.Body)), path)
+		}
+	}
+	h.resetQueryBar()
+}
+
+// editFilter evaluates the expression being typed so the result updates live
+func (h *MainHandler) editFilter() {
+	h.Filter = strings.TrimSpace(h.EditBuffer)
+	h.refilter()
+}
+
+// refilter re-evaluates the filter and redraws the Response pane
+func (h *MainHandler) refilter() {
+	h.applyFilter()
+	h.showResponse()
+	h.Output.SelectedRow = 0
+}
+
+// handleTreeKey handles the tree view keys while the Response pane is
+// focused and reports whether the key was used
+func (h *MainHandler) handleTreeKey(id string) bool {
+	row := h.Output.SelectedRow - responseHeaderRows
+	switch id {
+	case "<Enter>", "<Space>":
+		h.Tree.Toggle(row)
+	case "<Right>":
+		h.Tree.Expand(row)
+	case "<Left>":
+		h.Output.SelectedRow = h.Tree.Collapse(row) + responseHeaderRows
+	case "+":
+		h.Tree.SetAll(true)
+	case "-":
+		h.Tree.SetAll(false)
+		h.Output.SelectedRow = 0
+	case "p", "c":
+		node := h.Tree.Node(row)
+		if node == nil {
+			return true
+		}
+		text, what := node.Pointer, "pointer "+node.Pointer
+		if id == "c" {
+			text, what = node.JSON(), "value of "+node.Pointer
+		}
+		if err := copyToClipboard(text); err != nil {
+			h.Output.Title = "Response Tree (copy failed: " + err.Error() + ")"
+		} else {
+			h.Output.Title = "Response Tree (copied " + what + ")"
+		}
+		return true
+	default:
+		return false
+	}
+	h.showResponse()
+	if h.Output.SelectedRow >= len(h.Output.Rows) {
+		h.Output.SelectedRow = len(h.Output.Rows) - 1
+	}
+	return true
+}
+
+// findOperation looks up the spec operation behind ep in the loaded documents
+func (h *MainHandler) findOperation(ep *model.Endpoint) *openapi3.Operation {
+	for _, doc := range h.Docs {
+		if ep.Webhook != "" {
+			if item := parser.Webhooks(doc)[ep.Webhook]; item != nil && item.GetOperation(ep.Method) != nil {
+				return item.GetOperation(ep.Method)
 			}
-			h.GeminiWidget.SelectedRow = 0
-			ui.Render(h.GeminiWidget)
-		}()
+			continue
+		}
+		if op, _ := parser.FindOperation(doc, ep.Method, ep.Path); op != nil {
+			return op
+		}
 	}
+	return nil
 }
 
 func (h *MainHandler) HandleEvent(e tui.Event) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	if e.Type == ui.ResizeEvent {
 		payload := e.Payload.(ui.Resize)
 		h.Resize(payload.Width, payload.Height)
@@ -416,26 +1423,61 @@ func (h *MainHandler) HandleEvent(e tui.Event) bool {
 				h.GeminiInput.Text = h.GeminiQuery
 				h.GeminiInput.BorderStyle.Fg = ui.ColorMagenta
 
-				if h.GeminiChat != nil && h.GeminiQuery != "" {
-					h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, "You: "+h.GeminiQuery, "Thinking...")
-					ui.Render(h.GeminiWidget)
-
-					go func(query string) {
-						resp, err := h.GeminiChat.SendMessage(h.GeminiCtx, genai.Part{Text: query})
-						h.GeminiWidget.Rows = h.GeminiWidget.Rows[:len(h.GeminiWidget.Rows)-1] // remove Thinking
-						if err != nil {
-							h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, "Gemini Error: "+err.Error(), "")
-						} else {
-							h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, "Gemini:", "")
-							h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, strings.Split(ai.FormatContent(resp), "\n")...)
-							h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, "")
-						}
-						h.GeminiWidget.SelectedRow = len(h.GeminiWidget.Rows) - 1
-						ui.Render(h.GeminiWidget)
-					}(h.GeminiQuery)
+				if h.GeminiChat != nil && h.GeminiQuery != "" && h.GeminiCancel == nil {
+					query := h.GeminiQuery
+					h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, "You: "+query)
+					h.confirmSend(query, func() { h.streamReply(query, "AI:", nil) })
 				}
 				h.GeminiInput.Text = ""
 				h.GeminiQuery = ""
+			case "query":
+				h.Filter = strings.TrimSpace(h.EditBuffer)
+				h.QueryBar.Text = h.Filter
+				h.ShowFiltered = h.Filter != ""
+				h.refilter()
+				h.EditTarget = ""
+				h.updateLayout()
+				ui.Clear()
+			case "save", "search", "line", "compare", "pages", "frame", "graphql":
+				target := h.EditTarget
+				h.EditTarget = ""
+				switch target {
+				case "save":
+					h.saveBody(strings.TrimSpace(h.EditBuffer))
+				case "search":
+					row := h.Output.SelectedRow
+					h.showResponse()
+					h.Output.SelectedRow = row
+					h.resetQueryBar()
+				case "line":
+					h.gotoLine(h.EditBuffer)
+					h.resetQueryBar()
+				case "compare":
+					h.resetQueryBar()
+					h.compareWith(h.EditBuffer)
+				case "pages":
+					h.resetQueryBar()
+					h.fetchAllPages(h.EditBuffer)
+				case "frame":
+					h.resetQueryBar()
+					h.sendFrame(h.EditBuffer)
+				case "graphql":
+					h.resetQueryBar()
+					if ep := h.selectedEndpoint(); ep != nil && ep.GraphQL != "" {
+						h.GraphQLQuery, h.GraphQLFor = strings.TrimSpace(h.EditBuffer), ep
+						h.invokeGraphQL(ep, h.headerValues())
+					}
+				}
+				h.updateLayout()
+				ui.Clear()
+			case "compose":
+				request := strings.TrimSpace(h.EditBuffer)
+				h.GeminiInput.Text = ""
+				h.GeminiInput.Title = "AI Chat (Press 'G' to ask)"
+				h.GeminiInput.BorderStyle.Fg = ui.ColorMagenta
+				if request != "" && h.GeminiCancel == nil {
+					go h.composeRequests(request)
+				}
 			}
 			h.EditTarget = ""
 		case "<Backspace>":
@@ -445,17 +1487,28 @@ func (h *MainHandler) HandleEvent(e tui.Event) bool {
 					h.BaseURLWidget.Text = h.EditBuffer
 				} else if h.EditTarget == "headers" {
 					h.HeadersWidget.Text = h.EditBuffer
-				} else if h.EditTarget == "gemini" {
+				} else if h.EditTarget == "gemini" || h.EditTarget == "compose" {
 					h.GeminiInput.Text = h.EditBuffer
+				} else if isBarTarget(h.EditTarget) {
+					h.editBar()
 				} else {
 					h.Input.Text = h.EditBuffer
 				}
 			}
 		case "<C-c>":
 			return true
+		case "<Space>":
+			if isBarTarget(h.EditTarget) {
+				h.EditBuffer += " "
+				h.editBar()
+			}
 		default:
 			if len(e.ID) == 1 {
 				h.EditBuffer += e.ID
+				if isBarTarget(h.EditTarget) {
+					h.editBar()
+					return false
+				}
 				if h.EditTarget == "baseurl" {
 					h.BaseURLWidget.Text = h.EditBuffer
 				} else if h.EditTarget == "headers" {
@@ -464,7 +1517,7 @@ func (h *MainHandler) HandleEvent(e tui.Event) bool {
 					h.BodyWidget.Text = h.EditBuffer
 				} else if h.EditTarget == "content-type" {
 					h.ContentTypeWidget.Text = h.EditBuffer
-				} else if h.EditTarget == "gemini" {
+				} else if h.EditTarget == "gemini" || h.EditTarget == "compose" {
 					h.GeminiInput.Text = h.EditBuffer
 				} else {
 					h.Input.Text = h.EditBuffer
@@ -474,6 +1527,36 @@ func (h *MainHandler) HandleEvent(e tui.Event) bool {
 		return false
 	}
 
+	// 'y' and 'n' answer a preview or proposal only in the AI pane; elsewhere
+	// 'n' jumps to the next search match
+	if h.PendingSend != nil && h.FocusMode == "gemini" && (e.ID == "y" || e.ID == "n") {
+		send := h.PendingSend
+		h.PendingSend = nil
+		if e.ID == "y" {
+			send()
+		} else {
+			h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, "[Not sent](fg:yellow)", "")
+		}
+		return false
+	}
+
+	if h.Proposal != nil && h.FocusMode == "gemini" && (e.ID == "y" || e.ID == "n") {
+		proposal := h.Proposal
+		h.Proposal = nil
+		if e.ID == "y" && proposalProblems(proposal) > 0 {
+			h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, "[Not run: the spec rejects the proposed requests. Ask again with the missing details](fg:red)", "")
+		} else if e.ID == "y" {
+			go h.runProposal(proposal)
+		} else {
+			h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, "[Proposal discarded](fg:yellow)", "")
+		}
+		return false
+	}
+
+	if h.FocusMode == "output" && h.ShowTree && h.Tree != nil && h.handleTreeKey(e.ID) {
+		return false
+	}
+
 	switch e.ID {
 	case "q", "<C-c>":
 		return true
@@ -516,6 +1599,22 @@ func (h *MainHandler) HandleEvent(e tui.Event) bool {
 			if h.GeminiWidget.SelectedRow < len(h.GeminiWidget.Rows)-1 {
 				h.GeminiWidget.SelectedRow++
 			}
+		} else if h.FocusMode == "drift" {
+			if h.DriftWidget.SelectedRow < len(h.DriftWidget.Rows)-1 {
+				h.DriftWidget.SelectedRow++
+			}
+		} else if h.FocusMode == "diff" {
+			if h.DiffWidget.SelectedRow < len(h.DiffWidget.Rows)-1 {
+				h.DiffWidget.SelectedRow++
+			}
+		} else if h.FocusMode == "history" {
+			if h.HistoryWidget.SelectedRow < len(h.HistoryWidget.Rows)-1 {
+				h.HistoryWidget.SelectedRow++
+			}
+		} else if h.FocusMode == "diagnostics" {
+			if h.DiagnosticsWidget.SelectedRow < len(h.DiagnosticsWidget.Rows)-1 {
+				h.DiagnosticsWidget.SelectedRow++
+			}
 		} else {
 			if h.Output.SelectedRow < len(h.Output.Rows)-1 {
 				h.Output.SelectedRow++
@@ -530,102 +1629,66 @@ func (h *MainHandler) HandleEvent(e tui.Event) bool {
 			if h.GeminiWidget.SelectedRow > 0 {
 				h.GeminiWidget.SelectedRow--
 			}
+		} else if h.FocusMode == "drift" {
+			if h.DriftWidget.SelectedRow > 0 {
+				h.DriftWidget.SelectedRow--
+			}
+		} else if h.FocusMode == "diff" {
+			if h.DiffWidget.SelectedRow > 0 {
+				h.DiffWidget.SelectedRow--
+			}
+		} else if h.FocusMode == "history" {
+			if h.HistoryWidget.SelectedRow > 0 {
+				h.HistoryWidget.SelectedRow--
+			}
+		} else if h.FocusMode == "diagnostics" {
+			if h.DiagnosticsWidget.SelectedRow > 0 {
+				h.DiagnosticsWidget.SelectedRow--
+			}
 		} else {
 			if h.Output.SelectedRow > 0 {
 				h.Output.SelectedRow--
 			}
 		}
 	case "<Enter>":
-		if h.FocusMode == "output" || h.FocusMode == "gemini" {
+		if h.FocusMode == "history" {
+			if h.HistoryWidget.SelectedRow < len(h.HistoryItems) {
+				t := h.HistoryItems[h.HistoryWidget.SelectedRow]
+				h.ShowHistory = false
+				h.ShowGemini = true
+				h.GeminiZoomed = false
+				h.FocusMode = "gemini"
+				h.GeminiWidget.TitleStyle = ui.NewStyle(ui.ColorYellow)
+				h.GeminiWidget.BorderStyle.Fg = ui.ColorYellow
+				h.updateLayout()
+				ui.Clear()
+				go h.openTranscript(t)
+			}
+			return false
+		}
+		if h.FocusMode == "output" || h.FocusMode == "gemini" || h.FocusMode == "drift" || h.FocusMode == "diff" || h.FocusMode == "diagnostics" {
 			return false
 		}
 
 		// Reset Gemini chat state when a new API call is made
 		h.GeminiChat = nil
+		h.Transcript = nil
 		h.GeminiWidget.Rows = []string{}
 		h.GeminiWidget.SelectedRow = 0
-		ep := h.Endpoints[h.List.SelectedRow]
-		inputValues := map[string]string{}
-		// Initialize with global query params
-		for k, v := range h.GlobalQueryParams {
-			inputValues[k] = v
-		}
-		headerValues := map[string]string{}
-
-		// Check for shorthand input
-		var requiredPathParams []string
-		var requiredQueryParams []string
-		for _, p := range ep.Parameters {
-			if p.Required && p.In == "path" {
-				requiredPathParams = append(requiredPathParams, p.Name)
-			} else if p.Required && p.In == "query" {
-				requiredQueryParams = append(requiredQueryParams, p.Name)
-			}
-		}
-
-		if h.QueryInput != "" && !strings.Contains(h.QueryInput, "=") && !strings.Contains(h.QueryInput, "&") {
-			if len(requiredPathParams) == 1 && len(requiredQueryParams) == 0 {
-				inputValues[requiredPathParams[0]] = h.QueryInput
-			} else if len(requiredPathParams) == 0 && len(requiredQueryParams) == 1 {
-				inputValues[requiredQueryParams[0]] = h.QueryInput
-			}
-		} else if h.QueryInput != "" {
-			// Simple query param parsing key=value&key2=value2
-			pairs := strings.Split(h.QueryInput, "&")
-			for _, p := range pairs {
-				kv := strings.SplitN(p, "=", 2)
-				if len(kv) == 2 {
-					inputValues[kv[0]] = kv[1]
-				}
-			}
-		}
-		if h.HeaderInput != "" {
-			pairs := strings.FieldsFunc(h.HeaderInput, func(r rune) bool {
-				return r == '&' || r == ';'
-			})
-			for _, p := range pairs {
-				p = strings.TrimSpace(p)
-				if p == "" {
-					continue
-				}
-				kv := strings.SplitN(p, ":", 2)
-				if len(kv) == 2 {
-					key := strings.TrimSpace(kv[0])
-					val := strings.TrimSpace(kv[1])
-					if key != "" {
-						headerValues[key] = val
-					}
-					continue
-				}
-				kv = strings.SplitN(p, "=", 2)
-				if len(kv) == 2 {
-					key := strings.TrimSpace(kv[0])
-					val := strings.TrimSpace(kv[1])
-					if key != "" {
-						headerValues[key] = val
-					}
-				}
-			}
-		}
-		resp, statusCode, err := client.InvokeEndpoint(h.BaseURL, ep, inputValues, headerValues, h.BodyInput, h.ContentTypeInput)
-		statusColor := "green" // default success
-		h.Output.BorderStyle.Fg = ui.ColorGreen
-
-		if statusCode >= 400 {
-			statusColor = "red"
-			h.Output.BorderStyle.Fg = ui.ColorRed
+		ep := h.selectedEndpoint()
+		if ep == nil {
+			return false
 		}
-
-		if err != nil {
-			h.Output.Rows = []string{fmt.Sprintf("Error: %s", err.Error()), fmt.Sprintf("[Status: %d](fg:%s)", statusCode, statusColor)}
-			h.Output.BorderStyle.Fg = ui.ColorRed
+		inputValues, headerValues := h.requestInputs(ep)
+		if ep.GRPC != "" {
+			h.invokeGRPC(ep, headerValues, h.BodyInput)
+		} else if ep.GraphQL != "" {
+			h.invokeGraphQL(ep, headerValues)
+		} else if ep.WebSocket {
+			h.openSession(ep, inputValues, headerValues)
 		} else {
-			formattedResp := tryFormatJSON(resp)
-			headerLine := fmt.Sprintf("[Status: %d](fg:%s)", statusCode, statusColor)
-			h.Output.Rows = append([]string{headerLine, ""}, splitLines(formattedResp)...)
-			h.Output.BorderStyle.Fg = ui.ColorGreen
+			h.invoke(ep, inputValues, headerValues, h.BodyInput, h.ContentTypeInput)
 		}
-		h.Output.SelectedRow = 0
 		h.QueryInput = ""
 		h.Input.Text = ""
 
@@ -651,17 +1714,26 @@ func (h *MainHandler) HandleEvent(e tui.Event) bool {
 		h.HeadersWidget.BorderStyle.Fg = ui.ColorYellow
 		h.Output.BorderStyle.Fg = ui.ColorWhite
 	case "B":
-		currEp := h.Endpoints[h.List.SelectedRow]
-		if strings.EqualFold(currEp.Method, "POST") || strings.EqualFold(currEp.Method, "PUT") {
+		currEp := h.selectedEndpoint()
+		if currEp == nil {
+			return false
+		}
+		if strings.EqualFold(currEp.Method, "POST") || strings.EqualFold(currEp.Method, "PUT") || currEp.GRPC != "" {
 			h.InputMode = true
 			h.EditTarget = "body"
 			h.EditBuffer = h.BodyInput
+			if h.EditBuffer == "" {
+				h.EditBuffer = h.grpcTemplate(currEp) + h.graphqlVariables(currEp)
+			}
 			h.BodyWidget.Text = h.EditBuffer
 			h.BodyWidget.BorderStyle.Fg = ui.ColorYellow
 			h.Output.BorderStyle.Fg = ui.ColorWhite
 		}
 	case "C":
-		currEp := h.Endpoints[h.List.SelectedRow]
+		currEp := h.selectedEndpoint()
+		if currEp == nil {
+			return false
+		}
 		if strings.EqualFold(currEp.Method, "POST") || strings.EqualFold(currEp.Method, "PUT") {
 			h.InputMode = true
 			h.EditTarget = "content-type"
@@ -683,7 +1755,7 @@ func (h *MainHandler) HandleEvent(e tui.Event) bool {
 			h.GeminiWidget.BorderStyle.Fg = ui.ColorYellow
 			h.updateLayout()
 			ui.Clear()
-			h.Render()
+			h.render()
 
 			h.initGemini()
 		} else {
@@ -703,6 +1775,33 @@ func (h *MainHandler) HandleEvent(e tui.Event) bool {
 			h.GeminiInput.Text = h.EditBuffer
 			h.GeminiInput.BorderStyle.Fg = ui.ColorYellow
 		}
+	case "A":
+		if !h.ShowGemini {
+			h.ShowGemini = true
+			h.GeminiZoomed = false
+			h.updateLayout()
+			ui.Clear()
+		}
+		h.InputMode = true
+		h.EditTarget = "compose"
+		h.EditBuffer = ""
+		h.GeminiInput.Title = "Compose requests (describe what to do)"
+		h.GeminiInput.Text = ""
+		h.GeminiInput.BorderStyle.Fg = ui.ColorYellow
+	case "x":
+		if h.StreamCancel != nil && (h.FocusMode == "output" || h.GeminiCancel == nil) {
+			h.stopStream()
+			h.showResponse()
+		} else if h.GeminiCancel != nil {
+			h.GeminiCancel()
+		}
+	case "S":
+		h.StreamAll = !h.StreamAll
+		if h.StreamAll {
+			h.Output.Title = "Response (every response is streamed line by line, S to turn off)"
+		} else {
+			h.Output.Title = "Response (only event streams and NDJSON are streamed)"
+		}
 	case "Z":
 		h.GeminiZoomed = !h.GeminiZoomed
 		if h.GeminiZoomed {
@@ -720,7 +1819,220 @@ func (h *MainHandler) HandleEvent(e tui.Event) bool {
 		}
 		h.updateLayout()
 		ui.Clear()
-		h.Render()
+		h.render()
+	case "D":
+		h.ShowDrift = !h.ShowDrift
+		h.ShowDiff, h.ShowDiagnostics = false, false
+		if h.ShowDrift {
+			h.DriftWidget.Rows = h.driftRows()
+			h.DriftWidget.SelectedRow = 0
+			h.FocusMode = "drift"
+			h.List.TitleStyle = ui.NewStyle(ui.ColorWhite)
+			h.List.BorderStyle.Fg = ui.ColorWhite
+		} else {
+			h.FocusMode = "list"
+			h.List.TitleStyle = ui.NewStyle(ui.ColorYellow)
+			h.List.BorderStyle.Fg = ui.ColorYellow
+		}
+		h.updateLayout()
+		ui.Clear()
+	case "V":
+		h.ShowDiff = !h.ShowDiff
+		h.ShowDrift, h.ShowDiagnostics = false, false
+		if h.ShowDiff {
+			h.DiffWidget.SelectedRow = 0
+			h.FocusMode = "diff"
+			h.List.TitleStyle = ui.NewStyle(ui.ColorWhite)
+			h.List.BorderStyle.Fg = ui.ColorWhite
+		} else {
+			h.FocusMode = "list"
+			h.List.TitleStyle = ui.NewStyle(ui.ColorYellow)
+			h.List.BorderStyle.Fg = ui.ColorYellow
+		}
+		h.updateLayout()
+		ui.Clear()
+	case "L":
+		h.toggleDiagnostics(!h.ShowDiagnostics)
+		h.updateLayout()
+		ui.Clear()
+	case "t":
+		if h.FocusMode == "output" && h.Response != nil {
+			h.ShowTree = !h.ShowTree
+			h.Output.SelectedRow = 0
+			h.showResponse()
+		}
+	case "f":
+		if h.FocusMode == "output" && h.Response != nil {
+			h.InputMode = true
+			h.EditTarget = "query"
+			h.EditBuffer = h.Filter
+			h.ShowFiltered = true
+			h.QueryBar.Text = h.EditBuffer
+			h.refilter()
+			h.updateLayout()
+			ui.Clear()
+		}
+	case "s":
+		if h.FocusMode == "output" && h.Response != nil {
+			h.InputMode = true
+			h.EditTarget = "save"
+			h.EditBuffer = fmt.Sprintf("response-%s%s", time.Now().Format("20060102-150405"), render.Extension(h.Rendered.MediaType))
+			h.QueryBar.Title = "Save body to (Enter to save)"
+			h.QueryBar.BorderStyle.Fg = ui.ColorYellow
+			h.QueryBar.Text = h.EditBuffer
+			h.updateLayout()
+			ui.Clear()
+		}
+	case "/":
+		if h.FocusMode == "output" && h.Response != nil {
+			h.InputMode = true
+			h.EditTarget = "search"
+			h.EditBuffer = h.SearchQuery
+			h.SearchFrom = h.Output.SelectedRow
+			h.QueryBar.Text = h.EditBuffer
+			h.QueryBar.Title = "Search (regex, smart case, Enter to keep)"
+			h.QueryBar.BorderStyle.Fg = ui.ColorYellow
+			h.updateLayout()
+			ui.Clear()
+		}
+	case ":":
+		if h.FocusMode == "output" && h.Response != nil {
+			h.InputMode = true
+			h.EditTarget = "line"
+			h.EditBuffer = ""
+			h.QueryBar.Text = ""
+			h.QueryBar.Title = fmt.Sprintf("Go to line (1-%d, Enter to jump)", len(h.Output.Rows)-responseHeaderRows)
+			h.QueryBar.BorderStyle.Fg = ui.ColorYellow
+			h.updateLayout()
+			ui.Clear()
+		}
+	case "n", "N":
+		if h.FocusMode == "output" && len(h.Matches) > 0 {
+			if e.ID == "n" {
+				h.gotoMatch(h.MatchIndex + 1)
+			} else {
+				h.gotoMatch(h.MatchIndex - 1)
+			}
+		}
+	case "P":
+		if h.FocusMode == "output" && h.Response != nil {
+			h.pinResponse()
+		}
+	case "d":
+		if h.FocusMode == "output" && h.Response != nil {
+			if h.Baseline == nil {
+				h.Output.Title = "Response (pin a response with P or compare another base URL with E first)"
+			} else {
+				h.ShowCompare = !h.ShowCompare
+				h.Output.SelectedRow = 0
+				h.showResponse()
+			}
+		}
+	case "E":
+		if h.FocusMode == "output" && h.Response != nil && h.LastCall != nil {
+			h.InputMode = true
+			h.EditTarget = "compare"
+			h.EditBuffer = h.Config.CompareURL
+			h.QueryBar.Text = h.EditBuffer
+			h.QueryBar.Title = "Send the same request to base URL (Enter to compare)"
+			h.QueryBar.BorderStyle.Fg = ui.ColorYellow
+			h.updateLayout()
+			ui.Clear()
+		}
+	case "W":
+		if ep := h.selectedEndpoint(); h.FocusMode == "list" && ep != nil {
+			inputValues, headerValues := h.requestInputs(ep)
+			h.openSession(ep, inputValues, headerValues)
+		}
+	case "m":
+		if h.ShowSession && h.Session != nil {
+			h.InputMode = true
+			h.EditTarget = "frame"
+			h.EditBuffer = h.Frame
+			if h.EditBuffer == "" {
+				h.EditBuffer = h.BodyInput
+			}
+			if h.EditBuffer == "" {
+				h.EditBuffer = h.frameExample()
+			}
+			h.QueryBar.Text = h.EditBuffer
+			h.QueryBar.Title = "Send frame (text or JSON; /ping [data], /close [code] [reason])"
+			h.QueryBar.BorderStyle.Fg = ui.ColorYellow
+			h.updateLayout()
+			ui.Clear()
+		}
+	case "Q":
+		if ep := h.selectedEndpoint(); ep != nil && ep.GraphQL != "" {
+			h.InputMode = true
+			h.EditTarget = "graphql"
+			h.EditBuffer = h.graphqlQuery(ep)
+			h.QueryBar.Text = h.EditBuffer
+			h.QueryBar.Title = "GraphQL " + ep.GraphQL + " (Enter to send, B edits the variables)"
+			h.QueryBar.BorderStyle.Fg = ui.ColorYellow
+			h.updateLayout()
+			ui.Clear()
+		}
+	case "I":
+		if h.Config.GraphQLURL != "" {
+			h.showIntrospection()
+		}
+	case "X":
+		if h.ShowSession && h.Session != nil {
+			go h.Session.Close(1000, "")
+		}
+	case "]", "[":
+		if h.FocusMode == "output" && h.Response != nil && !h.Fetching {
+			if e.ID == "]" {
+				h.nextPage()
+			} else {
+				h.previousPage()
+			}
+		}
+	case "}":
+		if h.FocusMode == "output" && h.PageRule != nil && !h.Fetching {
+			h.InputMode = true
+			h.EditTarget = "pages"
+			h.EditBuffer = strconv.Itoa(defaultPageLimit)
+			h.QueryBar.Text = h.EditBuffer
+			h.QueryBar.Title = "Fetch all pages, at most (Enter to fetch)"
+			h.QueryBar.BorderStyle.Fg = ui.ColorYellow
+			h.updateLayout()
+			ui.Clear()
+		}
+	case "F":
+		if h.FocusMode == "output" && h.Filter != "" {
+			h.ShowFiltered = !h.ShowFiltered
+			h.refilter()
+			h.updateLayout()
+			ui.Clear()
+		}
+	case "T":
+		h.ShowHistory = !h.ShowHistory
+		h.ShowDrift = false
+		h.ShowDiff = false
+		h.ShowDiagnostics = false
+		if h.ShowHistory {
+			h.loadHistory()
+			h.FocusMode = "history"
+			h.List.TitleStyle = ui.NewStyle(ui.ColorWhite)
+			h.List.BorderStyle.Fg = ui.ColorWhite
+		} else {
+			h.FocusMode = "list"
+			h.List.TitleStyle = ui.NewStyle(ui.ColorYellow)
+			h.List.BorderStyle.Fg = ui.ColorYellow
+		}
+		h.updateLayout()
+		ui.Clear()
+	case "e":
+		if h.ShowDrift {
+			h.DriftWidget.Rows = append(h.DriftWidget.Rows, "", h.exportDrift())
+			h.DriftWidget.SelectedRow = len(h.DriftWidget.Rows) - 1
+		} else if h.FocusMode == "history" && h.HistoryWidget.SelectedRow < len(h.HistoryItems) {
+			h.HistoryWidget.Title = exportTranscript(h.HistoryItems[h.HistoryWidget.SelectedRow])
+		} else if h.FocusMode == "gemini" {
+			h.GeminiWidget.Rows = append(h.GeminiWidget.Rows, exportTranscript(h.Transcript))
+			h.GeminiWidget.SelectedRow = len(h.GeminiWidget.Rows) - 1
+		}
 	case "?", "h":
 		h.ShowHelp = true
 	}
@@ -728,7 +2040,134 @@ func (h *MainHandler) HandleEvent(e tui.Event) bool {
 	return false
 }
 
+// requestInputs collects the parameters and headers typed for ep, with the
+// global query params. A single value without "=" fills the only required
+// parameter.
+func (h *MainHandler) requestInputs(ep *model.Endpoint) (map[string]string, map[string]string) {
+	inputValues := map[string]string{}
+	// Initialize with global query params
+	for k, v := range h.GlobalQueryParams {
+		inputValues[k] = v
+	}
+
+	// Check for shorthand input
+	var requiredPathParams []string
+	var requiredQueryParams []string
+	for _, p := range ep.Parameters {
+		if p.Required && p.In == "path" {
+			requiredPathParams = append(requiredPathParams, p.Name)
+		} else if p.Required && p.In == "query" {
+			requiredQueryParams = append(requiredQueryParams, p.Name)
+		}
+	}
+
+	if h.QueryInput != "" && !strings.Contains(h.QueryInput, "=") && !strings.Contains(h.QueryInput, "&") {
+		if len(requiredPathParams) == 1 && len(requiredQueryParams) == 0 {
+			inputValues[requiredPathParams[0]] = h.QueryInput
+		} else if len(requiredPathParams) == 0 && len(requiredQueryParams) == 1 {
+			inputValues[requiredQueryParams[0]] = h.QueryInput
+		}
+	} else if h.QueryInput != "" {
+		// Simple query param parsing key=value&key2=value2
+		pairs := strings.Split(h.QueryInput, "&")
+		for _, p := range pairs {
+			kv := strings.SplitN(p, "=", 2)
+			if len(kv) == 2 {
+				inputValues[kv[0]] = kv[1]
+			}
+		}
+	}
+	return inputValues, h.headerValues()
+}
+
+// headerValues parses the Headers input, "Key: value" or key=value pairs
+// separated by & or ;
+func (h *MainHandler) headerValues() map[string]string {
+	headerValues := map[string]string{}
+	if h.HeaderInput != "" {
+		pairs := strings.FieldsFunc(h.HeaderInput, func(r rune) bool {
+			return r == '&' || r == ';'
+		})
+		for _, p := range pairs {
+			p = strings.TrimSpace(p)
+			if p == "" {
+				continue
+			}
+			kv := strings.SplitN(p, ":", 2)
+			if len(kv) == 2 {
+				key := strings.TrimSpace(kv[0])
+				val := strings.TrimSpace(kv[1])
+				if key != "" {
+					headerValues[key] = val
+				}
+				continue
+			}
+			kv = strings.SplitN(p, "=", 2)
+			if len(kv) == 2 {
+				key := strings.TrimSpace(kv[0])
+				val := strings.TrimSpace(kv[1])
+				if key != "" {
+					headerValues[key] = val
+				}
+			}
+		}
+	}
+	return headerValues
+}
+
+// aiProviderName returns "provider/model" for the configured assistant
+func aiProviderName(cfg *config.Config) string {
+	provider := cfg.AIProvider
+	if provider == "" {
+		provider = config.DefaultAIProvider
+	}
+	model := cfg.AIModel
+	if model == "" {
+		model = ai.DefaultModels[provider]
+	}
+	return provider + "/" + model
+}
+
+// exportDrift writes the drift report as Markdown and JSON to the working
+// directory and returns a status line for the drift pane
+func (h *MainHandler) exportDrift() string {
+	report := h.Drift.Report()
+	for name, write := range map[string]func(io.Writer) error{
+		"drift-report.md":   report.WriteMarkdown,
+		"drift-report.json": report.WriteJSON,
+	} {
+		f, err := os.Create(name)
+		if err != nil {
+			return "[Export failed: " + err.Error() + "](fg:red)"
+		}
+		err = write(f)
+		f.Close()
+		if err != nil {
+			return "[Export failed: " + err.Error() + "](fg:red)"
+		}
+	}
+	return "[Exported drift-report.md and drift-report.json](fg:green)"
+}
+
 func main() {
+	// dispatch subcommands before parsing the TUI flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "run":
+			os.Exit(runCollection(os.Args[2:]))
+		case "mock":
+			os.Exit(runMock(os.Args[2:]))
+		case "proxy":
+			os.Exit(runProxy(os.Args[2:]))
+		case "drift":
+			os.Exit(runDrift(os.Args[2:]))
+		case "diff":
+			os.Exit(runDiff(os.Args[2:]))
+		case "query":
+			os.Exit(runQuery(os.Args[2:]))
+		}
+	}
+
 	// parse CLI flags
 	fileFlag := flag.String("file", config.DefaultOpenAPIFile, "path to OpenAPI file")
 	var urlFlags stringSlice
@@ -736,6 +2175,21 @@ func main() {
 	var queryFlags stringSlice
 	flag.Var(&queryFlags, "q", "Global query param key=value (can be repeated)")
 	flag.Var(&queryFlags, "query", "Global query param key=value (can be repeated)")
+	diffAgainst := flag.String("diff-against", "", "older OpenAPI spec to compare the loaded spec with")
+	driftHistory := flag.String("drift-history", os.Getenv("API_TERM_DRIFT_HISTORY"), "cassette file responses are kept in, so the drift report covers earlier sessions (env API_TERM_DRIFT_HISTORY)")
+	aiProvider := flag.String("ai-provider", os.Getenv("API_TERM_AI_PROVIDER"), "AI provider: gemini, openai or ollama (env API_TERM_AI_PROVIDER)")
+	aiModel := flag.String("ai-model", os.Getenv("API_TERM_AI_MODEL"), "AI model, defaults per provider (env API_TERM_AI_MODEL)")
+	aiBaseURL := flag.String("ai-base-url", os.Getenv("API_TERM_AI_BASE_URL"), "base URL for openai/ollama providers (env API_TERM_AI_BASE_URL)")
+	redactionPolicy := flag.String("redaction", os.Getenv("API_TERM_REDACTION"), "YAML redaction policy for data sent to AI providers (env API_TERM_REDACTION)")
+	transcriptDir := flag.String("transcripts", os.Getenv("API_TERM_TRANSCRIPTS"), "directory for saved AI conversations, default ~/.api-term/transcripts (env API_TERM_TRANSCRIPTS)")
+	paginationFile := flag.String("pagination", os.Getenv("API_TERM_PAGINATION"), "YAML pagination rules per operationId or \"METHOD /path\" (env API_TERM_PAGINATION)")
+	grpcTarget := flag.String("grpc", os.Getenv("API_TERM_GRPC"), "gRPC server host:port whose methods are listed with the endpoints; grpcs:// for TLS (env API_TERM_GRPC)")
+	grpcDescriptors := flag.String("grpc-descriptors", os.Getenv("API_TERM_GRPC_DESCRIPTORS"), "descriptor set from protoc --include_imports -o, used instead of server reflection (env API_TERM_GRPC_DESCRIPTORS)")
+	graphqlURL := flag.String("graphql", os.Getenv("API_TERM_GRAPHQL"), "GraphQL endpoint URL, or a path under the base URL, whose operations are listed with the endpoints (env API_TERM_GRAPHQL)")
+	compareURL := flag.String("compare-url", os.Getenv("API_TERM_COMPARE_URL"), "base URL suggested when comparing a response with another environment (env API_TERM_COMPARE_URL)")
+	strict := flag.Bool("strict", false, "exit when a spec fails to load or validate instead of showing the diagnostics")
+	var diffIgnore stringSlice
+	flag.Var(&diffIgnore, "diff-ignore", "field name glob or $.path left out of response diffs, e.g. updatedAt or '$.items[*].etag' (can be repeated, env API_TERM_DIFF_IGNORE as a comma list)")
 	flag.Parse()
 
 	globalQueryParams := make(map[string]string)
@@ -747,13 +2201,88 @@ func main() {
 	}
 
 	cfg := config.New(*fileFlag, urlFlags, globalQueryParams)
+	cfg.DiffAgainst = *diffAgainst
+	cfg.DriftHistory = *driftHistory
+	if *aiProvider != "" {
+		cfg.AIProvider = *aiProvider
+	}
+	cfg.AIModel = *aiModel
+	cfg.AIBaseURL = *aiBaseURL
+	if *transcriptDir != "" {
+		cfg.TranscriptDir = *transcriptDir
+	}
+	cfg.RedactionPolicy = *redactionPolicy
+	cfg.CompareURL = *compareURL
+	cfg.PaginationFile = *paginationFile
+	cfg.GRPCTarget = *grpcTarget
+	cfg.GRPCDescriptors = *grpcDescriptors
+	cfg.GraphQLURL = *graphqlURL
+	if env := os.Getenv("API_TERM_DIFF_IGNORE"); env != "" {
+		cfg.DiffIgnore = strings.Split(env, ",")
+	}
+	cfg.DiffIgnore = append(cfg.DiffIgnore, diffIgnore...)
 
 	// Load OpenAPI endpoints
 	// For backward compatibility, treat fileFlag as a single file in a slice
 	files := []string{cfg.OpenAPIFile}
-	endpoints := parser.ParseOpenAPI(files, cfg.OpenAPIURLs)
+	docs, diags := parser.LoadDocuments(files, cfg.OpenAPIURLs)
+	if *strict && len(diags) > 0 {
+		printDiagnostics(diags)
+		os.Exit(1)
+	}
+	var endpoints []*model.Endpoint
+	for _, doc := range docs {
+		endpoints = append(endpoints, parser.Endpoints(doc)...)
+	}
 
-	handler := NewMainHandler(cfg, endpoints)
+	var grpcClient *grpcclient.Client
+	if cfg.GRPCTarget != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), grpcTimeout)
+		c, err := grpcclient.Dial(ctx, cfg.GRPCTarget, cfg.GRPCDescriptors)
+		cancel()
+		if err != nil {
+			log.Fatalf("Failed to load gRPC methods from %s: %v", cfg.GRPCTarget, err)
+		}
+		grpcClient = c
+		endpoints = append(endpoints, grpcEndpoints(c)...)
+	}
+	// List the webhooks of all documents after their endpoints
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		return endpoints[i].Webhook == "" && endpoints[j].Webhook != ""
+	})
+
+	handler := NewMainHandler(cfg, endpoints, docs, diags)
+	handler.GRPC = grpcClient
+	if parser.HasErrors(handler.Diagnostics) || len(endpoints) == 0 {
+		handler.toggleDiagnostics(true)
+	}
+	if cfg.GraphQLURL != "" {
+		handler.showIntrospection()
+	}
+	if cfg.RedactionPolicy != "" {
+		policy, err := redact.LoadPolicy(cfg.RedactionPolicy)
+		if err == nil {
+			handler.Redactor, err = redact.New(policy)
+		}
+		if err != nil {
+			log.Fatalf("Failed to load redaction policy %s: %v", cfg.RedactionPolicy, err)
+		}
+	}
+	if cfg.DriftHistory != "" {
+		history, err := loadDriftHistory(cfg.DriftHistory)
+		if err != nil {
+			log.Fatalf("Failed to load drift history %s: %v", cfg.DriftHistory, err)
+		}
+		observeCassette(handler.Drift, history)
+		handler.DriftHistory = history
+	}
+	if cfg.PaginationFile != "" {
+		pagination, err := paginate.LoadConfig(cfg.PaginationFile)
+		if err != nil {
+			log.Fatalf("Failed to load pagination rules %s: %v", cfg.PaginationFile, err)
+		}
+		handler.Pagination = pagination
+	}
 	app := tui.NewApp(handler)
 
 	if err := app.Run(); err != nil {
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	ui "github.com/gizak/termui/v3"
+	"github.com/gorilla/websocket"
+	"org.subh/api-term/pkgs/ai"
+	"org.subh/api-term/pkgs/api/model"
+	"org.subh/api-term/pkgs/config"
+)
+
+// newTestHandler builds a handler laid out on an 120x40 screen; termui draws
+// nowhere until ui.Init, so keys can be pressed without a terminal
+func newTestHandler(t *testing.T, endpoints ...*model.Endpoint) *MainHandler {
+	t.Helper()
+	cfg := config.New("", nil, nil)
+	cfg.TranscriptDir = t.TempDir()
+	h := NewMainHandler(cfg, endpoints, nil, nil)
+	h.Init(120, 40)
+	return h
+}
+
+func press(h *MainHandler, keys ...string) {
+	for _, key := range keys {
+		h.HandleEvent(ui.Event{Type: ui.KeyboardEvent, ID: key})
+		h.Render()
+	}
+}
+
+func TestKeysWithoutEndpoints(t *testing.T) {
+	for _, key := range []string{"C", "B", "<Enter>", "W", "Q", "j", "k", "<Tab>", "L", "D", "V"} {
+		t.Run(key, func(t *testing.T) {
+			h := newTestHandler(t)
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("pressing %s with no endpoints panicked: %v", key, r)
+				}
+			}()
+			press(h, key)
+		})
+	}
+}
+
+func TestProposalWithProblemsIsNotSent(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer ts.Close()
+
+	ep := &model.Endpoint{Method: "DELETE", Path: "/models/{id}", Parameters: []*model.Parameter{{Name: "id", In: "path", Required: true}}}
+	h := newTestHandler(t, ep)
+	h.BaseURL = ts.URL
+	h.Proposal = []*ai.ProposedRequest{{Endpoint: ep, Params: map[string]string{}, Problems: []string{"missing required path parameter id"}}}
+	h.focusAI()
+	press(h, "y")
+
+	time.Sleep(50 * time.Millisecond)
+	if calls.Load() != 0 {
+		t.Fatalf("a proposal the spec rejects was sent %d times", calls.Load())
+	}
+	if h.Proposal != nil || !strings.Contains(strings.Join(h.GeminiWidget.Rows, "\n"), "Not run") {
+		t.Errorf("expected the proposal to be refused, got %v", h.GeminiWidget.Rows)
+	}
+
+	h.Proposal = []*ai.ProposedRequest{{Endpoint: ep, Params: map[string]string{"id": "m-1"}}}
+	h.focusAI()
+	press(h, "y")
+	for deadline := time.Now().Add(time.Second); calls.Load() == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected a valid proposal to be sent once, got %d", calls.Load())
+	}
+}
+
+func TestProposalKeysOnlyInAIPane(t *testing.T) {
+	ep := &model.Endpoint{Method: "GET", Path: "/models"}
+	h := newTestHandler(t, ep)
+	h.Proposal = []*ai.ProposedRequest{{Endpoint: ep, Params: map[string]string{}}}
+	h.FocusMode = "output"
+	press(h, "n", "y")
+	if h.Proposal == nil {
+		t.Fatal("y and n outside the AI pane should not answer the proposal")
+	}
+	h.focusAI()
+	press(h, "n")
+	if h.Proposal != nil {
+		t.Error("n in the AI pane should discard the proposal")
+	}
+}
+
+func TestPreviewKeysOnlyInAIPane(t *testing.T) {
+	h := newTestHandler(t)
+	h.Redactor.Policy.Preview = true
+	sent := false
+	h.confirmSend("hello", func() { sent = true })
+	if h.FocusMode != "gemini" {
+		t.Fatalf("the preview should focus the AI pane, focus is %s", h.FocusMode)
+	}
+	h.FocusMode = "output"
+	press(h, "n", "y")
+	if sent || h.PendingSend == nil {
+		t.Fatal("y and n outside the AI pane should not answer the preview")
+	}
+	h.focusAI()
+	press(h, "y")
+	if !sent {
+		t.Error("y in the AI pane should send the message")
+	}
+}
+
+// waitFor presses keys until cond holds, so the handler is used while
+// streams and sessions update it from their readers
+func waitFor(t *testing.T, h *MainHandler, cond func() bool, keys ...string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		h.mu.Lock()
+		ok := cond()
+		h.mu.Unlock()
+		if ok {
+			return
+		}
+		press(h, keys...)
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("timed out")
+}
+
+func TestStreamUpdatesWhileHandlingKeys(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 50; i++ {
+			fmt.Fprintf(w, "event: tick\ndata: %d\n\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+
+	h := newTestHandler(t, &model.Endpoint{Method: "GET", Path: "/events"})
+	h.BaseURL = ts.URL
+	press(h, "<Enter>")
+	waitFor(t, h, func() bool { return h.StreamStatus == "ended" }, "<Tab>", "j", "k", "<Tab>")
+	if len(h.Events) != 50 {
+		t.Errorf("expected 50 events, got %d", len(h.Events))
+	}
+}
+
+func TestSessionUpdatesWhileHandlingKeys(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for i := 0; i < 50; i++ {
+			conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("tick %d", i)))
+			time.Sleep(time.Millisecond)
+		}
+		for {
+			kind, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(kind, data)
+		}
+	}))
+	defer ts.Close()
+
+	h := newTestHandler(t, &model.Endpoint{Method: "GET", Path: "/ws", WebSocket: true})
+	h.BaseURL = ts.URL
+	press(h, "W")
+	logged := func(text string) func() bool {
+		return func() bool { return strings.Contains(strings.Join(h.Output.Rows, "\n"), text) }
+	}
+	waitFor(t, h, logged("tick 49"), "<Tab>", "j", "k", "<Tab>")
+
+	// sending logs the frame from within the key handler
+	h.Frame = "hello"
+	done := make(chan struct{})
+	go func() {
+		press(h, "m", "<Enter>")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sending a frame blocked the key handler")
+	}
+	waitFor(t, h, logged("[←](fg:cyan) text      hello"))
+	h.mu.Lock()
+	h.closeSession()
+	h.mu.Unlock()
+}
+
+func TestComposeWhileHandlingKeys(t *testing.T) {
+	h := newTestHandler(t, &model.Endpoint{Method: "GET", Path: "/models"})
+	h.GeminiChat = &ai.FakeSession{Replies: []*ai.Reply{{ToolCalls: []ai.ToolCall{{Name: "get_models"}}}}}
+	go h.composeRequests("list the models")
+	waitFor(t, h, func() bool { return h.Proposal != nil && h.GeminiCancel == nil }, "j", "k")
+	if len(h.Proposal) != 1 || h.Proposal[0].Endpoint.Path != "/models" {
+		t.Errorf("unexpected proposal %v", h.Proposal)
+	}
+}
+
+// heldSession answers once release is closed, counting the messages sent
+type heldSession struct {
+	release chan struct{}
+	sent    atomic.Int32
+}
+
+func (s *heldSession) Send(ctx context.Context, message string) (*ai.Reply, error) {
+	return s.Stream(ctx, message, func(string) {})
+}
+
+func (s *heldSession) Stream(ctx context.Context, message string, onToken func(string)) (*ai.Reply, error) {
+	s.sent.Add(1)
+	onToken("thinking")
+	<-s.release
+	onToken(" done")
+	return &ai.Reply{Text: "thinking done"}, nil
+}
+
+func TestAskWhileAnswering(t *testing.T) {
+	h := newTestHandler(t)
+	session := &heldSession{release: make(chan struct{})}
+	h.GeminiChat = session
+	ask := func(question string) {
+		h.InputMode, h.EditTarget, h.EditBuffer = true, "gemini", question
+		press(h, "<Enter>")
+	}
+	ask("why?")
+	ask("and then?")
+	close(session.release)
+	waitFor(t, h, func() bool {
+		return h.GeminiCancel == nil && strings.Contains(strings.Join(h.GeminiWidget.Rows, "\n"), "tokens")
+	}, "j", "k")
+	if session.sent.Load() != 1 {
+		t.Errorf("a question was sent while the previous answer was streaming: %d sent", session.sent.Load())
+	}
+	if !strings.Contains(strings.Join(h.GeminiWidget.Rows, "\n"), "thinking done") {
+		t.Errorf("answer not shown: %v", h.GeminiWidget.Rows)
+	}
+}
+
+func TestFetchAllPagesDroppedForNewerResponse(t *testing.T) {
+	release := make(chan struct{})
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/other" {
+			fmt.Fprint(w, `{"other":true}`)
+			return
+		}
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", "<"+ts.URL+"/items?page=2>; rel=\"next\"")
+		}
+		if r.Header.Get("X-Fetch-All") != "" {
+			<-release
+		}
+		fmt.Fprint(w, `[{"id":1}]`)
+	}))
+	defer ts.Close()
+
+	h := newTestHandler(t, &model.Endpoint{Method: "GET", Path: "/items"}, &model.Endpoint{Method: "GET", Path: "/other"})
+	h.BaseURL = ts.URL
+	press(h, "<Enter>")
+	if h.PageRule == nil {
+		t.Fatal("pagination not detected")
+	}
+	h.LastCall.Headers = map[string]string{"X-Fetch-All": "1"}
+	h.FocusMode = "output"
+	press(h, "}", "<Enter>")
+
+	h.mu.Lock()
+	h.FocusMode = "list"
+	h.mu.Unlock()
+	press(h, "j", "<Enter>")
+	close(release)
+	waitFor(t, h, func() bool { return !h.Fetching })
+	if !strings.HasSuffix(h.Response.URL, "/other") || h.AllPages != "" {
+		t.Errorf("merged pages replaced the newer response: %s %q", h.Response.URL, h.AllPages)
+	}
+}
+
+func TestOpenTranscriptWhileHandlingKeys(t *testing.T) {
+	h := newTestHandler(t)
+	h.Assistant = ai.NewOpenAIClient("http://127.0.0.1:0", "m")
+	saved := ai.NewTranscript("openai", nil)
+	saved.Add(ai.RoleUser, "why?")
+	saved.Add(ai.RoleAssistant, "because")
+	if err := h.Transcripts.Save(saved); err != nil {
+		t.Fatal(err)
+	}
+	press(h, "T", "<Enter>")
+	waitFor(t, h, func() bool { return h.GeminiChat != nil }, "j", "k")
+	if h.Transcript == nil || h.Transcript.ID != saved.ID {
+		t.Errorf("transcript not resumed: %+v", h.Transcript)
+	}
+	if !strings.Contains(strings.Join(h.GeminiWidget.Rows, "\n"), "because") {
+		t.Errorf("conversation not shown: %v", h.GeminiWidget.Rows)
+	}
+}
+
+func TestProposalResolvedBodyIsChecked(t *testing.T) {
+	var posts atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			posts.Add(1)
+		}
+		fmt.Fprint(w, `{"name":5}`)
+	}))
+	defer ts.Close()
+
+	schema := openapi3.NewObjectSchema().WithProperty("name", openapi3.NewStringSchema())
+	create := openapi3.NewOperation()
+	create.RequestBody = &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().WithJSONSchema(schema)}
+	list := &model.Endpoint{Method: "GET", Path: "/models"}
+	post := &model.Endpoint{Method: "POST", Path: "/models"}
+	h := newTestHandler(t, list, post)
+	h.BaseURL = ts.URL
+	h.Proposal = []*ai.ProposedRequest{
+		{Endpoint: list, Params: map[string]string{}},
+		{Endpoint: post, Operation: create, Params: map[string]string{}, Body: `{"name":"{{step1.name}}"}`, ContentType: "application/json"},
+	}
+	h.focusAI()
+	press(h, "y")
+	waitFor(t, h, func() bool { return strings.Contains(strings.Join(h.GeminiWidget.Rows, "\n"), "not run") })
+	if posts.Load() != 0 {
+		t.Errorf("a resolved body the spec rejects was sent")
+	}
+}
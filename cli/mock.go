@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"org.subh/api-term/pkgs/api/parser"
+	"org.subh/api-term/pkgs/config"
+	"org.subh/api-term/pkgs/mock"
+)
+
+// runMock implements `api-term mock --file api.yaml --port 8080` and returns the exit code
+func runMock(args []string) int {
+	fs := flag.NewFlagSet("mock", flag.ExitOnError)
+	fileFlag := fs.String("file", config.DefaultOpenAPIFile, "path to OpenAPI file")
+	urlFlag := fs.String("url", "", "URL to OpenAPI spec (overrides --file)")
+	port := fs.Int("port", 8080, "port to listen on")
+	noValidate := fs.Bool("no-validate", false, "do not validate incoming requests against the spec")
+	var statusFlags stringSlice
+	fs.Var(&statusFlags, "status", "status override operation=code, operation is an operationId or \"METHOD /path\" (can be repeated)")
+	var latencyFlags stringSlice
+	fs.Var(&latencyFlags, "latency", "latency to inject, either duration or operation=duration (can be repeated)")
+	fs.Parse(args)
+
+	source := *fileFlag
+	if *urlFlag != "" {
+		source = *urlFlag
+	}
+	doc, diags, err := parser.LoadDocument(source)
+	printDiagnostics(diags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load spec %s: %v\n", source, err)
+		return 2
+	}
+
+	opts := mock.Options{
+		StatusOverrides:  make(map[string]int),
+		LatencyOverrides: make(map[string]time.Duration),
+		SkipValidation:   *noValidate,
+	}
+	for _, s := range statusFlags {
+		key, val, ok := splitOverride(s)
+		code, err := strconv.Atoi(val)
+		if !ok || err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --status %q, expected operation=code\n", s)
+			return 2
+		}
+		opts.StatusOverrides[key] = code
+	}
+	for _, l := range latencyFlags {
+		key, val, ok := splitOverride(l)
+		if !ok {
+			val = l
+		}
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --latency %q: %v\n", l, err)
+			return 2
+		}
+		if ok {
+			opts.LatencyOverrides[key] = d
+		} else {
+			opts.Latency = d
+		}
+	}
+
+	addr := fmt.Sprintf(":%d", *port)
+	fmt.Printf("Mock server for %s listening on http://localhost%s\n", source, addr)
+	if err := http.ListenAndServe(addr, mock.NewServer(doc, opts)); err != nil {
+		fmt.Fprintf(os.Stderr, "Mock server failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// splitOverride splits "operation=value" on the last '=' so operation keys may contain spaces
+func splitOverride(s string) (string, string, bool) {
+	i := strings.LastIndex(s, "=")
+	if i <= 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]), true
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	ui "github.com/gizak/termui/v3"
+	"org.subh/api-term/pkgs/ai"
+	"org.subh/api-term/pkgs/api/client"
+	"org.subh/api-term/pkgs/api/model"
+	"org.subh/api-term/pkgs/paginate"
+	"org.subh/api-term/pkgs/render"
+)
+
+// defaultPageLimit is the page limit suggested for fetching all pages
+const defaultPageLimit = 20
+
+// startPaging makes resp the first page of ep, using the configured rule
+// for the operation or one detected from the response
+func (h *MainHandler) startPaging(ep *model.Endpoint, params map[string]string, resp *client.Response) {
+	h.PageRule, h.Pages, h.AllPages = nil, nil, ""
+	if resp == nil || ep.GraphQL != "" {
+		return
+	}
+	operationID := ""
+	if op := h.findOperation(ep); op != nil {
+		operationID = op.OperationID
+	}
+	h.PageRule = h.Pagination.Rule(operationID, ep.Method, ep.Path)
+	if h.PageRule == nil {
+		h.PageRule = paginate.Detect(ep, resp.Header, resp.Body)
+	}
+	h.PageEndpoint = ep
+	h.Pages = []*paginate.Request{{Params: params}}
+}
+
+// pageTitle describes the current page for the Response pane title
+func (h *MainHandler) pageTitle() string {
+	if h.PageRule == nil || len(h.Pages) == 0 {
+		return ""
+	}
+	if h.AllPages != "" {
+		return fmt.Sprintf(" - %s, ]/[ back to page %d", h.AllPages, len(h.Pages))
+	}
+	return fmt.Sprintf(" - page %d by %s, ]/[ next/prev, } all", len(h.Pages), h.PageRule.Describe())
+}
+
+// fetchPage requests a page of the paginated operation, with the headers
+// and body of the first request
+func (h *MainHandler) fetchPage(req *paginate.Request) (*client.Response, error) {
+	return requestPage(h.BaseURL, h.PageEndpoint, h.LastCall, req)
+}
+
+// requestPage requests a page of ep with the headers and body of call
+func requestPage(baseURL string, ep *model.Endpoint, call *ai.CallContext, req *paginate.Request) (*client.Response, error) {
+	if req.URL != "" {
+		return client.Do(ep.Method, req.URL, call.Headers, call.Body, call.ContentType)
+	}
+	return client.Invoke(baseURL, ep, req.Params, call.Headers, call.Body, call.ContentType)
+}
+
+// showPage fetches and shows the page on top of the page stack
+func (h *MainHandler) showPage() {
+	req := h.Pages[len(h.Pages)-1]
+	call := h.LastCall
+	resp, err := h.fetchPage(req)
+	h.AllPages = ""
+	h.showResult(h.PageEndpoint, req.Params, call.Headers, call.Body, call.ContentType, resp, err)
+}
+
+// nextPage follows the pagination of the current response
+func (h *MainHandler) nextPage() {
+	if h.PageRule == nil || len(h.Pages) == 0 {
+		h.Output.Title = "Response (no pagination detected, configure it with --pagination)"
+		return
+	}
+	if h.AllPages != "" {
+		h.showPage()
+		return
+	}
+	cur := h.Pages[len(h.Pages)-1]
+	req, ok := h.PageRule.NextPage(cur.Params, h.Response.URL, h.Response.Header, h.Response.Body)
+	if !ok {
+		h.showResponse()
+		h.Output.Title += " (last page)"
+		return
+	}
+	if req.Params == nil {
+		req.Params = cur.Params
+	}
+	h.Pages = append(h.Pages, req)
+	h.showPage()
+}
+
+// previousPage fetches the page before the current one again
+func (h *MainHandler) previousPage() {
+	if h.PageRule == nil || len(h.Pages) == 0 {
+		return
+	}
+	if h.AllPages == "" {
+		if len(h.Pages) == 1 {
+			h.showResponse()
+			h.Output.Title += " (first page)"
+			return
+		}
+		h.Pages = h.Pages[:len(h.Pages)-1]
+	}
+	h.showPage()
+}
+
+// fetchAllPages fetches up to limit pages from the first one in the
+// background and shows their items concatenated into one JSON array. The
+// pages are dropped if another response is shown before they arrive.
+func (h *MainHandler) fetchAllPages(limit string) {
+	n, err := strconv.Atoi(strings.TrimSpace(limit))
+	if err != nil || n < 1 {
+		h.Output.Title = fmt.Sprintf("Response (not a page limit: %q)", limit)
+		return
+	}
+	h.Fetching = true
+	ep, rule, call, baseURL, shown := h.PageEndpoint, h.PageRule, h.LastCall, h.BaseURL, h.Response
+	req := h.Pages[0]
+	// stale reports whether another response was shown since the fetch
+	// started; h.mu must be held
+	stale := func() bool {
+		return h.Response != shown || h.PageEndpoint != ep
+	}
+	// status shows progress or a failure in the Response pane title
+	status := func(title string) bool {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if stale() {
+			return false
+		}
+		h.Output.Title = title
+		ui.Render(h.Output)
+		return true
+	}
+	go func() {
+		defer func() {
+			h.mu.Lock()
+			h.Fetching = false
+			h.mu.Unlock()
+		}()
+		var items []interface{}
+		pages, more := 0, false
+		var last *client.Response
+		for {
+			if !status(fmt.Sprintf("Response (fetching page %d of at most %d, %d items so far)", pages+1, n, len(items))) {
+				return
+			}
+			resp, err := requestPage(baseURL, ep, call, req)
+			if err != nil {
+				status(fmt.Sprintf("Response (fetching page %d failed: %s)", pages+1, err.Error()))
+				return
+			}
+			h.mu.Lock()
+			h.Drift.Observe(ep.Method, ep.Path, resp.StatusCode, resp.Header, resp.Body)
+			h.mu.Unlock()
+			if resp.StatusCode >= 400 {
+				status(fmt.Sprintf("Response (page %d returned status %d)", pages+1, resp.StatusCode))
+				return
+			}
+			items = append(items, rule.PageItems(resp.Body)...)
+			pages++
+			last = resp
+			next, ok := rule.NextPage(req.Params, resp.URL, resp.Header, resp.Body)
+			if !ok {
+				break
+			}
+			if next.Params == nil {
+				next.Params = req.Params
+			}
+			if pages == n {
+				more = true
+				break
+			}
+			req = next
+		}
+
+		if items == nil {
+			items = []interface{}{}
+		}
+		data, _ := json.MarshalIndent(items, "", "  ")
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if stale() {
+			return
+		}
+		header := http.Header{}
+		header.Set("Content-Type", "application/json")
+		h.Response = &client.Response{Body: string(data), StatusCode: last.StatusCode, Header: header, URL: last.URL}
+		h.Rendered = render.Body("application/json", data)
+		h.AllPages = fmt.Sprintf("%d %s from %d %s", len(items), plural(len(items), "item"), pages, plural(pages, "page"))
+		if more {
+			h.AllPages += ", stopped at the limit"
+		}
+		h.applyFilter()
+		h.showResponse()
+		h.Output.SelectedRow = 0
+		ui.Render(h.Output)
+	}()
+}
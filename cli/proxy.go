@@ -0,0 +1,328 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+	"org.subh/api-term/pkgs/api/parser"
+	"org.subh/api-term/pkgs/proxy"
+	"org.subh/api-term/pkgs/render"
+	"org.subh/api-term/pkgs/tui"
+)
+
+// runProxy implements `api-term proxy`, recording traffic to a target or
+// replaying a cassette, and returns the exit code
+func runProxy(args []string) int {
+	fs := flag.NewFlagSet("proxy", flag.ExitOnError)
+	target := fs.String("target", "", "upstream base URL to forward traffic to")
+	port := fs.Int("port", 8080, "port to listen on")
+	cassettePath := fs.String("cassette", "cassette.json", "cassette file to record to or replay from")
+	replay := fs.Bool("replay", false, "serve recorded responses from the cassette instead of forwarding")
+	specFile := fs.String("file", "", "OpenAPI spec used to match exchanges to operations")
+	specURL := fs.String("url", "", "URL to OpenAPI spec used to match exchanges to operations")
+	noTUI := fs.Bool("no-tui", false, "log exchanges to stdout instead of showing the TUI")
+	fs.Parse(args)
+
+	var doc *openapi3.T
+	if source := firstNonEmpty(*specURL, *specFile); source != "" {
+		var diags []parser.Diagnostic
+		var err error
+		doc, diags, err = parser.LoadDocument(source)
+		printDiagnostics(diags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load spec %s: %v\n", source, err)
+			return 2
+		}
+	}
+
+	// notify and notifyError are swapped for the TUI sinks once the mode is
+	// known; errors are logged to stderr otherwise
+	notify := func(*proxy.Exchange) {}
+	onExchange := func(ex *proxy.Exchange) { notify(ex) }
+	var notifyError func(error)
+	onError := func(err error) {
+		if notifyError != nil {
+			notifyError(err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Proxy error: %v\n", err)
+		}
+	}
+
+	var handler http.Handler
+	mode := ""
+	if *replay {
+		cassette, err := proxy.LoadCassette(*cassettePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load cassette: %v\n", err)
+			return 2
+		}
+		replayer := proxy.NewReplayer(cassette)
+		replayer.OnExchange = onExchange
+		handler = replayer
+		mode = fmt.Sprintf("Replaying %s (%d exchanges)", *cassettePath, len(cassette.Exchanges))
+	} else {
+		if *target == "" {
+			fmt.Fprintln(os.Stderr, "--target is required unless --replay is set")
+			return 2
+		}
+		targetURL, err := url.Parse(*target)
+		if err != nil || targetURL.Host == "" {
+			fmt.Fprintf(os.Stderr, "Invalid --target %q\n", *target)
+			return 2
+		}
+		recorder := proxy.NewRecorder(targetURL, doc, *cassettePath)
+		recorder.OnExchange = onExchange
+		recorder.OnError = onError
+		handler = recorder
+		mode = fmt.Sprintf("Recording %s -> %s", targetURL, *cassettePath)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to listen: %v\n", err)
+		return 1
+	}
+	status := fmt.Sprintf("%s on http://localhost:%d", mode, *port)
+
+	if *noTUI {
+		notify = func(ex *proxy.Exchange) {
+			fmt.Println(formatExchangeRow(ex))
+		}
+		fmt.Println(status)
+		if err := http.Serve(listener, handler); err != nil {
+			fmt.Fprintf(os.Stderr, "Proxy failed: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	ph := NewProxyHandler(status)
+	notify = ph.AddExchange
+	notifyError = ph.ShowError
+	// exchanges arriving before the TUI starts are drawn by its first render
+	go http.Serve(listener, handler)
+	if err := tui.NewApp(ph).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Action failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func formatExchangeRow(ex *proxy.Exchange) string {
+	path := ex.Request.Path
+	if ex.Request.Query != "" {
+		path += "?" + ex.Request.Query
+	}
+	op := ex.Operation
+	if op == "" {
+		op = "unmatched"
+	}
+	return fmt.Sprintf("%s %s %s %d %s [%s]", ex.RecordedAt.Format("15:04:05"), ex.Request.Method, path, ex.Response.Status, ex.Duration.Round(time.Millisecond), op)
+}
+
+// ProxyHandler shows the exchanges seen by the proxy in a list with a detail pane
+type ProxyHandler struct {
+	Exchanges []*proxy.Exchange
+
+	// UI Widgets
+	Status *widgets.Paragraph
+	List   *widgets.List
+	Detail *widgets.List
+
+	// State
+	FocusMode string
+	status    string
+	// ready is set once the terminal is initialised; renders are dropped before
+	ready bool
+	mu    sync.Mutex
+}
+
+func NewProxyHandler(status string) *ProxyHandler {
+	statusWidget := widgets.NewParagraph()
+	statusWidget.Title = "Proxy (q to quit)"
+	statusWidget.Text = status
+	statusWidget.BorderStyle.Fg = ui.ColorMagenta
+
+	list := widgets.NewList()
+	list.Title = "Exchanges (j/k to scroll, Tab to focus detail)"
+	list.Rows = []string{}
+	list.TextStyle = ui.NewStyle(ui.ColorYellow)
+	list.WrapText = false
+	list.TitleStyle = ui.NewStyle(ui.ColorYellow)
+	list.BorderStyle.Fg = ui.ColorYellow
+
+	detail := widgets.NewList()
+	detail.Title = "Exchange"
+	detail.Rows = []string{"Waiting for traffic..."}
+	detail.WrapText = true
+	detail.TextStyle = ui.NewStyle(ui.ColorWhite)
+	detail.SelectedRowStyle = ui.NewStyle(ui.ColorWhite, ui.ColorBlack)
+	detail.BorderStyle.Fg = ui.ColorWhite
+
+	return &ProxyHandler{
+		Status:    statusWidget,
+		List:      list,
+		Detail:    detail,
+		FocusMode: "list",
+		status:    status,
+	}
+}
+
+func (h *ProxyHandler) Init(termWidth, termHeight int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.layout(termWidth, termHeight)
+	h.ready = true
+}
+
+func (h *ProxyHandler) layout(termWidth, termHeight int) {
+	h.Status.SetRect(0, 0, termWidth, 3)
+	h.List.SetRect(0, 3, termWidth/2, termHeight)
+	h.Detail.SetRect(termWidth/2, 3, termWidth, termHeight)
+}
+
+// AddExchange appends an exchange and redraws; safe to call from server goroutines
+func (h *ProxyHandler) AddExchange(ex *proxy.Exchange) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	follow := len(h.Exchanges) == 0 || h.List.SelectedRow == len(h.Exchanges)-1
+	h.Exchanges = append(h.Exchanges, ex)
+	h.List.Rows = append(h.List.Rows, formatExchangeRow(ex))
+	if follow {
+		h.List.SelectedRow = len(h.Exchanges) - 1
+		h.updateDetail()
+	}
+	h.render()
+}
+
+// ShowError reports an error in the status line; safe to call from server goroutines
+func (h *ProxyHandler) ShowError(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.Status.Text = h.status + "  [" + err.Error() + "](fg:red)"
+	h.Status.BorderStyle.Fg = ui.ColorRed
+	h.render()
+}
+
+func (h *ProxyHandler) updateDetail() {
+	ex := h.Exchanges[h.List.SelectedRow]
+	rows := []string{
+		fmt.Sprintf("[%s %s](fg:yellow)", ex.Request.Method, ex.Request.Path),
+	}
+	if ex.Request.Query != "" {
+		rows = append(rows, "Query: "+ex.Request.Query)
+	}
+	if ex.Operation != "" {
+		rows = append(rows, "Operation: "+ex.Operation+" "+ex.OperationID)
+	}
+	rows = append(rows, formatHeaderRows(ex.Request.Header)...)
+	if ex.Request.Body != "" {
+		rows = append(rows, "")
+		rows = append(rows, splitLines(render.Body(ex.Request.Header.Get("Content-Type"), []byte(ex.Request.Body)).Text)...)
+	}
+	statusColor := "green"
+	if ex.Response.Status >= 400 {
+		statusColor = "red"
+	}
+	rows = append(rows, "", fmt.Sprintf("[Status: %d](fg:%s) %s", ex.Response.Status, statusColor, ex.Duration.Round(time.Millisecond)))
+	rows = append(rows, formatHeaderRows(ex.Response.Header)...)
+	rows = append(rows, "")
+	rows = append(rows, splitLines(render.Body(ex.Response.Header.Get("Content-Type"), []byte(ex.Response.Body)).Text)...)
+
+	h.Detail.Rows = rows
+	h.Detail.SelectedRow = 0
+}
+
+func formatHeaderRows(header http.Header) []string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rows := make([]string, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, name+": "+strings.Join(header[name], ", "))
+	}
+	return rows
+}
+
+func (h *ProxyHandler) HandleEvent(e tui.Event) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if e.Type == ui.ResizeEvent {
+		payload := e.Payload.(ui.Resize)
+		h.layout(payload.Width, payload.Height)
+		ui.Clear()
+		return false
+	}
+
+	switch e.ID {
+	case "q", "<C-c>":
+		return true
+	case "<Tab>":
+		if h.FocusMode == "list" {
+			h.FocusMode = "detail"
+			h.List.BorderStyle.Fg = ui.ColorWhite
+			h.Detail.BorderStyle.Fg = ui.ColorYellow
+		} else {
+			h.FocusMode = "list"
+			h.List.BorderStyle.Fg = ui.ColorYellow
+			h.Detail.BorderStyle.Fg = ui.ColorWhite
+		}
+	case "j", "<Down>":
+		if h.FocusMode == "list" {
+			if h.List.SelectedRow < len(h.List.Rows)-1 {
+				h.List.SelectedRow++
+				h.updateDetail()
+			}
+		} else if h.Detail.SelectedRow < len(h.Detail.Rows)-1 {
+			h.Detail.SelectedRow++
+		}
+	case "k", "<Up>":
+		if h.FocusMode == "list" {
+			if h.List.SelectedRow > 0 {
+				h.List.SelectedRow--
+				h.updateDetail()
+			}
+		} else if h.Detail.SelectedRow > 0 {
+			h.Detail.SelectedRow--
+		}
+	}
+	return false
+}
+
+func (h *ProxyHandler) Render() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.render()
+}
+
+func (h *ProxyHandler) render() {
+	if !h.ready {
+		return
+	}
+	ui.Render(h.Status, h.List, h.Detail)
+}
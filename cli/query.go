@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"org.subh/api-term/pkgs/query"
+)
+
+// runQuery implements `api-term query EXPR [file]`, filtering a JSON document
+// with the same expressions as the Response pane filter, and returns the exit code
+func runQuery(args []string) int {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	raw := fs.Bool("raw", false, "print string results without JSON quotes")
+	compact := fs.Bool("compact", false, "print each result on a single line")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: api-term query [flags] EXPR [file.json]")
+		fmt.Fprintln(fs.Output(), "EXPR is a jq expression such as '.items[] | .id', or JSONPath starting with $. Reads stdin without a file.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+	q, err := query.Compile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid expression: %v\n", err)
+		return 2
+	}
+
+	var data []byte
+	if fs.NArg() == 2 {
+		data, err = os.ReadFile(fs.Arg(1))
+	} else {
+		data, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read input: %v\n", err)
+		return 2
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		fmt.Fprintf(os.Stderr, "Input is not JSON: %v\n", err)
+		return 2
+	}
+
+	results, err := q.Run(doc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Query failed: %v\n", err)
+		return 1
+	}
+	for _, r := range results {
+		if s, ok := r.(string); ok && *raw {
+			fmt.Println(s)
+			continue
+		}
+		var out []byte
+		if *compact {
+			out, _ = json.Marshal(r)
+		} else {
+			out, _ = json.MarshalIndent(r, "", "  ")
+		}
+		fmt.Println(string(out))
+	}
+	return 0
+}
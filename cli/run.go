@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"org.subh/api-term/pkgs/api/parser"
+	"org.subh/api-term/pkgs/runner"
+)
+
+// runCollection implements `api-term run collection.yaml` and returns the exit code
+func runCollection(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	format := fs.String("format", "tty", "report format: tty, junit or json")
+	outFile := fs.String("out", "", "write the report to this file instead of stdout")
+	parallel := fs.Bool("parallel", false, "run requests in parallel")
+	baseURL := fs.String("base-url", "", "override the collection base URL")
+	specFile := fs.String("spec", "", "OpenAPI spec for schema assertions (overrides the collection)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: api-term run [flags] collection.yaml")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	collection, err := runner.LoadCollection(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load collection: %v\n", err)
+		return 2
+	}
+	if *baseURL != "" {
+		collection.BaseURL = *baseURL
+	}
+	if *specFile != "" {
+		collection.Spec = *specFile
+	}
+
+	var doc *openapi3.T
+	if collection.Spec != "" {
+		var diags []parser.Diagnostic
+		doc, diags, err = parser.LoadDocument(collection.Spec)
+		printDiagnostics(diags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load spec %s: %v\n", collection.Spec, err)
+			return 2
+		}
+	}
+
+	r := runner.NewRunner(collection, doc)
+	if *parallel {
+		r.Parallel = true
+	}
+	report := r.Run()
+
+	var out io.Writer = os.Stdout
+	if *outFile != "" {
+		f, err := os.Create(*outFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create %s: %v\n", *outFile, err)
+			return 2
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := runner.WriteReport(out, *format, report); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+		return 2
+	}
+
+	if report.Failed() > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/mattn/go-runewidth"
+	"org.subh/api-term/pkgs/api/client"
+	"org.subh/api-term/pkgs/api/model"
+	"org.subh/api-term/pkgs/render"
+	"org.subh/api-term/pkgs/stream"
+)
+
+// streamEvent is an event with the time it arrived, since the headers
+type streamEvent struct {
+	stream.Event
+	At time.Duration
+}
+
+// Widths of the time, event type and id columns of a stream
+const (
+	streamTimeWidth = 9
+	streamTypeWidth = 14
+	streamIDWidth   = 8
+)
+
+// streamRefresh is how often the Response pane is redrawn while events
+// arrive
+const streamRefresh = 50 * time.Millisecond
+
+// startStream shows resp as a stream and reads its body in the background
+func (h *MainHandler) startStream(ep *model.Endpoint, inputValues, headerValues map[string]string, body, contentType string, resp *client.Response, reader io.ReadCloser, cancel context.CancelFunc) {
+	respType := resp.Header.Get("Content-Type")
+	h.Response, h.Rendered = resp, render.Body(respType, nil)
+	h.recordCall(ep, inputValues, headerValues, body, contentType, resp)
+	h.Events, h.ShowStream = nil, true
+	h.StreamCancel, h.StreamStatus = cancel, "streaming"
+	h.applyFilter()
+	h.Output.BorderStyle.Fg = ui.ColorGreen
+	if resp.StatusCode >= 400 {
+		h.Output.BorderStyle.Fg = ui.ColorRed
+	}
+	h.showResponse()
+	h.Output.SelectedRow = 0
+	go h.readStream(resp, reader, respType)
+}
+
+// readStream adds events to the Response pane as they arrive. Once the
+// stream ends the raw body is kept so it can be saved, searched or diffed.
+func (h *MainHandler) readStream(resp *client.Response, reader io.ReadCloser, contentType string) {
+	defer reader.Close()
+	var raw strings.Builder
+	start := time.Now()
+	var drawn time.Time
+	err := stream.Read(io.TeeReader(reader, &raw), contentType, func(ev stream.Event) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.Response != resp {
+			return
+		}
+		h.Events = append(h.Events, streamEvent{Event: ev, At: resp.Duration + time.Since(start)})
+		if time.Since(drawn) >= streamRefresh {
+			drawn = time.Now()
+			h.refreshStream()
+		}
+	})
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.Response != resp {
+		return
+	}
+	resp.Body = raw.String()
+	resp.Duration += time.Since(start)
+	if h.StreamStatus == "streaming" {
+		h.StreamStatus = "ended"
+		if err != nil {
+			h.StreamStatus = "failed: " + err.Error()
+		}
+	}
+	h.StreamCancel = nil
+	h.Rendered = render.Body(contentType, []byte(resp.Body))
+	h.applyFilter()
+	h.refreshStream()
+}
+
+// refreshStream redraws the stream, following its tail unless another row
+// was selected; h.mu must be held
+func (h *MainHandler) refreshStream() {
+	follow := h.Output.SelectedRow >= len(h.Output.Rows)-1
+	h.showResponse()
+	if follow {
+		h.Output.SelectedRow = max(len(h.Output.Rows)-1, 0)
+	}
+	h.render()
+}
+
+// stopStream cancels the stream being read, if any
+func (h *MainHandler) stopStream() {
+	if h.StreamCancel != nil {
+		h.StreamStatus = "stopped"
+		h.StreamCancel()
+		h.StreamCancel = nil
+	}
+}
+
+// streamTitle counts the events and tells whether the stream is still open
+func (h *MainHandler) streamTitle() string {
+	title := fmt.Sprintf("Response Stream · %d %s, %s", len(h.Events), plural(len(h.Events), "event"), h.StreamStatus)
+	if h.StreamCancel != nil {
+		return title + " (x to stop)"
+	}
+	return title + fmt.Sprintf(" after %s (s to save)", h.Response.Duration.Round(time.Millisecond))
+}
+
+// streamRows lays out one event per row with time, type and id columns;
+// further lines of multi-line data are indented under the data column
+func (h *MainHandler) streamRows() []string {
+	rows := []string{column("TIME", streamTimeWidth) + " " + column("EVENT", streamTypeWidth) + " " + column("ID", streamIDWidth) + " DATA"}
+	indent := strings.Repeat(" ", streamTimeWidth+streamTypeWidth+streamIDWidth+3)
+	for _, ev := range h.Events {
+		eventType := column(ev.Type, streamTypeWidth)
+		if name := strings.TrimRight(eventType, " "); name != "" && !strings.ContainsAny(name, "[]()") {
+			eventType = "[" + name + "](fg:cyan)" + eventType[len(name):]
+		}
+		at := fmt.Sprintf("+%.3fs", ev.At.Seconds())
+		lines := strings.Split(expandTabs(ev.Data), "\n")
+		rows = append(rows, column(at, streamTimeWidth)+" "+eventType+" "+column(ev.ID, streamIDWidth)+" "+lines[0])
+		for _, line := range lines[1:] {
+			rows = append(rows, indent+line)
+		}
+	}
+	return rows
+}
+
+// column truncates or pads s to width cells
+func column(s string, width int) string {
+	return runewidth.FillRight(runewidth.Truncate(s, width, "…"), width)
+}
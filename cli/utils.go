@@ -1,17 +1,19 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"fmt"
+	"os"
 	"strings"
+
+	"org.subh/api-term/pkgs/api/parser"
 )
 
-func tryFormatJSON(s string) string {
-	var prettyJSON bytes.Buffer
-	if err := json.Indent(&prettyJSON, []byte(s), "", "  "); err != nil {
-		return s
+// printDiagnostics reports problems found loading specs on stderr, for the
+// subcommands that don't show the TUI
+func printDiagnostics(diags []parser.Diagnostic) {
+	for _, d := range diags {
+		fmt.Fprintln(os.Stderr, d.String())
 	}
-	return prettyJSON.String()
 }
 
 func splitLines(s string) []string {
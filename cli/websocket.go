@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+	"org.subh/api-term/pkgs/api/client"
+	"org.subh/api-term/pkgs/api/example"
+	"org.subh/api-term/pkgs/api/model"
+	"org.subh/api-term/pkgs/wsclient"
+)
+
+// dialTimeout bounds the WebSocket handshake
+const dialTimeout = 10 * time.Second
+
+// openSession connects to ep as a WebSocket and shows the session log in
+// the Response pane
+func (h *MainHandler) openSession(ep *model.Endpoint, inputValues, headerValues map[string]string) {
+	h.stopStream()
+	h.closeSession()
+	h.Response, h.Rendered = nil, nil
+	h.ShowSession, h.SessionEndpoint = true, ep
+	h.Output.SelectedRow = 0
+	url, err := client.BuildURL(h.BaseURL, ep, inputValues)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+		defer cancel()
+		// the session calls back from Send as well as from its reader, while
+		// h.mu may be held, so it only signals followSession to redraw
+		updates := make(chan struct{}, 1)
+		var s *wsclient.Session
+		s, err = wsclient.Dial(ctx, url, headerValues, func(wsclient.Message) {
+			select {
+			case updates <- struct{}{}:
+			default:
+			}
+		})
+		if err == nil {
+			h.Session = s
+			go h.followSession(s, updates)
+		}
+	}
+	if err != nil {
+		h.Output.Title = "WebSocket (connection failed)"
+		h.Output.Rows = []string{fmt.Sprintf("Error: %s", err.Error())}
+		h.Output.BorderStyle.Fg = ui.ColorRed
+		return
+	}
+	h.Output.BorderStyle.Fg = ui.ColorGreen
+	h.showSession()
+}
+
+// closeSession leaves the session view, closing its connection
+func (h *MainHandler) closeSession() {
+	if h.Session != nil {
+		go h.Session.Close(1000, "")
+		h.Session = nil
+	}
+	h.ShowSession = false
+}
+
+// frameExample generates a frame from the schema of the messages the client
+// sends, as declared by AsyncAPI channels
+func (h *MainHandler) frameExample() string {
+	op := h.findOperation(h.SessionEndpoint)
+	if op == nil || op.RequestBody == nil || op.RequestBody.Value == nil {
+		return ""
+	}
+	content := op.RequestBody.Value.Content
+	media := content.Get("application/json")
+	if media == nil {
+		for _, m := range content {
+			media = m
+			break
+		}
+	}
+	if media == nil {
+		return ""
+	}
+	data, err := json.Marshal(example.ForMedia(media))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// sendFrame sends text as a text frame. "/ping [data]" sends a ping and
+// "/close [code] [reason]" closes the session; "//" escapes a leading slash.
+func (h *MainHandler) sendFrame(text string) {
+	if h.Session == nil || text == "" {
+		return
+	}
+	h.Frame = text
+	var err error
+	switch {
+	case strings.HasPrefix(text, "//"):
+		err = h.Session.Send(text[1:])
+	case text == "/ping" || strings.HasPrefix(text, "/ping "):
+		err = h.Session.Ping(strings.TrimSpace(strings.TrimPrefix(text, "/ping")))
+	case text == "/close" || strings.HasPrefix(text, "/close "):
+		code, reason := 1000, ""
+		fields := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(text, "/close")), " ", 2)
+		if n, convErr := strconv.Atoi(fields[0]); convErr == nil {
+			code = n
+			if len(fields) == 2 {
+				reason = fields[1]
+			}
+		} else if fields[0] != "" {
+			reason = strings.Join(fields, " ")
+		}
+		go h.Session.Close(code, reason)
+	default:
+		err = h.Session.Send(text)
+	}
+	if err != nil {
+		h.showSession()
+		h.Output.Title += " - send failed: " + err.Error()
+	}
+}
+
+// followSession redraws the session log as messages arrive, until the
+// connection closes or another session replaces s
+func (h *MainHandler) followSession(s *wsclient.Session, updates <-chan struct{}) {
+	for {
+		closed := false
+		select {
+		case <-updates:
+		case <-s.Done():
+			closed = true
+		}
+		h.mu.Lock()
+		if h.Session != s {
+			h.mu.Unlock()
+			return
+		}
+		h.refreshSession()
+		h.mu.Unlock()
+		if closed {
+			return
+		}
+	}
+}
+
+// refreshSession redraws the session log, following its tail unless another
+// row was selected; h.mu must be held
+func (h *MainHandler) refreshSession() {
+	if !h.ShowSession {
+		return
+	}
+	follow := h.Output.SelectedRow >= len(h.Output.Rows)-1
+	h.showSession()
+	if follow {
+		h.Output.SelectedRow = max(len(h.Output.Rows)-1, 0)
+	}
+	h.render()
+}
+
+// showSession fills the Response pane with the timestamped message log
+func (h *MainHandler) showSession() {
+	if h.Session == nil {
+		return
+	}
+	state := "open (m to send, X to close)"
+	select {
+	case <-h.Session.Done():
+		state = "closed"
+	default:
+	}
+	h.Output.Title = "WebSocket " + h.Session.URL + " · " + state
+	var rows []string
+	for _, m := range h.Session.Log() {
+		marker := "[●](fg:yellow)"
+		switch m.Direction {
+		case wsclient.Outbound:
+			marker = "[→](fg:green)"
+		case wsclient.Inbound:
+			marker = "[←](fg:cyan)"
+		}
+		prefix := m.Time.Format("15:04:05.000") + " " + marker + " " + column(m.Kind, 9) + " "
+		lines := strings.Split(expandTabs(m.Data), "\n")
+		rows = append(rows, prefix+lines[0])
+		for _, line := range lines[1:] {
+			rows = append(rows, strings.Repeat(" ", 25)+line)
+		}
+	}
+	h.Output.Rows = rows
+}
@@ -0,0 +1,80 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+)
+
+// Supported providers
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+)
+
+// Default models per provider, used when no model is configured
+var DefaultModels = map[string]string{
+	ProviderGemini: "gemini-2.5-flash",
+	ProviderOpenAI: "gpt-4o-mini",
+	ProviderOllama: "llama3.1",
+}
+
+// Usage is the token accounting reported by the provider, zero when unknown
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Reply is a complete answer from the assistant
+type Reply struct {
+	Text  string
+	Usage Usage
+	// ToolCalls are the functions the assistant asked to call, only set by
+	// ToolSession.SendWithTools
+	ToolCalls []ToolCall
+}
+
+// Assistant starts chat sessions with an AI provider
+type Assistant interface {
+	// Name identifies the provider and model, e.g. "gemini/gemini-2.5-flash"
+	Name() string
+	StartSession(ctx context.Context) (Session, error)
+	// ResumeSession starts a session that continues from an earlier
+	// conversation
+	ResumeSession(ctx context.Context, history []Turn) (Session, error)
+}
+
+// Session is a multi-turn conversation that keeps its own history
+type Session interface {
+	// Send blocks until the whole answer is available
+	Send(ctx context.Context, message string) (*Reply, error)
+	// Stream calls onToken with each chunk of the answer as it arrives and
+	// returns the complete reply when generation finishes
+	Stream(ctx context.Context, message string, onToken func(string)) (*Reply, error)
+}
+
+// NewAssistant creates the Assistant for a provider. Empty model and baseURL
+// select the provider defaults.
+func NewAssistant(ctx context.Context, provider, model, baseURL string) (Assistant, error) {
+	if provider == "" {
+		provider = ProviderGemini
+	}
+	if model == "" {
+		model = DefaultModels[provider]
+	}
+	switch provider {
+	case ProviderGemini:
+		client, err := NewGeminiClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		client.Model = model
+		return client, nil
+	case ProviderOpenAI:
+		return NewOpenAIClient(baseURL, model), nil
+	case ProviderOllama:
+		return NewOllamaClient(baseURL, model), nil
+	default:
+		return nil, fmt.Errorf("unknown AI provider: %s", provider)
+	}
+}
@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestOpenAIClient_SendAndStream(t *testing.T) {
+	var lastMessages int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("unexpected request %s auth=%q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		lastMessages = len(req.Messages)
+		if !req.Stream {
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"hello"}}],"usage":{"prompt_tokens":3,"completion_tokens":1}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hel\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":2}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer ts.Close()
+
+	client := NewOpenAIClient(ts.URL+"/v1", "test-model")
+	client.APIKey = "key"
+	session, err := client.StartSession(context.Background())
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+
+	reply, err := session.Send(context.Background(), "hi")
+	if err != nil || reply.Text != "hello" || reply.Usage.PromptTokens != 3 {
+		t.Fatalf("unexpected reply %+v, err %v", reply, err)
+	}
+
+	var tokens []string
+	reply, err = session.Stream(context.Background(), "again", func(tok string) { tokens = append(tokens, tok) })
+	if err != nil || reply.Text != "hello" || strings.Join(tokens, "|") != "hel|lo" || reply.Usage.CompletionTokens != 2 {
+		t.Fatalf("unexpected streamed reply %+v tokens %v, err %v", reply, tokens, err)
+	}
+	if lastMessages != 3 {
+		t.Errorf("expected history of 3 messages to be sent, got %d", lastMessages)
+	}
+}
+
+func TestOllamaClient_Stream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"hi "},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"there"},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":4,"eval_count":2}`)
+	}))
+	defer ts.Close()
+
+	assistant, err := NewAssistant(context.Background(), ProviderOllama, "", ts.URL)
+	if err != nil {
+		t.Fatalf("NewAssistant: %v", err)
+	}
+	if assistant.Name() != "ollama/llama3.1" {
+		t.Errorf("unexpected name %q", assistant.Name())
+	}
+	session, _ := assistant.StartSession(context.Background())
+
+	var streamed string
+	reply, err := session.Stream(context.Background(), "hello", func(tok string) { streamed += tok })
+	if err != nil || reply.Text != "hi there" || streamed != "hi there" || reply.Usage.PromptTokens != 4 {
+		t.Fatalf("unexpected reply %+v streamed %q, err %v", reply, streamed, err)
+	}
+
+	if _, err := NewAssistant(context.Background(), "unknown", "", ""); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+func TestOpenAIClient_SendWithTools(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Tools) != 1 || req.Tools[0].Type != "function" || req.Tools[0].Function.Name != "getPet" {
+			t.Errorf("tools not sent: %+v", req.Tools)
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[{"id":"1","type":"function","function":{"name":"getPet","arguments":"{\"id\":\"42\"}"}}]}}]}`)
+	}))
+	defer ts.Close()
+
+	session, _ := NewOpenAIClient(ts.URL, "test-model").StartSession(context.Background())
+	tools := []Tool{{Name: "getPet", Parameters: map[string]interface{}{"type": "object"}}}
+	reply, err := session.(ToolSession).SendWithTools(context.Background(), "fetch pet 42", tools)
+	if err != nil || len(reply.ToolCalls) != 1 || reply.ToolCalls[0].Name != "getPet" || reply.ToolCalls[0].Arguments["id"] != "42" {
+		t.Fatalf("unexpected reply %+v, err %v", reply, err)
+	}
+}
+
+func TestGeminiSession_SendWithToolsKeepsHistory(t *testing.T) {
+	var requests []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		if len(requests) == 1 {
+			fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"getPet","args":{"id":"42"}}}]}}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"done"}]}}]}`)
+	}))
+	defer ts.Close()
+
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: ts.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, _ := (&GeminiClient{client: client, Model: "test-model"}).StartSession(context.Background())
+	tools := []Tool{{Name: "getPet", Parameters: map[string]interface{}{"type": "object"}}}
+	reply, err := session.(ToolSession).SendWithTools(context.Background(), "fetch pet 42", tools)
+	if err != nil || len(reply.ToolCalls) != 1 || reply.ToolCalls[0].Arguments["id"] != "42" {
+		t.Fatalf("unexpected reply %+v, err %v", reply, err)
+	}
+	if _, err := session.Send(context.Background(), "why?"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 2 || requests[1]["tools"] != nil {
+		t.Fatalf("follow-up should be sent without tools: %v", requests)
+	}
+	contents, _ := json.Marshal(requests[1]["contents"])
+	if !strings.Contains(string(contents), "fetch pet 42") || !strings.Contains(string(contents), `Proposed call: getPet {\"id\":\"42\"}`) {
+		t.Errorf("compose turn missing from the follow-up history: %s", contents)
+	}
+}
@@ -0,0 +1,435 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"org.subh/api-term/pkgs/api/model"
+	"org.subh/api-term/pkgs/api/parser"
+	"org.subh/api-term/pkgs/jsonpath"
+)
+
+// maxToolSchemaDepth bounds the request body schema given to the assistant,
+// which also stops recursive schemas
+const maxToolSchemaDepth = 6
+
+// composeInstructions precede the user's request when asking for a plan
+const composeInstructions = `You turn a request into a sequence of API calls. Call one tool per API call, in the order they must run.
+Fill every required parameter and give bodies that match the schema.
+When a value comes from the JSON response of an earlier call, write {{stepN.field}} where N is the 1-based position of that call and field is a dotted path into its response, for example {{step1.id}}.
+Request: `
+
+var (
+	toolNameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+	// quotedStepRef matches a reference that is a whole JSON string, so it can
+	// be replaced by a value of any type
+	quotedStepRef = regexp.MustCompile(`"\{\{\s*step(\d+)\.([^}]+?)\s*\}\}"`)
+	stepRef       = regexp.MustCompile(`\{\{\s*step(\d+)\.([^}]+?)\s*\}\}`)
+)
+
+// ProposedRequest is one API call suggested by the assistant, resolved
+// against the loaded endpoints
+type ProposedRequest struct {
+	Endpoint  *model.Endpoint
+	Operation *openapi3.Operation
+	Params    map[string]string
+	Headers   map[string]string
+	Body      string
+	// ContentType is the media type of the request body in the spec
+	ContentType string
+	// Problems lists missing parameters and spec violations in the body
+	Problems []string
+}
+
+// String renders the request on one line for the proposal view
+func (p *ProposedRequest) String() string {
+	line := p.Endpoint.Method + " " + p.Endpoint.Path
+	if len(p.Params) > 0 {
+		pairs := make([]string, 0, len(p.Params))
+		for _, name := range sortedNames(p.Params) {
+			pairs = append(pairs, name+"="+p.Params[name])
+		}
+		line += " " + strings.Join(pairs, "&")
+	}
+	if p.Body != "" {
+		line += " body " + p.Body
+	}
+	return line
+}
+
+// CheckBody validates a body against the request body schema of the
+// operation. Bodies referring to earlier steps are only checked this way,
+// once the references are resolved.
+func (p *ProposedRequest) CheckBody(body string) error {
+	_, schema, _ := requestBodySchema(p.Operation)
+	if schema == nil || body == "" {
+		return nil
+	}
+	var doc interface{}
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		return fmt.Errorf("body is not JSON: %v", err)
+	}
+	if err := schema.VisitJSON(doc); err != nil {
+		return fmt.Errorf("body: %s", firstLine(err.Error()))
+	}
+	return nil
+}
+
+type binding struct {
+	endpoint  *model.Endpoint
+	operation *openapi3.Operation
+	// body is the argument holding the request body
+	body string
+}
+
+// Composer plans API calls from natural language using one tool per
+// operation
+type Composer struct {
+	tools    []Tool
+	bindings map[string]binding
+}
+
+// NewComposer builds tools for endpoints, using docs for parameter types,
+// descriptions and request body schemas
+func NewComposer(endpoints []*model.Endpoint, docs []*openapi3.T) *Composer {
+	c := &Composer{bindings: map[string]binding{}}
+	for _, ep := range endpoints {
+		var op *openapi3.Operation
+		for _, doc := range docs {
+			if op, _ = parser.FindOperation(doc, ep.Method, ep.Path); op != nil {
+				break
+			}
+		}
+		name, body := c.toolName(ep, op), bodyArgument(ep)
+		c.bindings[name] = binding{endpoint: ep, operation: op, body: body}
+		c.tools = append(c.tools, Tool{
+			Name:        name,
+			Description: toolDescription(ep, op),
+			Parameters:  toolParameters(ep, op, body),
+		})
+	}
+	return c
+}
+
+// Tools returns the tool for every operation
+func (c *Composer) Tools() []Tool {
+	return c.tools
+}
+
+// Propose asks session for the calls that fulfil request. The assistant's
+// text is returned as well, since it may explain or ask a question instead
+// of proposing calls.
+func (c *Composer) Propose(ctx context.Context, session ToolSession, request string) ([]*ProposedRequest, string, error) {
+	reply, err := session.SendWithTools(ctx, ComposePrompt(request), c.tools)
+	if err != nil {
+		return nil, "", err
+	}
+	proposals := make([]*ProposedRequest, 0, len(reply.ToolCalls))
+	for _, call := range reply.ToolCalls {
+		p, err := c.Resolve(call)
+		if err != nil {
+			return nil, reply.Text, err
+		}
+		proposals = append(proposals, p)
+	}
+	return proposals, reply.Text, nil
+}
+
+// ComposePrompt is the message sent to plan request, alongside the tools
+func ComposePrompt(request string) string {
+	return composeInstructions + request
+}
+
+// Resolve maps a tool call back to its endpoint and checks the arguments
+// against the spec
+func (c *Composer) Resolve(call ToolCall) (*ProposedRequest, error) {
+	b, ok := c.bindings[call.Name]
+	if !ok {
+		return nil, fmt.Errorf("assistant called unknown operation %q", call.Name)
+	}
+	p := &ProposedRequest{
+		Endpoint:  b.endpoint,
+		Operation: b.operation,
+		Params:    map[string]string{},
+		Headers:   map[string]string{},
+	}
+	for _, param := range b.endpoint.Parameters {
+		value, ok := call.Arguments[param.Name]
+		if !ok || value == nil {
+			if param.Required {
+				p.Problems = append(p.Problems, "missing required "+param.In+" parameter "+param.Name)
+			}
+			continue
+		}
+		if param.In == "header" {
+			p.Headers[param.Name] = argString(value)
+		} else {
+			p.Params[param.Name] = argString(value)
+		}
+	}
+
+	body, hasBody := call.Arguments[b.body]
+	mediaType, schema, required := requestBodySchema(b.operation)
+	if hasBody && body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		p.Body = string(data)
+		p.ContentType = mediaType
+		if p.ContentType == "" {
+			p.ContentType = "application/json"
+		}
+		if schema != nil && !stepRef.Match(data) {
+			if err := schema.VisitJSON(body); err != nil {
+				p.Problems = append(p.Problems, "body: "+firstLine(err.Error()))
+			}
+		}
+	} else if required {
+		p.Problems = append(p.Problems, "missing required request body")
+	}
+	return p, nil
+}
+
+// ResolveStepRefs replaces {{stepN.field}} references in s with values from
+// the JSON bodies of earlier responses, responses[0] being step 1. A
+// reference that is a whole JSON string is replaced by the JSON value.
+func ResolveStepRefs(s string, responses []string) (string, error) {
+	var firstErr error
+	lookup := func(match []string) interface{} {
+		n, _ := strconv.Atoi(match[1])
+		if n < 1 || n > len(responses) {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("step %d has not run yet", n)
+			}
+			return nil
+		}
+		var doc interface{}
+		if err := json.Unmarshal([]byte(responses[n-1]), &doc); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("step %d did not return JSON", n)
+			}
+			return nil
+		}
+		values, err := jsonpath.Eval("$."+strings.TrimPrefix(match[2], "."), doc)
+		if err != nil || len(values) == 0 {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("step %d response has no %s", n, match[2])
+			}
+			return nil
+		}
+		return values[0]
+	}
+	s = quotedStepRef.ReplaceAllStringFunc(s, func(ref string) string {
+		data, _ := json.Marshal(lookup(quotedStepRef.FindStringSubmatch(ref)))
+		return string(data)
+	})
+	s = stepRef.ReplaceAllStringFunc(s, func(ref string) string {
+		return argString(lookup(stepRef.FindStringSubmatch(ref)))
+	})
+	return s, firstErr
+}
+
+func (c *Composer) toolName(ep *model.Endpoint, op *openapi3.Operation) string {
+	base := ""
+	if op != nil {
+		base = toolNameInvalid.ReplaceAllString(op.OperationID, "_")
+	}
+	if base == "" {
+		base = strings.ToLower(ep.Method) + "_" + toolNameInvalid.ReplaceAllString(strings.Trim(ep.Path, "/"), "_")
+	}
+	base = strings.Trim(base, "_")
+	if len(base) > 60 {
+		base = base[:60]
+	}
+	name := base
+	for i := 2; ; i++ {
+		if _, taken := c.bindings[name]; !taken {
+			return name
+		}
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+}
+
+func toolDescription(ep *model.Endpoint, op *openapi3.Operation) string {
+	desc := ep.Method + " " + ep.Path
+	if op != nil {
+		if op.Summary != "" {
+			desc += ": " + op.Summary
+		} else if op.Description != "" {
+			desc += ": " + firstLine(op.Description)
+		}
+	}
+	return desc
+}
+
+// bodyArgument names the request body argument "body", or "request_body" and
+// so on when a parameter of ep is already called that
+func bodyArgument(ep *model.Endpoint) string {
+	name := "body"
+	for taken := true; taken; {
+		taken = false
+		for _, param := range ep.Parameters {
+			if param.Name == name {
+				name, taken = "request_"+name, true
+				break
+			}
+		}
+	}
+	return name
+}
+
+func toolParameters(ep *model.Endpoint, op *openapi3.Operation, bodyArg string) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+
+	documented := map[string]*openapi3.Parameter{}
+	if op != nil {
+		for _, ref := range op.Parameters {
+			if ref.Value != nil {
+				documented[ref.Value.Name] = ref.Value
+			}
+		}
+	}
+	for _, param := range ep.Parameters {
+		prop := map[string]interface{}{"type": "string"}
+		if doc := documented[param.Name]; doc != nil {
+			if doc.Schema != nil && doc.Schema.Value != nil {
+				prop = jsonSchema(doc.Schema.Value, maxToolSchemaDepth)
+			}
+			if doc.Description != "" {
+				prop["description"] = doc.Description
+			}
+		}
+		if _, ok := prop["description"]; !ok {
+			prop["description"] = param.In + " parameter"
+		}
+		properties[param.Name] = prop
+		if param.Required {
+			required = append(required, param.Name)
+		}
+	}
+	if _, schema, bodyRequired := requestBodySchema(op); schema != nil {
+		body := jsonSchema(schema, maxToolSchemaDepth)
+		body["description"] = "JSON request body"
+		properties[bodyArg] = body
+		if bodyRequired {
+			required = append(required, bodyArg)
+		}
+	}
+
+	params := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		params["required"] = required
+	}
+	return params
+}
+
+// requestBodySchema returns the JSON media type and request body schema of
+// op and whether a body is required. application/json is preferred, then the
+// first other JSON media type by name.
+func requestBodySchema(op *openapi3.Operation) (string, *openapi3.Schema, bool) {
+	if op == nil || op.RequestBody == nil || op.RequestBody.Value == nil {
+		return "", nil, false
+	}
+	body := op.RequestBody.Value
+	mediaTypes := make([]string, 0, len(body.Content))
+	for mediaType := range body.Content {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Slice(mediaTypes, func(i, j int) bool {
+		if (mediaTypes[i] == "application/json") != (mediaTypes[j] == "application/json") {
+			return mediaTypes[i] == "application/json"
+		}
+		return mediaTypes[i] < mediaTypes[j]
+	})
+	for _, mediaType := range mediaTypes {
+		media := body.Content[mediaType]
+		if strings.Contains(mediaType, "json") && media.Schema != nil && media.Schema.Value != nil {
+			return mediaType, media.Schema.Value, body.Required
+		}
+	}
+	return "", nil, false
+}
+
+// jsonSchema converts an OpenAPI schema to a self-contained JSON schema,
+// inlining references
+func jsonSchema(s *openapi3.Schema, depth int) map[string]interface{} {
+	out := map[string]interface{}{}
+	if s == nil || depth <= 0 {
+		return out
+	}
+	if types := s.Type.Slice(); len(types) == 1 {
+		out["type"] = types[0]
+	} else if len(types) > 1 {
+		out["type"] = types
+	}
+	if s.Format != "" {
+		out["format"] = s.Format
+	}
+	if s.Description != "" {
+		out["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		out["enum"] = s.Enum
+	}
+	if len(s.Properties) > 0 {
+		props := map[string]interface{}{}
+		for name, ref := range s.Properties {
+			props[name] = jsonSchema(ref.Value, depth-1)
+		}
+		out["properties"] = props
+		if _, ok := out["type"]; !ok {
+			out["type"] = "object"
+		}
+	}
+	if len(s.Required) > 0 {
+		out["required"] = s.Required
+	}
+	if s.Items != nil {
+		out["items"] = jsonSchema(s.Items.Value, depth-1)
+	}
+	for key, refs := range map[string]openapi3.SchemaRefs{"allOf": s.AllOf, "anyOf": s.AnyOf, "oneOf": s.OneOf} {
+		if len(refs) == 0 {
+			continue
+		}
+		variants := make([]interface{}, 0, len(refs))
+		for _, ref := range refs {
+			variants = append(variants, jsonSchema(ref.Value, depth-1))
+		}
+		out[key] = variants
+	}
+	return out
+}
+
+// argString formats a decoded JSON argument as a parameter value
+func argString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,210 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"org.subh/api-term/pkgs/api/parser"
+)
+
+const composeSpec = `
+openapi: 3.0.0
+info: {title: models, version: "1"}
+paths:
+  /models:
+    post:
+      operationId: createModel
+      summary: Create a model
+      requestBody:
+        required: true
+        content:
+          application/vnd.models+json:
+            schema: {$ref: "#/components/schemas/Model"}
+      responses:
+        "201": {description: created}
+  /models/{id}:
+    get:
+      summary: Fetch a model
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+        - {name: verbose, in: query, schema: {type: boolean}}
+      responses:
+        "200": {description: ok}
+components:
+  schemas:
+    Model:
+      type: object
+      required: [name, type]
+      properties:
+        name: {type: string}
+        type: {type: string, enum: [bar, baz]}
+`
+
+func newTestComposer(t *testing.T) *Composer {
+	t.Helper()
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(composeSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewComposer(parser.Endpoints(doc), []*openapi3.T{doc})
+}
+
+func TestComposerTools(t *testing.T) {
+	c := newTestComposer(t)
+	tools := map[string]Tool{}
+	for _, tool := range c.Tools() {
+		tools[tool.Name] = tool
+	}
+
+	create, ok := tools["createModel"]
+	if !ok {
+		t.Fatalf("expected a tool named after the operationId, got %v", tools)
+	}
+	body := create.Parameters["properties"].(map[string]interface{})["body"].(map[string]interface{})
+	if body["type"] != "object" || body["properties"].(map[string]interface{})["type"].(map[string]interface{})["enum"] == nil {
+		t.Errorf("request body schema was not inlined: %v", body)
+	}
+	if req := create.Parameters["required"].([]string); len(req) != 1 || req[0] != "body" {
+		t.Errorf("body should be required, got %v", req)
+	}
+
+	get, ok := tools["get_models_id"]
+	if !ok {
+		t.Fatalf("expected a tool named after method and path, got %v", tools)
+	}
+	verbose := get.Parameters["properties"].(map[string]interface{})["verbose"].(map[string]interface{})
+	if verbose["type"] != "boolean" {
+		t.Errorf("parameter type not taken from the spec: %v", verbose)
+	}
+}
+
+func TestComposerPropose(t *testing.T) {
+	c := newTestComposer(t)
+	session := &FakeSession{Replies: []*Reply{{
+		Text: "Creating then fetching",
+		ToolCalls: []ToolCall{
+			{Name: "createModel", Arguments: map[string]interface{}{"body": map[string]interface{}{"name": "foo", "type": "bar"}}},
+			{Name: "get_models_id", Arguments: map[string]interface{}{"id": "{{step1.id}}", "verbose": true}},
+		},
+	}}}
+
+	proposals, text, err := c.Propose(context.Background(), session, "create a model named foo of type bar then fetch it")
+	if err != nil {
+		t.Fatalf("Propose: %v", err)
+	}
+	if text != "Creating then fetching" || len(proposals) != 2 {
+		t.Fatalf("unexpected proposal %q %v", text, proposals)
+	}
+	if !strings.HasSuffix(session.Messages[0], "create a model named foo of type bar then fetch it") || len(session.Tools[0]) != 2 {
+		t.Errorf("request or tools not sent: %v", session.Messages)
+	}
+
+	create, get := proposals[0], proposals[1]
+	if create.Endpoint.Method != "POST" || create.Body != `{"name":"foo","type":"bar"}` || create.ContentType != "application/vnd.models+json" || len(create.Problems) != 0 {
+		t.Errorf("unexpected create proposal %+v", create)
+	}
+	if get.Endpoint.Path != "/models/{id}" || get.Params["id"] != "{{step1.id}}" || get.Params["verbose"] != "true" {
+		t.Errorf("unexpected get proposal %+v", get)
+	}
+
+	id, err := ResolveStepRefs(get.Params["id"], []string{`{"id":"m-1"}`})
+	if err != nil || id != "m-1" {
+		t.Errorf("step reference not resolved: %q %v", id, err)
+	}
+}
+
+func TestComposerResolveProblems(t *testing.T) {
+	c := newTestComposer(t)
+
+	p, err := c.Resolve(ToolCall{Name: "createModel", Arguments: map[string]interface{}{"body": map[string]interface{}{"name": "foo", "type": "nope"}}})
+	if err != nil || len(p.Problems) != 1 || !strings.HasPrefix(p.Problems[0], "body:") {
+		t.Errorf("expected a body validation problem, got %+v %v", p, err)
+	}
+	p, err = c.Resolve(ToolCall{Name: "get_models_id", Arguments: map[string]interface{}{}})
+	if err != nil || len(p.Problems) != 1 || !strings.Contains(p.Problems[0], "id") {
+		t.Errorf("expected a missing parameter problem, got %+v %v", p, err)
+	}
+	if _, err := c.Resolve(ToolCall{Name: "deleteEverything"}); err == nil {
+		t.Error("expected an error for an unknown tool")
+	}
+}
+
+func TestProposedRequestCheckBody(t *testing.T) {
+	c := newTestComposer(t)
+	p, err := c.Resolve(ToolCall{Name: "createModel", Arguments: map[string]interface{}{"body": map[string]interface{}{"name": "{{step1.name}}", "type": "bar"}}})
+	if err != nil || len(p.Problems) != 0 {
+		t.Fatalf("a body with step references is checked once resolved, got %+v %v", p, err)
+	}
+	body, _ := ResolveStepRefs(p.Body, []string{`{"name":5}`})
+	if err := p.CheckBody(body); err == nil || !strings.HasPrefix(err.Error(), "body:") {
+		t.Errorf("expected the resolved body to be rejected, got %v", err)
+	}
+	body, _ = ResolveStepRefs(p.Body, []string{`{"name":"foo"}`})
+	if err := p.CheckBody(body); err != nil {
+		t.Errorf("valid resolved body rejected: %v", err)
+	}
+}
+
+func TestRequestBodySchemaPrefersJSON(t *testing.T) {
+	op := openapi3.NewOperation()
+	op.RequestBody = &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().WithContent(openapi3.Content{
+		"application/merge-patch+json": openapi3.NewMediaType().WithSchema(openapi3.NewObjectSchema()),
+		"application/json":             openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema()),
+		"application/xml":              openapi3.NewMediaType().WithSchema(openapi3.NewObjectSchema()),
+	})}
+	for i := 0; i < 20; i++ {
+		if mediaType, schema, _ := requestBodySchema(op); mediaType != "application/json" || !schema.Type.Is("string") {
+			t.Fatalf("got %s, want application/json", mediaType)
+		}
+	}
+	delete(op.RequestBody.Value.Content, "application/json")
+	op.RequestBody.Value.Content["application/vnd.api+json"] = openapi3.NewMediaType().WithSchema(openapi3.NewObjectSchema())
+	for i := 0; i < 20; i++ {
+		if mediaType, _, _ := requestBodySchema(op); mediaType != "application/merge-patch+json" {
+			t.Fatalf("got %s, want the first JSON media type by name", mediaType)
+		}
+	}
+}
+
+func TestComposerBodyParameter(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`
+openapi: 3.0.0
+info: {title: notes, version: "1"}
+paths:
+  /notes:
+    post:
+      parameters:
+        - {name: body, in: query, required: true, schema: {type: string}}
+      requestBody:
+        content:
+          application/json:
+            schema: {type: object, properties: {text: {type: string}}}
+      responses:
+        "201": {description: created}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewComposer(parser.Endpoints(doc), []*openapi3.T{doc})
+	properties := c.Tools()[0].Parameters["properties"].(map[string]interface{})
+	if properties["body"].(map[string]interface{})["type"] != "string" || properties["request_body"].(map[string]interface{})["type"] != "object" {
+		t.Fatalf("the body parameter and the request body should both be tool arguments: %v", properties)
+	}
+	p, err := c.Resolve(ToolCall{Name: c.Tools()[0].Name, Arguments: map[string]interface{}{"body": "short", "request_body": map[string]interface{}{"text": "hi"}}})
+	if err != nil || p.Params["body"] != "short" || p.Body != `{"text":"hi"}` || len(p.Problems) != 0 {
+		t.Errorf("unexpected proposal %+v %v", p, err)
+	}
+}
+
+func TestResolveStepRefs(t *testing.T) {
+	responses := []string{`{"id":7,"owner":{"name":"ann"}}`}
+	got, err := ResolveStepRefs(`{"model":"{{step1.id}}","by":"{{ step1.owner.name }}","note":"id {{step1.id}}"}`, responses)
+	if err != nil || got != `{"model":7,"by":"ann","note":"id 7"}` {
+		t.Errorf("got %s, %v", got, err)
+	}
+	if _, err := ResolveStepRefs("{{step2.id}}", responses); err == nil {
+		t.Error("expected an error for a step that has not run")
+	}
+}
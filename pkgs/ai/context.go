@@ -0,0 +1,327 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"org.subh/api-term/pkgs/api/client"
+	"org.subh/api-term/pkgs/api/parser"
+	"org.subh/api-term/pkgs/redact"
+)
+
+// Limits applied when the response body is summarized for the prompt
+const (
+	DefaultMaxBody   = 8000
+	maxArrayItems    = 5
+	maxStringLength  = 200
+	maxSchemaDepth   = 4
+	truncationMarker = "...(truncated)"
+)
+
+// insightsPromptPrefix starts the prompt sent when the AI pane is opened
+const insightsPromptPrefix = "Here is an API "
+
+// CallContext describes one API call: what was sent and what came back
+type CallContext struct {
+	Method      string
+	Path        string
+	Operation   *openapi3.Operation
+	Params      map[string]string
+	Headers     map[string]string
+	Body        string
+	ContentType string
+	Response    *client.Response
+	// Redactor hides sensitive values, redact.Default() when nil
+	Redactor *redact.Redactor
+	// MaxBody caps the size of the response body in the prompt, DefaultMaxBody when zero
+	MaxBody int
+}
+
+// BuildContext renders c as a prompt section so the assistant knows which
+// operation produced the data it is asked about
+func BuildContext(c *CallContext) string {
+	var b strings.Builder
+	r := c.redactor()
+
+	b.WriteString("## Operation\n")
+	fmt.Fprintf(&b, "%s %s\n", strings.ToUpper(c.Method), c.Path)
+	if op := c.Operation; op != nil {
+		if op.OperationID != "" {
+			fmt.Fprintf(&b, "operationId: %s\n", op.OperationID)
+		}
+		if op.Summary != "" {
+			fmt.Fprintf(&b, "summary: %s\n", op.Summary)
+		}
+		if op.Description != "" {
+			fmt.Fprintf(&b, "description: %s\n", strings.TrimSpace(op.Description))
+		}
+	}
+
+	b.WriteString("\n## Request\n")
+	if params := describeParams(c); len(params) > 0 {
+		b.WriteString("parameters:\n")
+		for _, line := range params {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	if len(c.Headers) > 0 {
+		b.WriteString("headers:\n")
+		for _, name := range sortedNames(c.Headers) {
+			fmt.Fprintf(&b, "  %s: %s\n", name, r.Value(name, c.Headers[name]))
+		}
+	}
+	if c.Body != "" && (strings.EqualFold(c.Method, "POST") || strings.EqualFold(c.Method, "PUT")) {
+		fmt.Fprintf(&b, "body (%s):\n%s\n", c.ContentType, truncateBody(r.Body(c.Body), c.maxBody()/2))
+	}
+
+	if resp := c.Response; resp != nil {
+		b.WriteString("\n## Response\n")
+		fmt.Fprintf(&b, "status: %d %s\n", resp.StatusCode, http.StatusText(resp.StatusCode))
+		if resp.Duration > 0 {
+			fmt.Fprintf(&b, "latency: %s\n", resp.Duration)
+		}
+		if len(resp.Header) > 0 {
+			b.WriteString("headers:\n")
+			for _, name := range sortedNames(resp.Header) {
+				fmt.Fprintf(&b, "  %s: %s\n", name, r.Value(name, strings.Join(resp.Header[name], ", ")))
+			}
+		}
+		if c.Operation != nil {
+			if schema := parser.ResponseSchema(c.Operation, resp.StatusCode); schema != nil {
+				b.WriteString("documented schema:\n")
+				writeSchema(&b, schema, "  ", 0)
+			}
+		}
+		fmt.Fprintf(&b, "body:\n%s\n", truncateBody(r.Body(resp.Body), c.maxBody()))
+	}
+	return b.String()
+}
+
+// InsightsPrompt asks for insights on call, or on the raw output shown in
+// the Response pane when no call was made
+func InsightsPrompt(call *CallContext, output string) string {
+	prompt := insightsPromptPrefix + "response:\n" + output
+	if call != nil {
+		prompt = insightsPromptPrefix + "call made against an OpenAPI spec, with its documented schema and the actual response:\n\n" + BuildContext(call)
+	}
+	return prompt + "\nProvide interesting insights, then ask the user for any recommendations or follow up actions."
+}
+
+func (c *CallContext) redactor() *redact.Redactor {
+	if c.Redactor != nil {
+		return c.Redactor
+	}
+	return redact.Default()
+}
+
+func (c *CallContext) maxBody() int {
+	if c.MaxBody > 0 {
+		return c.MaxBody
+	}
+	return DefaultMaxBody
+}
+
+// describeParams lists the values sent for each documented parameter, then
+// any extra values the user supplied
+func describeParams(c *CallContext) []string {
+	r := c.redactor()
+	var lines []string
+	seen := map[string]bool{}
+	if c.Operation != nil {
+		for _, ref := range c.Operation.Parameters {
+			p := ref.Value
+			if p == nil {
+				continue
+			}
+			seen[p.Name] = true
+			value, ok := c.Params[p.Name]
+			if !ok {
+				if !p.Required {
+					continue
+				}
+				value = "(missing)"
+			} else {
+				value = r.Value(p.Name, value)
+			}
+			line := fmt.Sprintf("%s (%s", p.Name, p.In)
+			if p.Required {
+				line += ", required"
+			}
+			line += "): " + value
+			if p.Description != "" {
+				line += " - " + p.Description
+			}
+			lines = append(lines, line)
+		}
+	}
+	for _, name := range sortedNames(c.Params) {
+		if !seen[name] {
+			lines = append(lines, fmt.Sprintf("%s: %s", name, r.Value(name, c.Params[name])))
+		}
+	}
+	return lines
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// truncateBody shortens body to about limit bytes. JSON keeps its shape:
+// long arrays are cut to their first items and long strings are clipped
+// before falling back to a plain cut.
+func truncateBody(body string, limit int) string {
+	if len(body) <= limit {
+		return body
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err == nil {
+		if data, err := json.MarshalIndent(shrink(v), "", "  "); err == nil {
+			body = string(data)
+		}
+	}
+	if len(body) <= limit {
+		return body
+	}
+	return cut(body, limit) + "\n" + truncationMarker
+}
+
+// cut shortens s to at most n bytes without splitting a UTF-8 character
+func cut(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func shrink(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = shrink(item)
+		}
+		return out
+	case []interface{}:
+		n := len(val)
+		if n > maxArrayItems {
+			n = maxArrayItems
+		}
+		out := make([]interface{}, 0, n+1)
+		for _, item := range val[:n] {
+			out = append(out, shrink(item))
+		}
+		if len(val) > n {
+			out = append(out, fmt.Sprintf("... %d more items", len(val)-n))
+		}
+		return out
+	case string:
+		if len(val) > maxStringLength {
+			return cut(val, maxStringLength) + truncationMarker
+		}
+	}
+	return v
+}
+
+// writeSchema prints a compact outline of schema, one property per line
+func writeSchema(b *strings.Builder, schema *openapi3.Schema, indent string, depth int) {
+	if schema == nil {
+		return
+	}
+	if len(schema.AllOf) > 0 {
+		for _, ref := range schema.AllOf {
+			writeSchema(b, ref.Value, indent, depth)
+		}
+		return
+	}
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		variants := schema.OneOf
+		if len(variants) == 0 {
+			variants = schema.AnyOf
+		}
+		for i, ref := range variants {
+			fmt.Fprintf(b, "%svariant %d:\n", indent, i+1)
+			writeSchema(b, ref.Value, indent+"  ", depth+1)
+		}
+		return
+	}
+	if schema.Type.Is("array") && schema.Items != nil {
+		fmt.Fprintf(b, "%sarray of %s\n", indent, schemaType(schema.Items.Value))
+		if depth < maxSchemaDepth {
+			writeProperties(b, schema.Items.Value, indent+"  ", depth+1)
+		}
+		return
+	}
+	if len(schema.Properties) == 0 {
+		fmt.Fprintf(b, "%s%s\n", indent, schemaType(schema))
+		return
+	}
+	writeProperties(b, schema, indent, depth)
+}
+
+func writeProperties(b *strings.Builder, schema *openapi3.Schema, indent string, depth int) {
+	if schema == nil || len(schema.Properties) == 0 {
+		return
+	}
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	for _, name := range sortedNames(schema.Properties) {
+		prop := schema.Properties[name].Value
+		line := fmt.Sprintf("%s%s: %s", indent, name, schemaType(prop))
+		if required[name] {
+			line += " (required)"
+		}
+		if prop != nil && prop.Description != "" {
+			line += " - " + prop.Description
+		}
+		b.WriteString(line + "\n")
+		if prop == nil || depth >= maxSchemaDepth {
+			continue
+		}
+		if prop.Type.Is("array") && prop.Items != nil {
+			writeProperties(b, prop.Items.Value, indent+"  ", depth+1)
+		} else {
+			writeProperties(b, prop, indent+"  ", depth+1)
+		}
+	}
+}
+
+func schemaType(schema *openapi3.Schema) string {
+	if schema == nil {
+		return "any"
+	}
+	if schema.Type.Is("array") {
+		if schema.Items != nil {
+			return "array of " + schemaType(schema.Items.Value)
+		}
+		return "array"
+	}
+	t := "any"
+	if schema.Type != nil && len(schema.Type.Slice()) > 0 {
+		t = strings.Join(schema.Type.Slice(), "|")
+	} else if len(schema.Properties) > 0 {
+		t = "object"
+	}
+	if schema.Format != "" {
+		t += " (" + schema.Format + ")"
+	}
+	if len(schema.Enum) > 0 {
+		values := make([]string, 0, len(schema.Enum))
+		for _, v := range schema.Enum {
+			values = append(values, fmt.Sprint(v))
+		}
+		t += " enum[" + strings.Join(values, ", ") + "]"
+	}
+	return t
+}
@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"org.subh/api-term/pkgs/api/client"
+	"org.subh/api-term/pkgs/redact"
+)
+
+const contextSpec = `
+openapi: 3.0.0
+info: {title: pets, version: "1"}
+paths:
+  /pets/{id}:
+    get:
+      operationId: getPet
+      summary: Fetch one pet
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+        - {name: api_key, in: query, schema: {type: string}}
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [name]
+                properties:
+                  name: {type: string}
+                  tags: {type: array, items: {type: string}}
+`
+
+func TestBuildContext(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(contextSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := doc.Paths.Find("/pets/{id}").Get
+
+	var tags []string
+	for i := 0; i < 500; i++ {
+		tags = append(tags, fmt.Sprintf("%q", strings.Repeat("t", 10)))
+	}
+	body := `{"name":"rex","tags":[` + strings.Join(tags, ",") + `]}`
+
+	out := BuildContext(&CallContext{
+		Method:    "get",
+		Path:      "/pets/{id}",
+		Operation: op,
+		Params:    map[string]string{"id": "42", "api_key": "s3cret"},
+		Headers:   map[string]string{"Authorization": "Bearer s3cret", "Accept": "application/json"},
+		Response: &client.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {"application/json"}, "Set-Cookie": {"sid=s3cret"}},
+			Body:       body,
+		},
+		MaxBody: 1000,
+	})
+
+	for _, want := range []string{
+		"GET /pets/{id}",
+		"operationId: getPet",
+		"summary: Fetch one pet",
+		"id (path, required): 42",
+		"api_key (query): REDACTED",
+		"Authorization: REDACTED",
+		"Accept: application/json",
+		"status: 200 OK",
+		"Set-Cookie: REDACTED",
+		"name: string (required)",
+		"tags: array of string",
+		"... 495 more items",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("context is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "s3cret") {
+		t.Errorf("secret leaked into context:\n%s", out)
+	}
+}
+
+func TestTruncateBody(t *testing.T) {
+	if got := truncateBody("short", 10); got != "short" {
+		t.Errorf("short body changed: %q", got)
+	}
+	got := truncateBody(strings.Repeat("x", 50), 10)
+	if got != strings.Repeat("x", 10)+"\n"+truncationMarker {
+		t.Errorf("plain body not cut: %q", got)
+	}
+	long := `{"a":"` + strings.Repeat("y", 500) + `"}`
+	if got := truncateBody(long, 300); !strings.Contains(got, truncationMarker) || strings.Count(got, "y") != maxStringLength {
+		t.Errorf("long JSON string not clipped: %q", got)
+	}
+	if got := truncateBody(strings.Repeat("é", 10), 5); got != "éé\n"+truncationMarker {
+		t.Errorf("multi-byte character split: %q", got)
+	}
+}
+
+func TestRedactedSession(t *testing.T) {
+	fake := &FakeSession{Replies: []*Reply{{Text: "ok"}, {Text: "ok"}}}
+	session := Redacted(fake, redact.Default())
+
+	session.Send(context.Background(), "mail ann@example.com")
+	session.SendWithTools(context.Background(), "token eyJhbGciOi.eyJzdWIiOi.sig", nil)
+	if strings.Contains(strings.Join(fake.Messages, " "), "ann@example.com") || strings.Contains(strings.Join(fake.Messages, " "), "eyJ") {
+		t.Errorf("unredacted data reached the provider: %v", fake.Messages)
+	}
+}
+
+func TestRedactedSession_InsightsWithoutCall(t *testing.T) {
+	r, err := redact.New(&redact.Policy{Fields: []string{"^dob$"}, Exclude: []string{"$.internal"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &FakeSession{Replies: []*Reply{{Text: "ok"}}}
+	output := "Status: 200\n{\n  \"internal\": {\"trace\": \"t-1\"},\n  \"dob\": \"1990-01-01\",\n  \"name\": \"ann\"\n}"
+	Redacted(fake, r).Send(context.Background(), InsightsPrompt(nil, output))
+
+	sent := strings.Join(fake.Messages, " ")
+	if strings.Contains(sent, "t-1") || strings.Contains(sent, "1990-01-01") {
+		t.Errorf("field and exclude rules were not applied to the raw output: %s", sent)
+	}
+	if !strings.Contains(sent, `"name": "ann"`) {
+		t.Errorf("output lost more than the policy asks for: %s", sent)
+	}
+}
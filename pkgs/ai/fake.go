@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// FakeSession is a scripted ToolSession for tests. Each message is answered
+// with the next reply in Replies; Messages and Tools record what was sent.
+type FakeSession struct {
+	Replies  []*Reply
+	Messages []string
+	Tools    [][]Tool
+}
+
+func (f *FakeSession) next(message string, tools []Tool) (*Reply, error) {
+	f.Messages = append(f.Messages, message)
+	f.Tools = append(f.Tools, tools)
+	if len(f.Replies) == 0 {
+		return nil, fmt.Errorf("fake session has no reply for %q", message)
+	}
+	reply := f.Replies[0]
+	f.Replies = f.Replies[1:]
+	return reply, nil
+}
+
+func (f *FakeSession) Send(ctx context.Context, message string) (*Reply, error) {
+	return f.next(message, nil)
+}
+
+// Stream delivers the reply text word by word
+func (f *FakeSession) Stream(ctx context.Context, message string, onToken func(string)) (*Reply, error) {
+	reply, err := f.next(message, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, word := range strings.SplitAfter(reply.Text, " ") {
+		if err := ctx.Err(); err != nil {
+			return reply, err
+		}
+		if onToken != nil && word != "" {
+			onToken(word)
+		}
+	}
+	return reply, nil
+}
+
+func (f *FakeSession) SendWithTools(ctx context.Context, message string, tools []Tool) (*Reply, error) {
+	return f.next(message, tools)
+}
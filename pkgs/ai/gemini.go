@@ -9,6 +9,7 @@ import (
 // GeminiClient wraps the GenAI client
 type GeminiClient struct {
 	client *genai.Client
+	Model  string
 }
 
 // NewGeminiClient creates a new Gemini Client
@@ -17,7 +18,38 @@ func NewGeminiClient(ctx context.Context) (*GeminiClient, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &GeminiClient{client: client}, nil
+	return &GeminiClient{client: client, Model: DefaultModels[ProviderGemini]}, nil
+}
+
+// Name implements Assistant
+func (g *GeminiClient) Name() string {
+	return ProviderGemini + "/" + g.Model
+}
+
+// StartSession implements Assistant
+func (g *GeminiClient) StartSession(ctx context.Context) (Session, error) {
+	chat, err := g.CreateChatSession(ctx, g.Model)
+	if err != nil {
+		return nil, err
+	}
+	return &geminiSession{client: g, chat: chat}, nil
+}
+
+// ResumeSession implements Assistant
+func (g *GeminiClient) ResumeSession(ctx context.Context, history []Turn) (Session, error) {
+	contents := make([]*genai.Content, 0, len(history))
+	for _, turn := range history {
+		role := genai.Role(genai.RoleUser)
+		if turn.Role == RoleAssistant {
+			role = genai.RoleModel
+		}
+		contents = append(contents, genai.NewContentFromText(turn.Text, role))
+	}
+	chat, err := g.client.Chats.Create(ctx, g.Model, nil, contents)
+	if err != nil {
+		return nil, err
+	}
+	return &geminiSession{client: g, chat: chat}, nil
 }
 
 // CreateChatSession initializes a chat session
@@ -40,3 +72,88 @@ func FormatContent(resp *genai.GenerateContentResponse) string {
 	}
 	return "No insights returned or unable to parse response."
 }
+
+// geminiSession adapts a genai.Chat to Session
+type geminiSession struct {
+	client *GeminiClient
+	chat   *genai.Chat
+}
+
+func (s *geminiSession) Send(ctx context.Context, message string) (*Reply, error) {
+	resp, err := s.chat.SendMessage(ctx, genai.Part{Text: message})
+	if err != nil {
+		return nil, err
+	}
+	return &Reply{Text: FormatContent(resp), Usage: geminiUsage(resp)}, nil
+}
+
+func (s *geminiSession) Stream(ctx context.Context, message string, onToken func(string)) (*Reply, error) {
+	reply := &Reply{}
+	for resp, err := range s.chat.SendMessageStream(ctx, genai.Part{Text: message}) {
+		if err != nil {
+			return reply, err
+		}
+		text := resp.Text()
+		reply.Text += text
+		if text != "" && onToken != nil {
+			onToken(text)
+		}
+		if resp.UsageMetadata != nil {
+			reply.Usage = geminiUsage(resp)
+		}
+	}
+	return reply, nil
+}
+
+// SendWithTools asks on a copy of the chat that has the tools configured, so
+// later messages in the session are sent without them. The turn is then
+// added to the session with the calls described as text, as the other
+// providers keep it.
+func (s *geminiSession) SendWithTools(ctx context.Context, message string, tools []Tool) (*Reply, error) {
+	decls := make([]*genai.FunctionDeclaration, 0, len(tools))
+	for _, t := range tools {
+		decls = append(decls, &genai.FunctionDeclaration{
+			Name:                 t.Name,
+			Description:          t.Description,
+			ParametersJsonSchema: t.Parameters,
+		})
+	}
+	config := &genai.GenerateContentConfig{Tools: []*genai.Tool{{FunctionDeclarations: decls}}}
+	chat, err := s.client.client.Chats.Create(ctx, s.client.Model, config, s.chat.History(true))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := chat.SendMessage(ctx, genai.Part{Text: message})
+	if err != nil {
+		return nil, err
+	}
+	reply := &Reply{Usage: geminiUsage(resp)}
+	if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
+		for _, part := range resp.Candidates[0].Content.Parts {
+			if part.Text != "" && !part.Thought {
+				reply.Text += part.Text
+			}
+			if part.FunctionCall != nil {
+				reply.ToolCalls = append(reply.ToolCalls, ToolCall{Name: part.FunctionCall.Name, Arguments: part.FunctionCall.Args})
+			}
+		}
+	}
+	history := append(s.chat.History(true),
+		genai.NewContentFromText(message, genai.RoleUser),
+		genai.NewContentFromText(describeToolCalls(reply), genai.RoleModel))
+	if chat, err = s.client.client.Chats.Create(ctx, s.client.Model, nil, history); err != nil {
+		return nil, err
+	}
+	s.chat = chat
+	return reply, nil
+}
+
+func geminiUsage(resp *genai.GenerateContentResponse) Usage {
+	if resp.UsageMetadata == nil {
+		return Usage{}
+	}
+	return Usage{
+		PromptTokens:     int(resp.UsageMetadata.PromptTokenCount),
+		CompletionTokens: int(resp.UsageMetadata.CandidatesTokenCount),
+	}
+}
@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultOllamaBaseURL is used when no base URL is configured
+const DefaultOllamaBaseURL = "http://localhost:11434"
+
+// OllamaClient talks to a local Ollama-style /api/chat server
+type OllamaClient struct {
+	BaseURL    string
+	Model      string
+	HTTPClient *http.Client
+}
+
+// NewOllamaClient creates a client for a local model server
+func NewOllamaClient(baseURL, model string) *OllamaClient {
+	if baseURL == "" {
+		baseURL = DefaultOllamaBaseURL
+	}
+	return &OllamaClient{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Model:      model,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Name implements Assistant
+func (c *OllamaClient) Name() string {
+	return ProviderOllama + "/" + c.Model
+}
+
+// StartSession implements Assistant
+func (c *OllamaClient) StartSession(ctx context.Context) (Session, error) {
+	return &ollamaSession{client: c}, nil
+}
+
+// ResumeSession implements Assistant
+func (c *OllamaClient) ResumeSession(ctx context.Context, history []Turn) (Session, error) {
+	s := &ollamaSession{client: c}
+	for _, turn := range history {
+		s.messages = append(s.messages, ollamaMessage{Role: turn.Role, Content: turn.Text})
+	}
+	return s, nil
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []openAITool    `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+}
+
+// ollamaChunk is a full response, or one line of a streamed NDJSON response
+type ollamaChunk struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// ollamaSession keeps the message history client side
+type ollamaSession struct {
+	client   *OllamaClient
+	messages []ollamaMessage
+}
+
+func (s *ollamaSession) Send(ctx context.Context, message string) (*Reply, error) {
+	return s.Stream(ctx, message, nil)
+}
+
+func (s *ollamaSession) Stream(ctx context.Context, message string, onToken func(string)) (*Reply, error) {
+	return s.send(ctx, message, nil, onToken)
+}
+
+func (s *ollamaSession) SendWithTools(ctx context.Context, message string, tools []Tool) (*Reply, error) {
+	return s.send(ctx, message, tools, nil)
+}
+
+func (s *ollamaSession) send(ctx context.Context, message string, tools []Tool, onToken func(string)) (*Reply, error) {
+	s.messages = append(s.messages, ollamaMessage{Role: "user", Content: message})
+	reply, err := s.chat(ctx, tools, onToken)
+	if err != nil {
+		s.messages = s.messages[:len(s.messages)-1]
+		return reply, err
+	}
+	s.messages = append(s.messages, ollamaMessage{Role: "assistant", Content: describeToolCalls(reply)})
+	return reply, nil
+}
+
+func (s *ollamaSession) chat(ctx context.Context, tools []Tool, onToken func(string)) (*Reply, error) {
+	req := ollamaRequest{Model: s.client.Model, Messages: s.messages, Stream: onToken != nil}
+	if len(tools) > 0 {
+		req.Tools = toOpenAITools(tools)
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", s.client.BaseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	reply := &Reply{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var chunk ollamaChunk
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			continue
+		}
+		if chunk.Error != "" {
+			return reply, fmt.Errorf("ollama: %s", chunk.Error)
+		}
+		for _, call := range chunk.Message.ToolCalls {
+			reply.ToolCalls = append(reply.ToolCalls, ToolCall{Name: call.Function.Name, Arguments: call.Function.Arguments})
+		}
+		if chunk.Message.Content != "" {
+			reply.Text += chunk.Message.Content
+			if onToken != nil {
+				onToken(chunk.Message.Content)
+			}
+		}
+		if chunk.Done {
+			reply.Usage = Usage{PromptTokens: chunk.PromptEvalCount, CompletionTokens: chunk.EvalCount}
+			break
+		}
+	}
+	return reply, scanner.Err()
+}
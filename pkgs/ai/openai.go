@@ -0,0 +1,220 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// DefaultOpenAIBaseURL is used when no base URL is configured
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAIClient talks to any OpenAI-compatible /chat/completions endpoint,
+// such as an internal LLM gateway
+type OpenAIClient struct {
+	BaseURL    string
+	APIKey     string
+	Model      string
+	HTTPClient *http.Client
+}
+
+// NewOpenAIClient creates an OpenAI-compatible client. The API key is read
+// from OPENAI_API_KEY.
+func NewOpenAIClient(baseURL, model string) *OpenAIClient {
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	return &OpenAIClient{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		APIKey:     os.Getenv("OPENAI_API_KEY"),
+		Model:      model,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Name implements Assistant
+func (c *OpenAIClient) Name() string {
+	return ProviderOpenAI + "/" + c.Model
+}
+
+// StartSession implements Assistant
+func (c *OpenAIClient) StartSession(ctx context.Context) (Session, error) {
+	return &openAISession{client: c}, nil
+}
+
+// ResumeSession implements Assistant
+func (c *OpenAIClient) ResumeSession(ctx context.Context, history []Turn) (Session, error) {
+	s := &openAISession{client: c}
+	for _, turn := range history {
+		s.messages = append(s.messages, openAIMessage{Role: turn.Role, Content: turn.Text})
+	}
+	return s, nil
+}
+
+type openAIMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []openAIToolCall `json:"tool_calls,omitempty"`
+}
+
+type openAIToolCall struct {
+	Function struct {
+		Name string `json:"name"`
+		// Arguments is a JSON encoded object
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIRequest struct {
+	Model         string          `json:"model"`
+	Messages      []openAIMessage `json:"messages"`
+	Tools         []openAITool    `json:"tools,omitempty"`
+	Stream        bool            `json:"stream,omitempty"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+		Delta   openAIMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (r *openAIResponse) usage() Usage {
+	if r.Usage == nil {
+		return Usage{}
+	}
+	return Usage{PromptTokens: r.Usage.PromptTokens, CompletionTokens: r.Usage.CompletionTokens}
+}
+
+// openAISession keeps the message history client side
+type openAISession struct {
+	client   *OpenAIClient
+	messages []openAIMessage
+}
+
+func (s *openAISession) post(ctx context.Context, stream bool, tools []Tool) (*http.Response, error) {
+	req := openAIRequest{Model: s.client.Model, Messages: s.messages, Stream: stream}
+	if len(tools) > 0 {
+		req.Tools = toOpenAITools(tools)
+	}
+	if stream {
+		req.StreamOptions = &struct {
+			IncludeUsage bool `json:"include_usage"`
+		}{IncludeUsage: true}
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", s.client.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if s.client.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+s.client.APIKey)
+	}
+	resp, err := s.client.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+func (s *openAISession) Send(ctx context.Context, message string) (*Reply, error) {
+	return s.SendWithTools(ctx, message, nil)
+}
+
+func (s *openAISession) SendWithTools(ctx context.Context, message string, tools []Tool) (*Reply, error) {
+	s.messages = append(s.messages, openAIMessage{Role: "user", Content: message})
+	resp, err := s.post(ctx, false, tools)
+	if err != nil {
+		s.messages = s.messages[:len(s.messages)-1]
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		s.messages = s.messages[:len(s.messages)-1]
+		return nil, err
+	}
+	reply := &Reply{Usage: out.usage()}
+	if len(out.Choices) > 0 {
+		msg := out.Choices[0].Message
+		reply.Text = msg.Content
+		for _, call := range msg.ToolCalls {
+			args := map[string]interface{}{}
+			if call.Function.Arguments != "" {
+				if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+					s.messages = s.messages[:len(s.messages)-1]
+					return nil, fmt.Errorf("invalid arguments for %s: %v", call.Function.Name, err)
+				}
+			}
+			reply.ToolCalls = append(reply.ToolCalls, ToolCall{Name: call.Function.Name, Arguments: args})
+		}
+	}
+	s.messages = append(s.messages, openAIMessage{Role: "assistant", Content: describeToolCalls(reply)})
+	return reply, nil
+}
+
+func (s *openAISession) Stream(ctx context.Context, message string, onToken func(string)) (*Reply, error) {
+	s.messages = append(s.messages, openAIMessage{Role: "user", Content: message})
+	resp, err := s.post(ctx, true, nil)
+	if err != nil {
+		s.messages = s.messages[:len(s.messages)-1]
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	reply := &Reply{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue
+		}
+		if chunk.Usage != nil {
+			reply.Usage = chunk.usage()
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			token := chunk.Choices[0].Delta.Content
+			reply.Text += token
+			if onToken != nil {
+				onToken(token)
+			}
+		}
+	}
+	s.messages = append(s.messages, openAIMessage{Role: "assistant", Content: reply.Text})
+	if err := scanner.Err(); err != nil {
+		return reply, err
+	}
+	return reply, nil
+}
@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+
+	"org.subh/api-term/pkgs/redact"
+)
+
+// ResumeRedacted resumes a session on a through r: the saved history is
+// redacted before the provider sees it, and so is everything sent after
+func ResumeRedacted(ctx context.Context, a Assistant, history []Turn, r *redact.Redactor) (ToolSession, error) {
+	redacted := make([]Turn, len(history))
+	for i, turn := range history {
+		turn.Text = r.Message(turn.Text)
+		redacted[i] = turn
+	}
+	session, err := a.ResumeSession(ctx, redacted)
+	if err != nil {
+		return nil, err
+	}
+	return Redacted(session, r), nil
+}
+
+// redactedSession applies a redaction policy to every message before it
+// reaches the provider, including JSON pasted into the message
+type redactedSession struct {
+	session  Session
+	redactor *redact.Redactor
+}
+
+// Redacted wraps s so that nothing is sent without passing through r
+func Redacted(s Session, r *redact.Redactor) ToolSession {
+	return &redactedSession{session: s, redactor: r}
+}
+
+func (s *redactedSession) Send(ctx context.Context, message string) (*Reply, error) {
+	return s.session.Send(ctx, s.redactor.Message(message))
+}
+
+func (s *redactedSession) Stream(ctx context.Context, message string, onToken func(string)) (*Reply, error) {
+	return s.session.Stream(ctx, s.redactor.Message(message), onToken)
+}
+
+func (s *redactedSession) SendWithTools(ctx context.Context, message string, tools []Tool) (*Reply, error) {
+	ts, ok := s.session.(ToolSession)
+	if !ok {
+		return nil, fmt.Errorf("this AI provider does not support tool calling")
+	}
+	return ts.SendWithTools(ctx, s.redactor.Message(message), tools)
+}
@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// Tool is a function the assistant may ask the application to call
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON schema of the arguments object
+	Parameters map[string]interface{}
+}
+
+// ToolCall is one function call requested by the assistant
+type ToolCall struct {
+	Name      string
+	Arguments map[string]interface{}
+}
+
+// ToolSession is implemented by sessions whose provider supports function
+// calling
+type ToolSession interface {
+	Session
+	// SendWithTools offers tools for this message only. The calls the
+	// assistant wants to make are returned in Reply.ToolCalls.
+	SendWithTools(ctx context.Context, message string, tools []Tool) (*Reply, error)
+}
+
+// describeToolCalls summarizes calls as text, which is how they are kept in
+// the history of providers that would otherwise expect tool results back
+func describeToolCalls(reply *Reply) string {
+	if len(reply.ToolCalls) == 0 {
+		return reply.Text
+	}
+	lines := []string{}
+	if reply.Text != "" {
+		lines = append(lines, reply.Text)
+	}
+	for _, call := range reply.ToolCalls {
+		args, _ := json.Marshal(call.Arguments)
+		lines = append(lines, "Proposed call: "+call.Name+" "+string(args))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// openAITool is the tool format shared by OpenAI-compatible and Ollama servers
+type openAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string                 `json:"name"`
+		Description string                 `json:"description,omitempty"`
+		Parameters  map[string]interface{} `json:"parameters"`
+	} `json:"function"`
+}
+
+func toOpenAITools(tools []Tool) []openAITool {
+	out := make([]openAITool, 0, len(tools))
+	for _, t := range tools {
+		var tool openAITool
+		tool.Type = "function"
+		tool.Function.Name = t.Name
+		tool.Function.Description = t.Description
+		tool.Function.Parameters = t.Parameters
+		out = append(out, tool)
+	}
+	return out
+}
@@ -0,0 +1,181 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Roles of a conversation turn
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Turn is one message of a conversation
+type Turn struct {
+	Role string    `json:"role"`
+	Text string    `json:"text"`
+	At   time.Time `json:"at"`
+}
+
+// IsInsightsPrompt reports whether the turn is the automatic request for
+// insights sent when the AI pane opens
+func (t Turn) IsInsightsPrompt() bool {
+	return t.Role == RoleUser && strings.HasPrefix(t.Text, insightsPromptPrefix)
+}
+
+// Transcript is a conversation with the assistant together with the API call
+// it was about
+type Transcript struct {
+	ID        string    `json:"id"`
+	Assistant string    `json:"assistant"`
+	StartedAt time.Time `json:"startedAt"`
+	// Operation is the call discussed, e.g. "GET /pets/{id}"
+	Operation  string `json:"operation,omitempty"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	// Context is the request and response as given to the assistant
+	Context string `json:"context,omitempty"`
+	Turns   []Turn `json:"turns"`
+}
+
+// NewTranscript starts a transcript about call, which may be nil
+func NewTranscript(assistant string, call *CallContext) *Transcript {
+	now := time.Now()
+	t := &Transcript{
+		ID:        now.Format("20060102-150405") + fmt.Sprintf("-%06d", now.Nanosecond()/1000),
+		Assistant: assistant,
+		StartedAt: now,
+	}
+	if call != nil {
+		t.Operation = strings.ToUpper(call.Method) + " " + call.Path
+		if call.Response != nil {
+			t.StatusCode = call.Response.StatusCode
+		}
+		t.Context = BuildContext(call)
+	}
+	return t
+}
+
+// Add appends a turn
+func (t *Transcript) Add(role, text string) {
+	t.Turns = append(t.Turns, Turn{Role: role, Text: text, At: time.Now()})
+}
+
+// Title summarizes the transcript on one line for the history list
+func (t *Transcript) Title() string {
+	title := t.StartedAt.Format("2006-01-02 15:04")
+	if t.Operation != "" {
+		title += "  " + t.Operation
+		if t.StatusCode != 0 {
+			title += fmt.Sprintf(" (%d)", t.StatusCode)
+		}
+	}
+	for _, turn := range t.Turns {
+		if turn.Role == RoleUser && !turn.IsInsightsPrompt() {
+			return title + "  " + firstLine(turn.Text)
+		}
+	}
+	return title
+}
+
+// WriteMarkdown renders the transcript for attaching to a postmortem or
+// ticket
+func (t *Transcript) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	heading := "AI Transcript"
+	if t.Operation != "" {
+		heading += ": " + t.Operation
+	}
+	fmt.Fprintf(&b, "# %s\n\n", heading)
+	fmt.Fprintf(&b, "- Started: %s\n", t.StartedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Assistant: %s\n", t.Assistant)
+	if t.StatusCode != 0 {
+		fmt.Fprintf(&b, "- Status: %d\n", t.StatusCode)
+	}
+	if t.Context != "" {
+		b.WriteString("\n## Request and Response\n\n```\n")
+		b.WriteString(strings.TrimRight(t.Context, "\n"))
+		b.WriteString("\n```\n")
+	}
+	b.WriteString("\n## Conversation\n")
+	for _, turn := range t.Turns {
+		who := "You"
+		if turn.Role == RoleAssistant {
+			who = "Assistant"
+		}
+		text := turn.Text
+		if turn.IsInsightsPrompt() {
+			text = "_Asked for insights on the response above._"
+		}
+		fmt.Fprintf(&b, "\n### %s (%s)\n\n%s\n", who, turn.At.Format("15:04:05"), strings.TrimSpace(text))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// TranscriptStore keeps transcripts as JSON files in a directory
+type TranscriptStore struct {
+	Dir string
+}
+
+// NewTranscriptStore creates a store in dir; the directory is created on
+// the first save
+func NewTranscriptStore(dir string) *TranscriptStore {
+	return &TranscriptStore{Dir: dir}
+}
+
+// Save writes t, replacing an earlier save of the same transcript
+func (s *TranscriptStore) Save(t *Transcript) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.Dir, t.ID+".json"), data, 0o600)
+}
+
+// List returns all saved transcripts, newest first. A missing directory is
+// an empty history.
+func (s *TranscriptStore) List() ([]*Transcript, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []*Transcript
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		t, err := s.Load(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.After(out[j].StartedAt) })
+	return out, nil
+}
+
+// Load reads the transcript with the given ID
+func (s *TranscriptStore) Load(id string) (*Transcript, error) {
+	data, err := os.ReadFile(filepath.Join(s.Dir, id+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var t Transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("%s: %v", id, err)
+	}
+	return &t, nil
+}
@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"org.subh/api-term/pkgs/api/client"
+	"org.subh/api-term/pkgs/redact"
+)
+
+func TestTranscriptStore(t *testing.T) {
+	store := NewTranscriptStore(t.TempDir() + "/transcripts")
+	if items, err := store.List(); err != nil || len(items) != 0 {
+		t.Fatalf("expected empty history before the first save, got %v %v", items, err)
+	}
+
+	call := &CallContext{Method: "get", Path: "/pets/{id}", Response: &client.Response{StatusCode: 500, Body: `{"error":"boom"}`}}
+	older := NewTranscript("ollama/llama3.1", call)
+	older.StartedAt = older.StartedAt.Add(-time.Hour)
+	older.Add(RoleUser, InsightsPrompt(call, ""))
+	older.Add(RoleAssistant, "The server failed.")
+	older.Add(RoleUser, "Why did it fail?")
+	newer := NewTranscript("ollama/llama3.1", nil)
+	newer.ID += "-b"
+	for _, tr := range []*Transcript{older, newer} {
+		if err := store.Save(tr); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	items, err := store.List()
+	if err != nil || len(items) != 2 || items[0].ID != newer.ID {
+		t.Fatalf("expected newest first, got %v %v", items, err)
+	}
+	loaded := items[1]
+	if len(loaded.Turns) != 3 || loaded.Operation != "GET /pets/{id}" || loaded.StatusCode != 500 {
+		t.Errorf("transcript not round-tripped: %+v", loaded)
+	}
+	if title := loaded.Title(); !strings.Contains(title, "GET /pets/{id} (500)  Why did it fail?") {
+		t.Errorf("unexpected title %q", title)
+	}
+
+	var md strings.Builder
+	if err := loaded.WriteMarkdown(&md); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# AI Transcript: GET /pets/{id}", "## Request and Response", `"error": "boom"`, "_Asked for insights on the response above._", "### Assistant", "Why did it fail?"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown is missing %q:\n%s", want, md.String())
+		}
+	}
+}
+
+func TestOpenAIClient_ResumeSession(t *testing.T) {
+	var sent []openAIMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		sent = req.Messages
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"again"}}]}`)
+	}))
+	defer ts.Close()
+
+	history := []Turn{{Role: RoleUser, Text: "first"}, {Role: RoleAssistant, Text: "answer"}}
+	session, err := NewOpenAIClient(ts.URL, "m").ResumeSession(context.Background(), history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.Send(context.Background(), "follow up"); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 3 || sent[0].Content != "first" || sent[1].Role != "assistant" || sent[2].Content != "follow up" {
+		t.Errorf("history not resumed: %+v", sent)
+	}
+}
+
+func TestResumeRedacted(t *testing.T) {
+	var sent []openAIMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		sent = req.Messages
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"again"}}]}`)
+	}))
+	defer ts.Close()
+
+	r, err := redact.New(&redact.Policy{Fields: []string{"^dob$"}, Exclude: []string{"$.internal"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := []Turn{
+		{Role: RoleUser, Text: `Why? {"internal":{"trace":"t-1"},"dob":"1990-01-01"}`},
+		{Role: RoleAssistant, Text: "mail ann@example.com"},
+	}
+	session, err := ResumeRedacted(context.Background(), NewOpenAIClient(ts.URL, "m"), history, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.Send(context.Background(), `and {"dob":"2000-01-01"}`); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 3 {
+		t.Fatalf("history not resumed: %+v", sent)
+	}
+	for _, m := range sent {
+		for _, secret := range []string{"t-1", "1990-01-01", "2000-01-01", "ann@example.com"} {
+			if strings.Contains(m.Content, secret) {
+				t.Errorf("%q reached the provider: %+v", secret, sent)
+			}
+		}
+	}
+	if history[0].Text != `Why? {"internal":{"trace":"t-1"},"dob":"1990-01-01"}` {
+		t.Error("the saved history was modified")
+	}
+}
@@ -0,0 +1,592 @@
+// Package asyncapi converts AsyncAPI 2.x and 3.x documents into OpenAPI
+// documents, so their channels are listed and invoked like REST endpoints.
+//
+// Channels reached over WebSocket become GET operations marked x-websocket,
+// with the messages a client sends as the request body and the messages it
+// receives as the 101 response. Operations with HTTP bindings become
+// requests with the binding's method: messages the client sends are the
+// request body and messages it receives are the 200 response.
+package asyncapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+type node = map[string]interface{}
+
+// Protocols an operation can be invoked with
+const (
+	protocolHTTP = "http"
+	protocolWS   = "ws"
+)
+
+// IsAsyncAPI reports whether data is an AsyncAPI document
+func IsAsyncAPI(data []byte) bool {
+	var head struct {
+		AsyncAPI string `yaml:"asyncapi"`
+	}
+	return yaml.Unmarshal(data, &head) == nil && head.AsyncAPI != ""
+}
+
+// Convert turns an AsyncAPI document into an OpenAPI document. Operations
+// over protocols other than HTTP and WebSocket are left out and reported as
+// warnings.
+func Convert(data []byte) (*openapi3.T, []string, error) {
+	var root node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, fmt.Errorf("invalid AsyncAPI document: %v", err)
+	}
+	version, _ := root["asyncapi"].(string)
+	c := &converter{root: root, paths: map[string]node{}}
+	switch {
+	case strings.HasPrefix(version, "2."):
+		c.channels2()
+	case strings.HasPrefix(version, "3."):
+		c.operations3()
+	default:
+		return nil, nil, fmt.Errorf("unsupported AsyncAPI version %q", version)
+	}
+
+	info := node{"title": "AsyncAPI", "version": "0.0.0"}
+	if in, ok := root["info"].(node); ok {
+		for _, key := range []string{"title", "version", "description"} {
+			if v, ok := in[key].(string); ok && v != "" {
+				info[key] = v
+			}
+		}
+	}
+	doc := node{"openapi": "3.0.3", "info": info, "paths": c.pathItems()}
+	if servers := c.servers(); len(servers) > 0 {
+		doc["servers"] = servers
+	}
+	if schemas, ok := c.lookup("#/components/schemas").(node); ok {
+		converted := node{}
+		for name, schema := range schemas {
+			converted[name] = jsonSchema(schema)
+		}
+		doc["components"] = node{"schemas": converted}
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, c.warnings, err
+	}
+	t, err := openapi3.NewLoader().LoadFromData(out)
+	if err != nil {
+		return nil, c.warnings, fmt.Errorf("converting AsyncAPI document: %v", err)
+	}
+	return t, c.warnings, nil
+}
+
+type converter struct {
+	root     node
+	paths    map[string]node
+	warnings []string
+}
+
+// operation is one direction of traffic on a channel, seen from the client
+type operation struct {
+	id, summary, description string
+	// send is true when the client sends the messages
+	send     bool
+	messages []node
+	bindings node
+}
+
+// channels2 reads AsyncAPI 2.x channels, where publish means the client
+// sends and subscribe means the client receives
+func (c *converter) channels2() {
+	channels, _ := c.root["channels"].(node)
+	for _, name := range sortedKeys(channels) {
+		channel, _ := c.resolve(channels[name]).(node)
+		var ops []*operation
+		for _, kind := range []string{"publish", "subscribe"} {
+			op, ok := c.resolve(channel[kind]).(node)
+			if !ok {
+				continue
+			}
+			ops = append(ops, &operation{
+				id:          str(op, "operationId"),
+				summary:     str(op, "summary"),
+				description: str(op, "description"),
+				send:        kind == "publish",
+				messages:    c.messages(op["message"]),
+				bindings:    c.bindings(op),
+			})
+		}
+		c.addChannel(name, channel, ops, serverNames(channel["servers"]))
+	}
+}
+
+// operations3 reads AsyncAPI 3.x operations, where send means the
+// application sends, so the client receives
+func (c *converter) operations3() {
+	channels, _ := c.root["channels"].(node)
+	byChannel := map[string][]*operation{}
+	operations, _ := c.root["operations"].(node)
+	for _, id := range sortedKeys(operations) {
+		op, ok := c.resolve(operations[id]).(node)
+		if !ok {
+			continue
+		}
+		ref, _ := c.refOf(op["channel"])
+		channelID := strings.TrimPrefix(ref, "#/channels/")
+		channel, _ := c.resolve(op["channel"]).(node)
+		if channel == nil {
+			c.warnings = append(c.warnings, fmt.Sprintf("operation %s has no channel", id))
+			continue
+		}
+		var messages []node
+		if list, ok := op["messages"].([]interface{}); ok && len(list) > 0 {
+			for _, m := range list {
+				messages = append(messages, c.messages(m)...)
+			}
+		} else if all, ok := channel["messages"].(node); ok {
+			for _, name := range sortedKeys(all) {
+				messages = append(messages, c.messages(all[name])...)
+			}
+		}
+		byChannel[channelID] = append(byChannel[channelID], &operation{
+			id:          id,
+			summary:     str(op, "summary"),
+			description: str(op, "description"),
+			send:        str(op, "action") == "receive",
+			messages:    messages,
+			bindings:    c.bindings(op),
+		})
+	}
+	for _, id := range sortedKeys(channels) {
+		channel, _ := c.resolve(channels[id]).(node)
+		if len(byChannel[id]) == 0 || channel == nil {
+			continue
+		}
+		address := id
+		if a, ok := channel["address"].(string); ok && a != "" {
+			address = a
+		}
+		var servers []string
+		if list, ok := channel["servers"].([]interface{}); ok {
+			for _, s := range list {
+				if ref, ok := c.refOf(s); ok {
+					servers = append(servers, strings.TrimPrefix(ref, "#/servers/"))
+				}
+			}
+		}
+		c.addChannel(address, channel, byChannel[id], servers)
+	}
+}
+
+// addChannel adds the operations of a channel to the OpenAPI paths
+func (c *converter) addChannel(address string, channel node, ops []*operation, servers []string) {
+	path := "/" + strings.TrimPrefix(address, "/")
+	params := c.parameters(channel)
+	for _, op := range ops {
+		protocol := c.protocol(op, channel, servers)
+		switch protocol {
+		case protocolWS:
+			c.addWebSocket(path, params, channel, op)
+		case protocolHTTP:
+			c.addHTTP(path, params, op)
+		default:
+			label := op.id
+			if label == "" {
+				label = address
+			}
+			if protocol == "" {
+				protocol = "an unknown protocol"
+			}
+			c.warnings = append(c.warnings, fmt.Sprintf("operation %s uses %s, only HTTP and WebSocket are supported", label, protocol))
+		}
+	}
+}
+
+// protocol picks the protocol of an operation from its bindings, the
+// channel's bindings, then the servers the channel is available on
+func (c *converter) protocol(op *operation, channel node, servers []string) string {
+	for _, bindings := range []node{op.bindings, c.bindings(channel)} {
+		if _, ok := bindings["ws"]; ok {
+			return protocolWS
+		}
+		if _, ok := bindings["http"]; ok {
+			return protocolHTTP
+		}
+	}
+	all, _ := c.root["servers"].(node)
+	if len(servers) == 0 {
+		servers = sortedKeys(all)
+	}
+	other := ""
+	for _, name := range servers {
+		server, _ := c.resolve(all[name]).(node)
+		switch p := strings.ToLower(str(server, "protocol")); p {
+		case "ws", "wss":
+			return protocolWS
+		case "http", "https":
+			return protocolHTTP
+		case "":
+		default:
+			other = p
+		}
+	}
+	return other
+}
+
+func (c *converter) addWebSocket(path string, params []interface{}, channel node, op *operation) {
+	item := c.item(path)
+	get, _ := item["get"].(node)
+	if get == nil {
+		get = node{"x-websocket": true, "responses": node{"101": node{"description": "Switching Protocols"}}}
+		if ws, ok := c.bindings(channel)["ws"].(node); ok {
+			params = append(params, bindingParams(ws["query"], "query")...)
+			params = append(params, bindingParams(ws["headers"], "header")...)
+		}
+		if len(params) > 0 {
+			get["parameters"] = params
+		}
+		item["get"] = get
+	}
+	describe(get, op)
+	if op.send {
+		if content := content(op.messages); content != nil {
+			get["requestBody"] = node{"content": content}
+		}
+	} else if content := content(op.messages); content != nil {
+		get["responses"] = node{"101": node{"description": "Messages received after the upgrade", "content": content}}
+	}
+}
+
+func (c *converter) addHTTP(path string, params []interface{}, op *operation) {
+	method := "get"
+	if op.send {
+		method = "post"
+	}
+	if http, ok := op.bindings["http"].(node); ok {
+		if m := strings.ToLower(str(http, "method")); m != "" {
+			method = m
+		}
+	}
+	item := c.item(path)
+	target, _ := item[method].(node)
+	if target == nil {
+		target = node{"responses": node{"200": node{"description": "OK"}}}
+		if len(params) > 0 {
+			target["parameters"] = params
+		}
+		item[method] = target
+	}
+	describe(target, op)
+	content := content(op.messages)
+	if content == nil {
+		return
+	}
+	if op.send && method != "get" {
+		target["requestBody"] = node{"content": content}
+	} else if !op.send {
+		target["responses"] = node{"200": node{"description": "Messages received", "content": content}}
+	}
+}
+
+func (c *converter) item(path string) node {
+	if c.paths[path] == nil {
+		c.paths[path] = node{}
+	}
+	return c.paths[path]
+}
+
+func (c *converter) pathItems() node {
+	items := node{}
+	for path, item := range c.paths {
+		items[path] = item
+	}
+	return items
+}
+
+// describe copies the operation id and texts, keeping the first id when a
+// WebSocket channel has operations both ways
+func describe(target node, op *operation) {
+	if _, ok := target["operationId"]; !ok && op.id != "" {
+		target["operationId"] = op.id
+	}
+	for key, text := range map[string]string{"summary": op.summary, "description": op.description} {
+		if text == "" {
+			continue
+		}
+		if prev, ok := target[key].(string); ok && prev != text {
+			text = prev + "; " + text
+		}
+		target[key] = text
+	}
+}
+
+// parameters maps channel parameters, such as {userId} in the address, to
+// required path parameters
+func (c *converter) parameters(channel node) []interface{} {
+	var params []interface{}
+	defined, _ := channel["parameters"].(node)
+	for _, name := range sortedKeys(defined) {
+		p, _ := c.resolve(defined[name]).(node)
+		schema := node{"type": "string"}
+		if s, ok := p["schema"].(node); ok {
+			schema = jsonSchema(s).(node)
+		} else if enum, ok := p["enum"].([]interface{}); ok {
+			schema["enum"] = enum
+		}
+		param := node{"name": name, "in": "path", "required": true, "schema": schema}
+		if d := str(p, "description"); d != "" {
+			param["description"] = d
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// bindingParams turns the query or headers object schema of a WebSocket
+// binding into parameters
+func bindingParams(schema interface{}, in string) []interface{} {
+	s, _ := schema.(node)
+	props, _ := s["properties"].(node)
+	required := map[string]bool{}
+	if list, ok := s["required"].([]interface{}); ok {
+		for _, r := range list {
+			if name, ok := r.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+	var params []interface{}
+	for _, name := range sortedKeys(props) {
+		params = append(params, node{"name": name, "in": in, "required": required[name], "schema": jsonSchema(props[name])})
+	}
+	return params
+}
+
+// messages resolves a message, or a oneOf of messages, to a list
+func (c *converter) messages(v interface{}) []node {
+	m, ok := c.resolve(v).(node)
+	if !ok {
+		return nil
+	}
+	if list, ok := m["oneOf"].([]interface{}); ok && m["payload"] == nil {
+		var out []node
+		for _, item := range list {
+			out = append(out, c.messages(item)...)
+		}
+		return out
+	}
+	msg := node{}
+	for k, v := range m {
+		msg[k] = v
+	}
+	payload := m["payload"]
+	// AsyncAPI 3 allows a multi-format schema around the payload
+	if p, ok := payload.(node); ok && p["schemaFormat"] != nil && p["schema"] != nil {
+		payload = p["schema"]
+	}
+	if ref, ok := c.refOf(payload); !ok || !strings.HasPrefix(ref, "#/components/schemas/") {
+		payload = c.resolve(payload)
+	}
+	if payload != nil {
+		msg["payload"] = jsonSchema(payload)
+	}
+	if msg["contentType"] == nil {
+		contentType := str(c.root, "defaultContentType")
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		msg["contentType"] = contentType
+	}
+	return []node{msg}
+}
+
+// content builds OpenAPI content from messages, grouped by content type,
+// with a oneOf schema when several messages share one
+func content(messages []node) node {
+	byType := map[string][]node{}
+	for _, m := range messages {
+		t := str(m, "contentType")
+		byType[t] = append(byType[t], m)
+	}
+	out := node{}
+	for t, list := range byType {
+		media := node{}
+		var schemas []interface{}
+		for _, m := range list {
+			if m["payload"] != nil {
+				schemas = append(schemas, m["payload"])
+			}
+			if _, ok := media["example"]; !ok {
+				if examples, ok := m["examples"].([]interface{}); ok && len(examples) > 0 {
+					if ex, ok := examples[0].(node); ok && ex["payload"] != nil {
+						media["example"] = ex["payload"]
+					}
+				}
+			}
+		}
+		switch len(schemas) {
+		case 0:
+		case 1:
+			media["schema"] = schemas[0]
+		default:
+			media["schema"] = node{"oneOf": schemas}
+		}
+		out[t] = media
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// servers maps AsyncAPI servers to OpenAPI servers, with ws and wss URLs
+// turned into http and https
+func (c *converter) servers() []interface{} {
+	all, _ := c.root["servers"].(node)
+	var servers []interface{}
+	for _, name := range sortedKeys(all) {
+		s, _ := c.resolve(all[name]).(node)
+		protocol := strings.ToLower(str(s, "protocol"))
+		scheme := map[string]string{"ws": "http", "http": "http", "wss": "https", "https": "https"}[protocol]
+		if scheme == "" {
+			continue
+		}
+		url := str(s, "url")
+		if host := str(s, "host"); host != "" {
+			url = host + str(s, "pathname")
+		}
+		if i := strings.Index(url, "://"); i >= 0 {
+			url = url[i+3:]
+		}
+		server := node{"url": scheme + "://" + url, "description": name}
+		if vars, ok := s["variables"].(node); ok {
+			server["variables"] = vars
+		}
+		servers = append(servers, server)
+	}
+	return servers
+}
+
+func (c *converter) bindings(v node) node {
+	b, _ := c.resolve(v["bindings"]).(node)
+	return b
+}
+
+// refOf returns the $ref of a reference object
+func (c *converter) refOf(v interface{}) (string, bool) {
+	m, ok := v.(node)
+	if !ok {
+		return "", false
+	}
+	ref, ok := m["$ref"].(string)
+	return ref, ok
+}
+
+// resolve follows local $refs, returning nil for ones that don't resolve
+func (c *converter) resolve(v interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		ref, ok := c.refOf(v)
+		if !ok {
+			return v
+		}
+		v = c.lookup(ref)
+	}
+	return nil
+}
+
+// lookup finds a JSON pointer such as #/components/messages/userSignedUp
+func (c *converter) lookup(ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var cur interface{} = c.root
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := cur.(node)
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+// jsonSchema adapts a JSON Schema to the OpenAPI 3.0 dialect: const becomes
+// a single value enum, type lists become nullable types and the first of
+// examples becomes the example
+func jsonSchema(v interface{}) interface{} {
+	switch s := v.(type) {
+	case node:
+		out := node{}
+		for k, value := range s {
+			switch k {
+			case "$id", "$schema", "$comment":
+			case "const":
+				out["enum"] = []interface{}{value}
+			case "examples":
+				if list, ok := value.([]interface{}); ok && len(list) > 0 {
+					out["example"] = list[0]
+				}
+			case "type":
+				if list, ok := value.([]interface{}); ok {
+					for _, t := range list {
+						if t == "null" {
+							out["nullable"] = true
+						} else if out["type"] == nil {
+							out["type"] = t
+						}
+					}
+				} else {
+					out["type"] = value
+				}
+			case "properties", "patternProperties":
+				props, _ := value.(node)
+				converted := node{}
+				for name, p := range props {
+					converted[name] = jsonSchema(p)
+				}
+				out[k] = converted
+			case "items", "additionalProperties", "not", "allOf", "oneOf", "anyOf":
+				out[k] = jsonSchema(value)
+			default:
+				out[k] = value
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(s))
+		for i, item := range s {
+			out[i] = jsonSchema(item)
+		}
+		return out
+	}
+	return v
+}
+
+func serverNames(v interface{}) []string {
+	list, _ := v.([]interface{})
+	var names []string
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			names = append(names, s)
+		}
+	}
+	return names
+}
+
+func str(m node, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+func sortedKeys(m node) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,184 @@
+package asyncapi
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const streetlights2 = `
+asyncapi: '2.6.0'
+info:
+  title: Market Feed
+  version: '1.0.0'
+defaultContentType: application/json
+servers:
+  production:
+    url: feed.example.com/v1
+    protocol: wss
+  broker:
+    url: kafka.example.com:9092
+    protocol: kafka
+channels:
+  /ws/prices/{symbol}:
+    servers: [production]
+    parameters:
+      symbol:
+        description: Ticker symbol
+        schema:
+          type: string
+    bindings:
+      ws:
+        query:
+          type: object
+          properties:
+            depth:
+              type: integer
+    publish:
+      operationId: subscribePrices
+      message:
+        $ref: '#/components/messages/Subscribe'
+    subscribe:
+      operationId: receivePrices
+      message:
+        oneOf:
+          - $ref: '#/components/messages/Price'
+          - name: heartbeat
+            payload:
+              type: object
+              properties:
+                type:
+                  const: heartbeat
+  trades:
+    servers: [broker]
+    subscribe:
+      operationId: receiveTrades
+      message:
+        payload:
+          type: object
+components:
+  messages:
+    Subscribe:
+      payload:
+        type: object
+        required: [action]
+        properties:
+          action:
+            type: string
+            enum: [subscribe, unsubscribe]
+      examples:
+        - payload:
+            action: subscribe
+    Price:
+      payload:
+        $ref: '#/components/schemas/Price'
+  schemas:
+    Price:
+      type: object
+      properties:
+        symbol:
+          type: string
+        bid:
+          type: [number, 'null']
+`
+
+func TestConvert2(t *testing.T) {
+	if !IsAsyncAPI([]byte(streetlights2)) || IsAsyncAPI([]byte("openapi: 3.0.0\n")) {
+		t.Fatal("unexpected document detection")
+	}
+	doc, warnings, err := Convert([]byte(streetlights2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Errorf("converted document is invalid: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "receiveTrades uses kafka") {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	if doc.Paths.Len() != 1 {
+		t.Fatalf("expected only the WebSocket channel, got %v", doc.Paths.InMatchingOrder())
+	}
+	op := doc.Paths.Value("/ws/prices/{symbol}").Get
+	if op == nil || op.Extensions["x-websocket"] != true || op.OperationID != "subscribePrices" {
+		t.Fatalf("unexpected operation %+v", op)
+	}
+	if len(op.Parameters) != 2 || op.Parameters[0].Value.In != "path" || op.Parameters[1].Value.Name != "depth" {
+		t.Errorf("unexpected parameters %+v", op.Parameters)
+	}
+	media := op.RequestBody.Value.Content.Get("application/json")
+	if media == nil || media.Example == nil || media.Schema.Value.Properties["action"] == nil {
+		t.Errorf("client messages not in the request body: %+v", media)
+	}
+	received := op.Responses.Value("101").Value.Content.Get("application/json").Schema.Value
+	if len(received.OneOf) != 2 || received.OneOf[0].Value.Properties["bid"].Value.Nullable != true {
+		t.Errorf("unexpected received messages %+v", received)
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "https://feed.example.com/v1" {
+		t.Errorf("unexpected servers %+v", doc.Servers)
+	}
+}
+
+const orders3 = `
+asyncapi: 3.0.0
+info:
+  title: Orders
+  version: 2.0.0
+servers:
+  api:
+    host: api.example.com
+    pathname: /events
+    protocol: https
+channels:
+  orderCreated:
+    address: orders/{orderId}/created
+    parameters:
+      orderId:
+        description: Order id
+    messages:
+      created:
+        contentType: application/json
+        payload:
+          schemaFormat: application/schema+json;version=draft-07
+          schema:
+            type: object
+            properties:
+              orderId:
+                type: string
+operations:
+  publishOrderCreated:
+    action: receive
+    channel:
+      $ref: '#/channels/orderCreated'
+    bindings:
+      http:
+        method: PUT
+  pollOrderCreated:
+    action: send
+    channel:
+      $ref: '#/channels/orderCreated'
+`
+
+func TestConvert3(t *testing.T) {
+	doc, warnings, err := Convert([]byte(orders3))
+	if err != nil || len(warnings) != 0 {
+		t.Fatal(err, warnings)
+	}
+	item := doc.Paths.Value("/orders/{orderId}/created")
+	if item == nil || item.Put == nil || item.Get == nil {
+		t.Fatalf("unexpected paths %v", doc.Paths.InMatchingOrder())
+	}
+	if item.Put.OperationID != "publishOrderCreated" || item.Put.RequestBody.Value.Content.Get("application/json").Schema.Value.Properties["orderId"] == nil {
+		t.Errorf("unexpected PUT %+v", item.Put)
+	}
+	if item.Get.Responses.Value("200").Value.Content.Get("application/json") == nil || item.Get.Extensions["x-websocket"] != nil {
+		t.Errorf("unexpected GET %+v", item.Get)
+	}
+	if doc.Servers[0].URL != "https://api.example.com/events" {
+		t.Errorf("unexpected server %s", doc.Servers[0].URL)
+	}
+
+	if _, _, err := Convert([]byte("asyncapi: 1.2.0\n")); err == nil {
+		t.Error("expected an unsupported version error")
+	}
+}
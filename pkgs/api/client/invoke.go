@@ -1,16 +1,47 @@
 package client
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"org.subh/api-term/pkgs/api/model"
 )
 
+// Response holds everything observed about a single invocation
+type Response struct {
+	Body       string
+	StatusCode int
+	Header     http.Header
+	Duration   time.Duration
+	// URL is where the request was sent
+	URL string
+}
+
 func InvokeEndpoint(baseURL string, ep *model.Endpoint, inputValues map[string]string, headerValues map[string]string, body string, contentType string) (string, int, error) {
+	resp, err := Invoke(baseURL, ep, inputValues, headerValues, body, contentType)
+	if err != nil {
+		return "", 0, err
+	}
+	return resp.Body, resp.StatusCode, nil
+}
+
+// Invoke sends the request for ep and returns the full response, including headers and latency
+func Invoke(baseURL string, ep *model.Endpoint, inputValues map[string]string, headerValues map[string]string, body string, contentType string) (*Response, error) {
+	url, err := BuildURL(baseURL, ep, inputValues)
+	if err != nil {
+		return nil, err
+	}
+	return Do(ep.Method, url, headerValues, body, contentType)
+}
+
+// BuildURL fills the path parameters of ep and appends the other input
+// values as query parameters
+func BuildURL(baseURL string, ep *model.Endpoint, inputValues map[string]string) (string, error) {
 	finalPath := ep.Path
 
 	usedParams := make(map[string]bool)
@@ -20,7 +51,7 @@ func InvokeEndpoint(baseURL string, ep *model.Endpoint, inputValues map[string]s
 		if param.In == "path" {
 			val, ok := inputValues[param.Name]
 			if !ok {
-				return "", 0, fmt.Errorf("Missing path param: %s", param.Name)
+				return "", fmt.Errorf("Missing path param: %s", param.Name)
 			}
 			finalPath = strings.Replace(finalPath, "{"+param.Name+"}", val, 1)
 			usedParams[param.Name] = true
@@ -34,7 +65,7 @@ func InvokeEndpoint(baseURL string, ep *model.Endpoint, inputValues map[string]s
 			val, ok := inputValues[param.Name]
 			if !ok {
 				if param.Required {
-					return "", 0, fmt.Errorf("Missing query param: %s", param.Name)
+					return "", fmt.Errorf("Missing query param: %s", param.Name)
 				}
 				continue
 			}
@@ -55,21 +86,44 @@ func InvokeEndpoint(baseURL string, ep *model.Endpoint, inputValues map[string]s
 	if len(queryParts) > 0 {
 		finalPath += "?" + strings.Join(queryParts, "&")
 	}
+	return baseURL + finalPath, nil
+}
+
+// Do sends a request to a complete URL, as found in pagination links
+func Do(method, url string, headerValues map[string]string, body string, contentType string) (*Response, error) {
+	resp, reader, err := Open(context.Background(), method, url, headerValues, body, contentType)
+	if err != nil {
+		return nil, err
+	}
+	ReadBody(resp, reader)
+	return resp, nil
+}
 
-	url := baseURL + finalPath
+// ReadBody reads the rest of a body returned by Open into resp
+func ReadBody(resp *Response, body io.ReadCloser) {
+	defer body.Close()
+	start := time.Now()
+	respBody, _ := io.ReadAll(body)
+	resp.Body = string(respBody)
+	resp.Duration += time.Since(start)
+}
 
+// Open sends a request and returns as soon as the response headers arrive,
+// leaving the body to be read as it streams in. Cancelling ctx stops the
+// stream. The returned Response has no Body yet and its Duration is the time
+// to the headers.
+func Open(ctx context.Context, method, url string, headerValues map[string]string, body string, contentType string) (*Response, io.ReadCloser, error) {
 	var req *http.Request
-	var resp *http.Response
 	var err error
 
 	if body != "" {
-		req, err = http.NewRequest(ep.Method, url, strings.NewReader(body))
+		req, err = http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
 	} else {
-		req, err = http.NewRequest(ep.Method, url, nil)
+		req, err = http.NewRequestWithContext(ctx, method, url, nil)
 	}
 
 	if err != nil {
-		return "", 0, fmt.Errorf("Error: %v", err)
+		return nil, nil, fmt.Errorf("Error: %v", err)
 	}
 
 	if contentType != "" {
@@ -81,11 +135,15 @@ func InvokeEndpoint(baseURL string, ep *model.Endpoint, inputValues map[string]s
 			req.Header.Set(k, v)
 		}
 	}
-	resp, err = http.DefaultClient.Do(req)
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", 0, fmt.Errorf("Error: %v", err)
+		return nil, nil, fmt.Errorf("Error: %v", err)
 	}
-	defer resp.Body.Close()
-	respBody, _ := ioutil.ReadAll(resp.Body)
-	return string(respBody), resp.StatusCode, nil
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Duration:   time.Since(start),
+		URL:        url,
+	}, resp.Body, nil
 }
@@ -1,6 +1,11 @@
 package client
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -58,3 +63,31 @@ func TestInvokeEndpoint_MissingQueryParam(t *testing.T) {
 		t.Errorf("Expected error containing %q, got %q", expectedError, err.Error())
 	}
 }
+
+func TestOpenStreamsBody(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+		fmt.Fprint(w, "data: second\n\n")
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resp, body, err := Open(ctx, "GET", server.URL+"/events", nil, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	line, err := bufio.NewReader(body).ReadString('\n')
+	if err != nil || line != "data: first\n" {
+		t.Errorf("expected the first event before the stream ends, got %q, %v", line, err)
+	}
+}
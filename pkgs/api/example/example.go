@@ -0,0 +1,161 @@
+package example
+
+import (
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// maxDepth bounds recursion through self-referencing schemas
+const maxDepth = 8
+
+// ForMedia returns an example value for a media type, preferring the
+// documented example, then the first named example, then generated data
+func ForMedia(media *openapi3.MediaType) interface{} {
+	if media == nil {
+		return nil
+	}
+	if media.Example != nil {
+		return media.Example
+	}
+	if len(media.Examples) > 0 {
+		names := make([]string, 0, len(media.Examples))
+		for name := range media.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if ex := media.Examples[names[0]]; ex != nil && ex.Value != nil && ex.Value.Value != nil {
+			return ex.Value.Value
+		}
+	}
+	if media.Schema != nil {
+		return FromSchema(media.Schema.Value)
+	}
+	return nil
+}
+
+// FromSchema generates a value that validates against the schema
+func FromSchema(schema *openapi3.Schema) interface{} {
+	return generate(schema, 0)
+}
+
+func generate(s *openapi3.Schema, depth int) interface{} {
+	if s == nil || depth > maxDepth {
+		return nil
+	}
+	if s.Example != nil {
+		return s.Example
+	}
+	if s.Default != nil {
+		return s.Default
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0]
+	}
+
+	if len(s.AllOf) > 0 {
+		merged := map[string]interface{}{}
+		for _, ref := range s.AllOf {
+			if ref == nil {
+				continue
+			}
+			if obj, ok := generate(ref.Value, depth+1).(map[string]interface{}); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		for k, v := range generateProperties(s, depth) {
+			merged[k] = v
+		}
+		return merged
+	}
+	if len(s.OneOf) > 0 && s.OneOf[0] != nil {
+		return generate(s.OneOf[0].Value, depth+1)
+	}
+	if len(s.AnyOf) > 0 && s.AnyOf[0] != nil {
+		return generate(s.AnyOf[0].Value, depth+1)
+	}
+
+	switch {
+	case s.Type.Includes(openapi3.TypeObject) || (s.Type == nil && len(s.Properties) > 0):
+		return generateProperties(s, depth)
+	case s.Type.Includes(openapi3.TypeArray):
+		count := int(s.MinItems)
+		if count == 0 {
+			count = 1
+		}
+		items := make([]interface{}, 0, count)
+		for i := 0; i < count; i++ {
+			var item interface{}
+			if s.Items != nil {
+				item = generate(s.Items.Value, depth+1)
+			}
+			items = append(items, item)
+		}
+		return items
+	case s.Type.Includes(openapi3.TypeString):
+		return generateString(s)
+	case s.Type.Includes(openapi3.TypeInteger):
+		return int64(generateNumber(s))
+	case s.Type.Includes(openapi3.TypeNumber):
+		return generateNumber(s)
+	case s.Type.Includes(openapi3.TypeBoolean):
+		return true
+	}
+	return nil
+}
+
+func generateProperties(s *openapi3.Schema, depth int) map[string]interface{} {
+	obj := map[string]interface{}{}
+	for name, ref := range s.Properties {
+		if ref == nil || (ref.Value != nil && ref.Value.WriteOnly) {
+			continue
+		}
+		obj[name] = generate(ref.Value, depth+1)
+	}
+	return obj
+}
+
+func generateString(s *openapi3.Schema) string {
+	var v string
+	switch s.Format {
+	case "date-time":
+		v = "2024-01-01T00:00:00Z"
+	case "date":
+		v = "2024-01-01"
+	case "email":
+		v = "user@example.com"
+	case "uuid":
+		v = "00000000-0000-4000-8000-000000000000"
+	case "uri", "url":
+		v = "https://example.com"
+	case "ipv4":
+		v = "192.0.2.1"
+	case "byte":
+		v = "c3RyaW5n"
+	default:
+		v = "string"
+	}
+	for uint64(len(v)) < s.MinLength {
+		v += "x"
+	}
+	if s.MaxLength != nil && uint64(len(v)) > *s.MaxLength {
+		v = v[:*s.MaxLength]
+	}
+	return v
+}
+
+func generateNumber(s *openapi3.Schema) float64 {
+	v := 1.0
+	if s.Min != nil {
+		v = *s.Min
+		if s.ExclusiveMin {
+			v++
+		}
+	}
+	if s.Max != nil && v > *s.Max {
+		v = *s.Max
+	}
+	return v
+}
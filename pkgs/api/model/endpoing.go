@@ -10,4 +10,15 @@ type Endpoint struct {
 	Method     string
 	Path       string
 	Parameters []*Parameter
+	// WebSocket is set for endpoints that upgrade to a WebSocket
+	WebSocket bool
+	// Webhook names the OpenAPI 3.1 webhook the endpoint describes; webhooks
+	// have no path and are delivered to the base URL
+	Webhook string
+	// GRPC is how a gRPC method streams ("unary", "server streaming", ...);
+	// the path of gRPC endpoints is the full method name
+	GRPC string
+	// GraphQL is the operation type of GraphQL root fields ("query",
+	// "mutation" or "subscription"); the path is the field name
+	GraphQL string
 }
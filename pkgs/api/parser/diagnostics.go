@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Severity tells whether a document could be used despite a diagnostic
+type Severity string
+
+const (
+	// SeverityError means the document could not be loaded
+	SeverityError Severity = "error"
+	// SeverityWarning means the document loaded but is invalid or was
+	// converted with losses
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found while loading a document
+type Diagnostic struct {
+	// Source is the file path or URL of the document
+	Source string
+	// Pointer is a JSON pointer to the invalid part of the document, or
+	// empty when the problem is with the whole document
+	Pointer  string
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	location := d.Source
+	if d.Pointer != "" {
+		location += "#" + d.Pointer
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Severity, location, d.Message)
+}
+
+// HasErrors reports whether a document failed to load
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// validationContext matches the prefixes kin-openapi wraps validation errors
+// in, from the document down to the invalid part
+var validationContext = []struct {
+	re      *regexp.Regexp
+	pointer func(m []string) string
+}{
+	{regexp.MustCompile(`^invalid (components|info|paths|security|servers|tags|external docs): `), func(m []string) string {
+		if m[1] == "external docs" {
+			return "/externalDocs"
+		}
+		return "/" + m[1]
+	}},
+	{regexp.MustCompile(`^invalid path (\S+): `), func(m []string) string { return "/" + escapePointer(m[1]) }},
+	{regexp.MustCompile(`^invalid operation (\w+): `), func(m []string) string { return "/" + strings.ToLower(m[1]) }},
+	{regexp.MustCompile(`^(schema|parameter|request body|response|header|security scheme|example|link|callback) "([^"]+)": `), func(m []string) string {
+		return "/" + componentSections[m[1]] + "/" + escapePointer(m[2])
+	}},
+}
+
+var componentSections = map[string]string{
+	"schema":          "schemas",
+	"parameter":       "parameters",
+	"request body":    "requestBodies",
+	"response":        "responses",
+	"header":          "headers",
+	"security scheme": "securitySchemes",
+	"example":         "examples",
+	"link":            "links",
+	"callback":        "callbacks",
+}
+
+// validationDiagnostic turns a validation error into a warning pointing at
+// the invalid part of the document
+func validationDiagnostic(source string, err error) Diagnostic {
+	d := Diagnostic{Source: source, Severity: SeverityWarning, Message: err.Error()}
+	for matched := true; matched; {
+		matched = false
+		for _, c := range validationContext {
+			if m := c.re.FindStringSubmatch(d.Message); m != nil {
+				d.Pointer += c.pointer(m)
+				d.Message = d.Message[len(m[0]):]
+				matched = true
+				break
+			}
+		}
+	}
+	return d
+}
+
+// escapePointer escapes a JSON pointer token
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
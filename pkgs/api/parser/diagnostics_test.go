@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const invalidSpec = `
+openapi: 3.0.3
+info:
+  title: Pets
+  version: 1.0.0
+paths:
+  /pets/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          schema:
+            type: string
+      responses:
+        '200':
+          description: a pet
+`
+
+func TestLoadDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.yaml")
+	if err := os.WriteFile(spec, []byte(invalidSpec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.yaml")
+
+	endpoints, diags := ParseOpenAPI([]string{spec, missing}, []string{"http://127.0.0.1:1/openapi.yaml"})
+	if len(endpoints) != 1 {
+		t.Errorf("the invalid document should still be used, got %d endpoints", len(endpoints))
+	}
+	if len(diags) != 3 || !HasErrors(diags) {
+		t.Fatalf("unexpected diagnostics %v", diags)
+	}
+	bySource := map[string]Diagnostic{}
+	for _, d := range diags {
+		bySource[d.Source] = d
+	}
+	if d := bySource[missing]; d.Severity != SeverityError || !strings.Contains(d.Message, "no such file") {
+		t.Errorf("unexpected diagnostic for the missing file %+v", d)
+	}
+	if d := bySource["http://127.0.0.1:1/openapi.yaml"]; d.Severity != SeverityError {
+		t.Errorf("unexpected diagnostic for the unreachable URL %+v", d)
+	}
+	d := bySource[spec]
+	if d.Severity != SeverityWarning || d.Pointer != "/paths/~1pets~1{id}/get" || !strings.Contains(d.Message, `"id" must be required`) {
+		t.Errorf("unexpected validation diagnostic %+v", d)
+	}
+	if got := d.String(); !strings.HasPrefix(got, "warning: "+spec+"#/paths/~1pets~1{id}/get: ") {
+		t.Errorf("unexpected text %s", got)
+	}
+}
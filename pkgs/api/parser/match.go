@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// FindOperation returns the operation in doc that serves method and a concrete
+// request path, along with the matching path template. Literal segments win
+// over templated ones when several paths match.
+func FindOperation(doc *openapi3.T, method, path string) (*openapi3.Operation, string) {
+	if doc == nil || doc.Paths == nil {
+		return nil, ""
+	}
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	segments := splitPath(path)
+
+	var bestOp *openapi3.Operation
+	bestTemplate := ""
+	bestScore := -1
+	for template, item := range doc.Paths.Map() {
+		op := item.GetOperation(strings.ToUpper(method))
+		if op == nil {
+			continue
+		}
+		score, ok := matchTemplate(splitPath(template), segments)
+		if !ok {
+			continue
+		}
+		if score > bestScore || (score == bestScore && template < bestTemplate) {
+			bestOp, bestTemplate, bestScore = op, template, score
+		}
+	}
+	return bestOp, bestTemplate
+}
+
+// ResponseSchema returns the JSON schema documented for status on op, falling
+// back to the default response
+func ResponseSchema(op *openapi3.Operation, status int) *openapi3.Schema {
+	if op.Responses == nil {
+		return nil
+	}
+	ref := op.Responses.Status(status)
+	if ref == nil {
+		ref = op.Responses.Default()
+	}
+	if ref == nil || ref.Value == nil {
+		return nil
+	}
+	for mediaType, media := range ref.Value.Content {
+		if strings.Contains(mediaType, "json") && media.Schema != nil {
+			return media.Schema.Value
+		}
+	}
+	return nil
+}
+
+// matchTemplate reports whether segments fit the template and how many
+// segments matched literally
+func matchTemplate(template, segments []string) (int, bool) {
+	if len(template) != len(segments) {
+		return 0, false
+	}
+	literal := 0
+	for i, t := range template {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			continue
+		}
+		if t != segments[i] {
+			return 0, false
+		}
+		literal++
+	}
+	return literal, true
+}
+
+func splitPath(p string) []string {
+	return strings.Split(strings.Trim(p, "/"), "/")
+}
+
+// PathParams extracts the values of the {placeholders} in template from a
+// concrete request path
+func PathParams(template, path string) map[string]string {
+	params := map[string]string{}
+	tSegs, pSegs := splitPath(template), splitPath(path)
+	if len(tSegs) != len(pSegs) {
+		return params
+	}
+	for i, t := range tSegs {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			if v, err := url.PathUnescape(pSegs[i]); err == nil {
+				params[t[1:len(t)-1]] = v
+			} else {
+				params[t[1:len(t)-1]] = pSegs[i]
+			}
+		}
+	}
+	return params
+}
@@ -0,0 +1,267 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oasdiff/yaml"
+)
+
+// webhooksExtension holds the webhooks of an OpenAPI 3.1 document, which
+// openapi3.T has no field for
+const webhooksExtension = "x-webhooks"
+
+// webhookPrefix marks webhooks moved into the paths while loading, so the
+// loader resolves their references
+const webhookPrefix = "/~webhooks~/"
+
+// unsupportedKeywords are JSON Schema 2020-12 keywords without an OpenAPI 3.0
+// equivalent; they are dropped rather than failing validation
+var unsupportedKeywords = map[string]bool{
+	"$id": true, "$schema": true, "$anchor": true, "$dynamicAnchor": true,
+	"$dynamicRef": true, "$defs": true, "$comment": true, "$vocabulary": true,
+	"prefixItems": true, "contains": true, "minContains": true, "maxContains": true,
+	"patternProperties": true, "propertyNames": true, "dependentRequired": true,
+	"dependentSchemas": true, "unevaluatedItems": true, "unevaluatedProperties": true,
+	"if": true, "then": true, "else": true,
+	"contentMediaType": true, "contentEncoding": true, "contentSchema": true,
+}
+
+// exclusiveBounds maps the numeric exclusive bounds of 2020-12 to the bound
+// they mark exclusive in OpenAPI 3.0
+var exclusiveBounds = map[string]string{
+	"exclusiveMinimum": "minimum",
+	"exclusiveMaximum": "maximum",
+}
+
+// isOpenAPI31 reports whether data is an OpenAPI 3.1 document
+func isOpenAPI31(data []byte) bool {
+	var head struct {
+		OpenAPI string `json:"openapi"`
+	}
+	return yaml.Unmarshal(data, &head) == nil && strings.HasPrefix(head.OpenAPI, "3.1")
+}
+
+// loadOpenAPI31 loads an OpenAPI 3.1 document by rewriting it into the 3.0
+// dialect the loader understands. Schema type arrays with null become
+// nullable, const becomes a one-value enum, $ref siblings are kept through
+// allOf, and webhooks are loaded like paths and then moved to the
+// x-webhooks extension (see Webhooks).
+func loadOpenAPI31(loader *openapi3.Loader, data []byte, location *url.URL) (*openapi3.T, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %v", err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(jsonData, &raw); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %v", err)
+	}
+	downgrade(raw)
+
+	paths, _ := raw["paths"].(map[string]interface{})
+	if paths == nil {
+		paths = map[string]interface{}{}
+		raw["paths"] = paths
+	}
+	if hooks, ok := raw["webhooks"].(map[string]interface{}); ok {
+		for name, item := range hooks {
+			paths[webhookPrefix+name] = item
+		}
+	}
+	delete(raw, "webhooks")
+	delete(raw, "jsonSchemaDialect")
+	if components, ok := raw["components"].(map[string]interface{}); ok {
+		delete(components, "pathItems")
+	}
+
+	out, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := loader.LoadFromDataWithPath(out, location)
+	if err != nil {
+		return nil, err
+	}
+	webhooks := map[string]*openapi3.PathItem{}
+	for path, item := range doc.Paths.Map() {
+		if name, ok := strings.CutPrefix(path, webhookPrefix); ok {
+			webhooks[name] = item
+			doc.Paths.Delete(path)
+		}
+	}
+	if len(webhooks) > 0 {
+		if doc.Extensions == nil {
+			doc.Extensions = map[string]interface{}{}
+		}
+		doc.Extensions[webhooksExtension] = webhooks
+	}
+	return doc, nil
+}
+
+// Webhooks returns the webhooks of an OpenAPI 3.1 document by name
+func Webhooks(doc *openapi3.T) map[string]*openapi3.PathItem {
+	hooks, _ := doc.Extensions[webhooksExtension].(map[string]*openapi3.PathItem)
+	return hooks
+}
+
+// webhookNames returns the webhook names of doc in order
+func webhookNames(doc *openapi3.T) []string {
+	hooks := Webhooks(doc)
+	names := make([]string, 0, len(hooks))
+	for name := range hooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// downgrade rewrites the 3.1-only parts of a raw document in place
+func downgrade(raw map[string]interface{}) {
+	if info, ok := raw["info"].(map[string]interface{}); ok {
+		if summary, ok := info["summary"]; ok {
+			if _, ok := info["description"]; !ok {
+				info["description"] = summary
+			}
+			delete(info, "summary")
+		}
+		if license, ok := info["license"].(map[string]interface{}); ok {
+			delete(license, "identifier")
+		}
+	}
+	walk(raw)
+}
+
+// walk finds the schemas of a document, outside of schemas, and drops the
+// summary and description siblings 3.1 allows on references
+func walk(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			switch k {
+			case "schema":
+				v[k] = schema31(child)
+			case "schemas":
+				if schemas, ok := child.(map[string]interface{}); ok {
+					for name, s := range schemas {
+						schemas[name] = schema31(s)
+					}
+				}
+			default:
+				walk(child)
+			}
+		}
+		if _, ok := v["$ref"]; ok {
+			for k := range v {
+				if k != "$ref" {
+					delete(v, k)
+				}
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			walk(child)
+		}
+	}
+}
+
+// schema31 converts a JSON Schema 2020-12 schema to an OpenAPI 3.0 one
+func schema31(v interface{}) interface{} {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return map[string]interface{}{}
+		}
+		return map[string]interface{}{"not": map[string]interface{}{}}
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			switch {
+			case unsupportedKeywords[k]:
+			case k == "const":
+				out["enum"] = []interface{}{value}
+			case k == "examples":
+				if list, ok := value.([]interface{}); ok && len(list) > 0 {
+					if _, ok := v["example"]; !ok {
+						out["example"] = list[0]
+					}
+				}
+			case k == "type":
+				setType(out, value)
+			case k == "exclusiveMinimum" || k == "exclusiveMaximum":
+				if n, ok := value.(float64); ok {
+					out[exclusiveBounds[k]] = n
+					out[k] = true
+				} else {
+					out[k] = value
+				}
+			case k == "properties":
+				if props, ok := value.(map[string]interface{}); ok {
+					converted := make(map[string]interface{}, len(props))
+					for name, s := range props {
+						converted[name] = schema31(s)
+					}
+					value = converted
+				}
+				out[k] = value
+			case k == "additionalProperties":
+				if _, ok := value.(bool); !ok {
+					value = schema31(value)
+				}
+				out[k] = value
+			case k == "items" || k == "not":
+				out[k] = schema31(value)
+			case k == "allOf" || k == "oneOf" || k == "anyOf":
+				if list, ok := value.([]interface{}); ok {
+					converted := make([]interface{}, len(list))
+					for i, s := range list {
+						converted[i] = schema31(s)
+					}
+					value = converted
+				}
+				out[k] = value
+			default:
+				out[k] = value
+			}
+		}
+		if ref, ok := out["$ref"]; ok && len(out) > 1 {
+			delete(out, "$ref")
+			allOf, _ := out["allOf"].([]interface{})
+			out["allOf"] = append([]interface{}{map[string]interface{}{"$ref": ref}}, allOf...)
+		}
+		return out
+	}
+	return v
+}
+
+// setType sets the type of a converted schema, turning a null member of a
+// type array into nullable
+func setType(out map[string]interface{}, value interface{}) {
+	list, ok := value.([]interface{})
+	if !ok {
+		if value == "null" {
+			out["nullable"] = true
+			return
+		}
+		out["type"] = value
+		return
+	}
+	var types []interface{}
+	for _, t := range list {
+		if t == "null" {
+			out["nullable"] = true
+		} else {
+			types = append(types, t)
+		}
+	}
+	switch len(types) {
+	case 0:
+	case 1:
+		out["type"] = types[0]
+	default:
+		out["type"] = types
+	}
+}
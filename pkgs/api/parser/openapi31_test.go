@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"org.subh/api-term/pkgs/api/example"
+)
+
+const petstore31 = `
+openapi: 3.1.0
+jsonSchemaDialect: https://json-schema.org/draft/2020-12/schema
+info:
+  title: Pets
+  summary: Pets and their adoptions
+  version: 1.0.0
+  license:
+    name: MIT
+    identifier: MIT
+paths:
+  /pets/{id}:
+    get:
+      parameters:
+        - $ref: '#/components/parameters/PetId'
+          description: The pet to fetch
+      responses:
+        '200':
+          description: a pet
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+webhooks:
+  petAdopted:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Adoption'
+      responses:
+        '200':
+          description: received
+components:
+  parameters:
+    PetId:
+      name: id
+      in: path
+      required: true
+      schema:
+        type: string
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+          examples: [Rex]
+        tag:
+          type: [string, 'null']
+        kind:
+          const: dog
+        age:
+          type: integer
+          exclusiveMinimum: 0
+    Adoption:
+      type: object
+      properties:
+        pet:
+          $ref: '#/components/schemas/Pet'
+          description: The adopted pet
+        at:
+          type: string
+          format: date-time
+`
+
+func TestLoadOpenAPI31(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openapi.yaml")
+	if err := os.WriteFile(path, []byte(petstore31), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	doc, _, err := LoadDocument(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Errorf("validation failed: %v", err)
+	}
+
+	pet := doc.Components.Schemas["Pet"].Value
+	if tag := pet.Properties["tag"].Value; !tag.Nullable || !tag.Type.Is("string") {
+		t.Errorf("type array not converted to nullable: %+v", tag)
+	}
+	if kind := pet.Properties["kind"].Value; len(kind.Enum) != 1 || kind.Enum[0] != "dog" {
+		t.Errorf("const not converted to enum: %+v", kind.Enum)
+	}
+	if age := pet.Properties["age"].Value; age.Min == nil || *age.Min != 0 || !age.ExclusiveMin {
+		t.Errorf("exclusiveMinimum not converted: %+v", age)
+	}
+	adopted := doc.Components.Schemas["Adoption"].Value.Properties["pet"].Value
+	if adopted.Description != "The adopted pet" || len(adopted.AllOf) != 1 || adopted.AllOf[0].Value != pet {
+		t.Errorf("$ref siblings not kept: %+v", adopted)
+	}
+
+	generated, ok := example.FromSchema(pet).(map[string]interface{})
+	if !ok || generated["name"] != "Rex" || generated["kind"] != "dog" {
+		t.Errorf("unexpected example %v", generated)
+	}
+
+	if doc.Paths.Len() != 1 {
+		t.Errorf("webhooks should not be paths: %v", doc.Paths.InMatchingOrder())
+	}
+	hook := Webhooks(doc)["petAdopted"]
+	if hook == nil || hook.Post == nil || hook.Post.RequestBody.Value.Content.Get("application/json").Schema.Value.Properties["at"] == nil {
+		t.Fatalf("webhook not loaded: %+v", hook)
+	}
+
+	endpoints := Endpoints(doc)
+	if len(endpoints) != 2 {
+		t.Fatalf("expected 2 endpoints, got %d", len(endpoints))
+	}
+	if ep := endpoints[0]; ep.Path != "/pets/{id}" || ep.Webhook != "" || len(ep.Parameters) != 1 {
+		t.Errorf("unexpected endpoint %+v", ep)
+	}
+	if ep := endpoints[1]; ep.Webhook != "petAdopted" || ep.Method != "POST" || ep.Path != "" {
+		t.Errorf("webhooks should follow the paths: %+v", ep)
+	}
+}
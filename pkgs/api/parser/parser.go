@@ -1,64 +1,55 @@
 package parser
 
 import (
-	"log"
+	"context"
+	"fmt"
 	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
+	"org.subh/api-term/pkgs/api/asyncapi"
 	"org.subh/api-term/pkgs/api/model"
 
 	"github.com/getkin/kin-openapi/openapi3"
 )
 
 // ParseOpenAPI loads OpenAPI specs from multiple files and URLs
-func ParseOpenAPI(filePaths []string, urls []string) []*model.Endpoint {
-	loader := openapi3.NewLoader()
+func ParseOpenAPI(filePaths []string, urls []string) ([]*model.Endpoint, []Diagnostic) {
 	var endpoints []*model.Endpoint
+	docs, diags := LoadDocuments(filePaths, urls)
+	for _, doc := range docs {
+		endpoints = append(endpoints, Endpoints(doc)...)
+	}
+	return endpoints, diags
+}
 
-	// Helper to process a doc
-	processDoc := func(doc *openapi3.T) {
-		if err := doc.Validate(loader.Context); err != nil {
-			log.Printf("Validation warning: %v", err)
-		}
-
-		for path, pathItem := range doc.Paths.Map() {
-			for method, op := range map[string]*openapi3.Operation{
-				"GET":    pathItem.Get,
-				"POST":   pathItem.Post,
-				"DELETE": pathItem.Delete,
-				"PUT":    pathItem.Put,
-				"PATCH":  pathItem.Patch,
-			} {
-				if op != nil {
-					var params []*model.Parameter
-					for _, paramRef := range op.Parameters {
-						if paramRef.Value != nil {
-							params = append(params, &model.Parameter{
-								Name:     paramRef.Value.Name,
-								In:       paramRef.Value.In,
-								Required: paramRef.Value.Required,
-							})
-						}
-					}
-					endpoints = append(endpoints, &model.Endpoint{
-						Method:     method,
-						Path:       path,
-						Parameters: params,
-					})
-				}
-			}
+// LoadDocuments loads and validates OpenAPI documents from multiple files and URLs,
+// skipping the ones that fail to load. Swagger 2.0 and AsyncAPI documents
+// are converted to OpenAPI 3. Documents that fail to load are reported as
+// errors, and validation problems and conversion losses as warnings.
+func LoadDocuments(filePaths []string, urls []string) ([]*openapi3.T, []Diagnostic) {
+	loader := openapi3.NewLoader()
+	var docs []*openapi3.T
+	var sources []string
+	var diags []Diagnostic
+	add := func(source string, doc *openapi3.T, warnings []Diagnostic, err error) {
+		diags = append(diags, warnings...)
+		if err != nil {
+			diags = append(diags, Diagnostic{Source: source, Severity: SeverityError, Message: err.Error()})
+			return
 		}
+		docs = append(docs, doc)
+		sources = append(sources, source)
 	}
 
 	for _, filePath := range filePaths {
 		if filePath == "" {
 			continue
 		}
-		doc, err := loader.LoadFromFile(filePath)
-		if err != nil {
-			log.Printf("Failed to load file %s: %v", filePath, err)
-			continue
-		}
-		processDoc(doc)
+		doc, warnings, err := loadFile(loader, filePath)
+		add(filePath, doc, warnings, err)
 	}
 
 	for _, u := range urls {
@@ -67,16 +58,171 @@ func ParseOpenAPI(filePaths []string, urls []string) []*model.Endpoint {
 		}
 		parsedURL, err := url.Parse(u)
 		if err != nil {
-			log.Printf("Invalid URL %s: %v", u, err)
+			add(u, nil, nil, fmt.Errorf("invalid URL: %v", err))
 			continue
 		}
-		doc, err := loader.LoadFromURI(parsedURL)
-		if err != nil {
-			log.Printf("Failed to load URL %s: %v", u, err)
-			continue
+		doc, warnings, err := loadURL(loader, parsedURL)
+		add(u, doc, warnings, err)
+	}
+
+	for i, doc := range docs {
+		if err := doc.Validate(context.Background()); err != nil {
+			diags = append(diags, validationDiagnostic(sources[i], err))
 		}
-		processDoc(doc)
 	}
+	return docs, diags
+}
 
+// Endpoints extracts the endpoint list from a loaded document. Webhooks of
+// OpenAPI 3.1 documents follow the paths, sorted by name.
+func Endpoints(doc *openapi3.T) []*model.Endpoint {
+	var endpoints []*model.Endpoint
+	for path, pathItem := range doc.Paths.Map() {
+		for method, op := range operations(pathItem) {
+			endpoints = append(endpoints, &model.Endpoint{
+				Method:     method,
+				Path:       path,
+				Parameters: parameters(op),
+				WebSocket:  method == "GET" && isWebSocket(path, op),
+			})
+		}
+	}
+	hooks := Webhooks(doc)
+	for _, name := range webhookNames(doc) {
+		ops := operations(hooks[name])
+		methods := make([]string, 0, len(ops))
+		for method := range ops {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			endpoints = append(endpoints, &model.Endpoint{
+				Method:     method,
+				Parameters: parameters(ops[method]),
+				Webhook:    name,
+			})
+		}
+	}
 	return endpoints
 }
+
+// operations returns the operations of a path item by method
+func operations(pathItem *openapi3.PathItem) map[string]*openapi3.Operation {
+	ops := map[string]*openapi3.Operation{}
+	for method, op := range map[string]*openapi3.Operation{
+		"GET":    pathItem.Get,
+		"POST":   pathItem.Post,
+		"DELETE": pathItem.Delete,
+		"PUT":    pathItem.Put,
+		"PATCH":  pathItem.Patch,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+// parameters converts the parameters of an operation
+func parameters(op *openapi3.Operation) []*model.Parameter {
+	var params []*model.Parameter
+	for _, paramRef := range op.Parameters {
+		if paramRef.Value != nil {
+			params = append(params, &model.Parameter{
+				Name:     paramRef.Value.Name,
+				In:       paramRef.Value.In,
+				Required: paramRef.Value.Required,
+			})
+		}
+	}
+	return params
+}
+
+// isWebSocket reports whether an operation upgrades to a WebSocket: it
+// declares a 101 response, an Upgrade header or x-websocket, or its path
+// ends in /ws or /websocket
+func isWebSocket(path string, op *openapi3.Operation) bool {
+	if op.Responses != nil && op.Responses.Value("101") != nil {
+		return true
+	}
+	for _, paramRef := range op.Parameters {
+		if paramRef.Value != nil && paramRef.Value.In == "header" && strings.EqualFold(paramRef.Value.Name, "Upgrade") {
+			return true
+		}
+	}
+	if v, ok := op.Extensions["x-websocket"].(bool); ok {
+		return v
+	}
+	last := path[strings.LastIndex(path, "/")+1:]
+	return last == "ws" || last == "websocket"
+}
+
+// LoadDocument loads a single OpenAPI, Swagger 2.0 or AsyncAPI document from
+// a file path or an http(s) URL, with the warnings of its conversion
+func LoadDocument(source string) (*openapi3.T, []Diagnostic, error) {
+	loader := openapi3.NewLoader()
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		parsedURL, err := url.Parse(source)
+		if err != nil {
+			return nil, nil, err
+		}
+		return loadURL(loader, parsedURL)
+	}
+	return loadFile(loader, source)
+}
+
+// loadFile loads an OpenAPI 3.0 or 3.1 document, or converts a Swagger 2.0
+// or AsyncAPI one
+func loadFile(loader *openapi3.Loader, path string) (*openapi3.T, []Diagnostic, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if asyncapi.IsAsyncAPI(data) {
+		return convertAsyncAPI(path, data)
+	}
+	location := &url.URL{Path: filepath.ToSlash(path)}
+	var doc *openapi3.T
+	switch {
+	case isSwagger(data):
+		doc, err = convertSwagger(loader, data, location)
+	case isOpenAPI31(data):
+		doc, err = loadOpenAPI31(loader, data, location)
+	default:
+		doc, err = loader.LoadFromFile(path)
+	}
+	return doc, nil, err
+}
+
+// loadURL loads an OpenAPI 3.0 or 3.1 document, or converts a Swagger 2.0
+// or AsyncAPI one
+func loadURL(loader *openapi3.Loader, location *url.URL) (*openapi3.T, []Diagnostic, error) {
+	data, err := openapi3.DefaultReadFromURI(loader, location)
+	if err != nil {
+		return nil, nil, err
+	}
+	if asyncapi.IsAsyncAPI(data) {
+		return convertAsyncAPI(location.String(), data)
+	}
+	var doc *openapi3.T
+	switch {
+	case isSwagger(data):
+		doc, err = convertSwagger(loader, data, location)
+	case isOpenAPI31(data):
+		doc, err = loadOpenAPI31(loader, data, location)
+	default:
+		doc, err = loader.LoadFromDataWithPath(data, location)
+	}
+	return doc, nil, err
+}
+
+// convertAsyncAPI converts an AsyncAPI document, reporting the operations
+// it had to skip as warnings
+func convertAsyncAPI(source string, data []byte) (*openapi3.T, []Diagnostic, error) {
+	doc, warnings, err := asyncapi.Convert(data)
+	var diags []Diagnostic
+	for _, w := range warnings {
+		diags = append(diags, Diagnostic{Source: source, Severity: SeverityWarning, Message: w})
+	}
+	return doc, diags, err
+}
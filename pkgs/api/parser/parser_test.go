@@ -25,7 +25,10 @@ paths:
 	}))
 	defer ts.Close()
 
-	endpoints := ParseOpenAPI(nil, []string{ts.URL})
+	endpoints, diags := ParseOpenAPI(nil, []string{ts.URL})
+	if len(diags) != 0 {
+		t.Errorf("unexpected diagnostics %v", diags)
+	}
 
 	if len(endpoints) != 1 {
 		t.Fatalf("expected 1 endpoint, got %d", len(endpoints))
@@ -40,3 +43,78 @@ paths:
 		t.Errorf("expected method GET, got %s", ep.Method)
 	}
 }
+
+func TestParseOpenAPI_WebSocket(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `openapi: 3.0.0
+info:
+  title: Sample API
+  version: 0.1.9
+paths:
+  /ws:
+    get:
+      responses:
+        '200':
+          description: events
+  /jobs/{id}/updates:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        '101':
+          description: Switching Protocols
+  /users:
+    get:
+      responses:
+        '200':
+          description: users
+`)
+	}))
+	defer ts.Close()
+
+	endpoints, _ := ParseOpenAPI(nil, []string{ts.URL})
+	for _, ep := range endpoints {
+		want := ep.Path != "/users"
+		if ep.WebSocket != want {
+			t.Errorf("%s: expected WebSocket %v", ep.Path, want)
+		}
+	}
+}
+
+func TestParseAsyncAPI(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `asyncapi: 3.0.0
+info:
+  title: Jobs
+  version: 1.0.0
+servers:
+  local:
+    host: localhost:8080
+    protocol: ws
+channels:
+  jobStatus:
+    address: /jobs/{id}/ws
+    parameters:
+      id: {}
+operations:
+  watchJob:
+    action: send
+    channel:
+      $ref: '#/channels/jobStatus'
+`)
+	}))
+	defer ts.Close()
+
+	endpoints, _ := ParseOpenAPI(nil, []string{ts.URL})
+	if len(endpoints) != 1 {
+		t.Fatalf("expected 1 endpoint, got %d", len(endpoints))
+	}
+	ep := endpoints[0]
+	if ep.Method != "GET" || ep.Path != "/jobs/{id}/ws" || !ep.WebSocket || len(ep.Parameters) != 1 || !ep.Parameters[0].Required {
+		t.Errorf("unexpected endpoint %+v", ep)
+	}
+}
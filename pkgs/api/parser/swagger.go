@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/oasdiff/yaml"
+)
+
+// isSwagger reports whether data is a Swagger 2.0 document
+func isSwagger(data []byte) bool {
+	var head struct {
+		Swagger string `json:"swagger"`
+	}
+	return yaml.Unmarshal(data, &head) == nil && strings.HasPrefix(head.Swagger, "2.")
+}
+
+// convertSwagger converts a Swagger 2.0 document to OpenAPI 3. The basePath
+// is moved into the paths, since requests are sent to the configured base
+// URL, and document-wide produces apply to operations that don't set their
+// own. Consumes, formData parameters and securityDefinitions are converted by
+// openapi2conv.
+func convertSwagger(loader *openapi3.Loader, data []byte, location *url.URL) (*openapi3.T, error) {
+	var doc2 openapi2.T
+	if err := yaml.Unmarshal(data, &doc2); err != nil {
+		return nil, fmt.Errorf("invalid Swagger document: %v", err)
+	}
+	if base := strings.TrimRight(doc2.BasePath, "/"); base != "" {
+		paths := make(map[string]*openapi2.PathItem, len(doc2.Paths))
+		for path, item := range doc2.Paths {
+			paths[base+path] = item
+		}
+		doc2.Paths = paths
+		doc2.BasePath = ""
+	}
+	if len(doc2.Produces) > 0 {
+		for _, item := range doc2.Paths {
+			for _, op := range item.Operations() {
+				if len(op.Produces) == 0 {
+					op.Produces = doc2.Produces
+				}
+			}
+		}
+	}
+	return openapi2conv.ToV3WithLoader(&doc2, loader, location)
+}
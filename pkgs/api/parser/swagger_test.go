@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const petstore2 = `{
+  "swagger": "2.0",
+  "info": {"title": "Legacy Pets", "version": "1.0"},
+  "host": "pets.example.com",
+  "basePath": "/v1/",
+  "schemes": ["https"],
+  "produces": ["application/xml"],
+  "securityDefinitions": {
+    "api_key": {"type": "apiKey", "name": "X-API-Key", "in": "header"}
+  },
+  "security": [{"api_key": []}],
+  "paths": {
+    "/pets/{id}": {
+      "get": {
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "type": "string"},
+          {"name": "fields", "in": "query", "type": "string"}
+        ],
+        "responses": {"200": {"description": "a pet", "schema": {"$ref": "#/definitions/Pet"}}}
+      }
+    },
+    "/pets/{id}/photo": {
+      "post": {
+        "consumes": ["multipart/form-data"],
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "type": "string"},
+          {"name": "caption", "in": "formData", "type": "string"},
+          {"name": "file", "in": "formData", "type": "file", "required": true}
+        ],
+        "responses": {"201": {"description": "uploaded"}}
+      }
+    }
+  },
+  "definitions": {
+    "Pet": {"type": "object", "properties": {"name": {"type": "string"}}}
+  }
+}`
+
+func TestLoadSwagger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swagger.json")
+	if err := os.WriteFile(path, []byte(petstore2), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	doc, _, err := LoadDocument(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := doc.Paths.Value("/v1/pets/{id}")
+	if get == nil || get.Get == nil {
+		t.Fatalf("basePath not applied to the paths: %v", doc.Paths.InMatchingOrder())
+	}
+	if get.Get.Responses.Value("200").Value.Content.Get("application/xml") == nil {
+		t.Error("document-wide produces not applied")
+	}
+	photo := doc.Paths.Value("/v1/pets/{id}/photo").Post
+	form := photo.RequestBody.Value.Content.Get("multipart/form-data")
+	if form == nil || form.Schema.Value.Properties["caption"] == nil || form.Schema.Value.Properties["file"] == nil {
+		t.Errorf("formData parameters not converted to a form body: %+v", photo.RequestBody.Value)
+	}
+	if scheme := doc.Components.SecuritySchemes["api_key"]; scheme == nil || scheme.Value.Name != "X-API-Key" {
+		t.Error("securityDefinitions not converted")
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "https://pets.example.com/" {
+		t.Errorf("unexpected servers %+v", doc.Servers)
+	}
+
+	endpoints := Endpoints(doc)
+	if len(endpoints) != 2 {
+		t.Fatalf("expected 2 endpoints, got %d", len(endpoints))
+	}
+	for _, ep := range endpoints {
+		if ep.Path == "/v1/pets/{id}/photo" && len(ep.Parameters) != 1 {
+			t.Errorf("form fields should not be parameters: %+v", ep.Parameters)
+		}
+	}
+}
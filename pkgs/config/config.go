@@ -1,14 +1,67 @@
 package config
 
+import (
+	"os"
+	"path/filepath"
+)
+
 type Config struct {
 	BaseURL           string
 	OpenAPIFile       string
 	OpenAPIURLs       []string
 	GlobalQueryParams map[string]string
+	DiffAgainst       string
+
+	// DriftHistory is a cassette file that responses are appended to and
+	// read back from, so drift covers earlier sessions; empty keeps drift
+	// to the current session
+	DriftHistory string
+
+	// AI assistant selection; empty model and base URL use the provider defaults
+	AIProvider string
+	AIModel    string
+	AIBaseURL  string
+
+	// TranscriptDir is where AI conversations are saved
+	TranscriptDir string
+	// RedactionPolicy is a YAML file with the redaction applied to anything
+	// sent to an AI provider; empty uses the built-in policy
+	RedactionPolicy string
+
+	// CompareURL is the base URL suggested when comparing a response with
+	// another environment
+	CompareURL string
+	// DiffIgnore lists field name globs and $.paths left out of response diffs
+	DiffIgnore []string
+
+	// PaginationFile is a YAML file with pagination rules per operation;
+	// operations without a rule are detected from their responses
+	PaginationFile string
+
+	// GRPCTarget is a gRPC server whose methods are listed with the
+	// endpoints, discovered through reflection unless GRPCDescriptors names
+	// a descriptor set
+	GRPCTarget      string
+	GRPCDescriptors string
+
+	// GraphQLURL is a GraphQL endpoint whose operations are listed with the
+	// endpoints; a path without a host is relative to the base URL
+	GraphQLURL string
 }
 
 var DefaultBaseURL = "http://localhost:8080"
 var DefaultOpenAPIFile = "assets/api.yaml"
+var DefaultAIProvider = "gemini"
+
+// DefaultTranscriptDir returns ~/.api-term/transcripts, or a relative
+// directory when the home directory is unknown
+func DefaultTranscriptDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".api-term", "transcripts")
+	}
+	return filepath.Join(home, ".api-term", "transcripts")
+}
 
 func New(openAPIFile string, openAPIURLs []string, globalQueryParams map[string]string) *Config {
 	return &Config{
@@ -16,5 +69,7 @@ func New(openAPIFile string, openAPIURLs []string, globalQueryParams map[string]
 		OpenAPIFile:       openAPIFile,
 		OpenAPIURLs:       openAPIURLs,
 		GlobalQueryParams: globalQueryParams,
+		AIProvider:        DefaultAIProvider,
+		TranscriptDir:     DefaultTranscriptDir(),
 	}
 }
@@ -0,0 +1,276 @@
+package drift
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"org.subh/api-term/pkgs/api/parser"
+)
+
+// operationStats aggregates everything observed for one spec operation
+type operationStats struct {
+	operation string
+	op        *openapi3.Operation
+	count     int
+	statuses  map[int]int
+	headers   map[string]bool
+	// fields maps status code to the set of field paths seen in its bodies
+	fields map[int]map[string]bool
+}
+
+// Collector aggregates observed responses per spec operation
+type Collector struct {
+	Docs []*openapi3.T
+
+	mu        sync.Mutex
+	stats     map[string]*operationStats
+	unmatched map[string]int
+}
+
+// NewCollector creates a Collector that matches observations against docs
+func NewCollector(docs ...*openapi3.T) *Collector {
+	return &Collector{
+		Docs:      docs,
+		stats:     make(map[string]*operationStats),
+		unmatched: make(map[string]int),
+	}
+}
+
+// Observe records a single response. path may be concrete or a path template.
+func (c *Collector) Observe(method, path string, status int, header http.Header, body string) {
+	method = strings.ToUpper(method)
+	var op *openapi3.Operation
+	template := ""
+	for _, doc := range c.Docs {
+		if op, template = parser.FindOperation(doc, method, path); op != nil {
+			break
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if op == nil {
+		c.unmatched[method+" "+path]++
+		return
+	}
+
+	key := method + " " + template
+	st, ok := c.stats[key]
+	if !ok {
+		st = &operationStats{
+			operation: key,
+			op:        op,
+			statuses:  make(map[int]int),
+			headers:   make(map[string]bool),
+			fields:    make(map[int]map[string]bool),
+		}
+		c.stats[key] = st
+	}
+	st.count++
+	st.statuses[status]++
+	for name := range header {
+		st.headers[http.CanonicalHeaderKey(name)] = true
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(body), &decoded); err == nil {
+		if st.fields[status] == nil {
+			st.fields[status] = make(map[string]bool)
+		}
+		collectFields(decoded, "", st.fields[status])
+	}
+}
+
+// Report compares the observations with the documented responses
+func (c *Collector) Report() *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := &Report{}
+	keys := make([]string, 0, len(c.stats))
+	for k := range c.stats {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		st := c.stats[k]
+		or := &OperationReport{
+			Operation:   st.operation,
+			OperationID: st.op.OperationID,
+			Observed:    st.count,
+		}
+
+		statuses := make([]int, 0, len(st.statuses))
+		for status := range st.statuses {
+			statuses = append(statuses, status)
+		}
+		sort.Ints(statuses)
+
+		undocumented := map[string]bool{}
+		documentedAll, observedAll := map[string]bool{}, map[string]bool{}
+		for _, status := range statuses {
+			or.Statuses = append(or.Statuses, StatusCount{Status: status, Count: st.statuses[status]})
+			resp := documentedResponse(st.op, status)
+			if resp == nil {
+				or.UndocumentedStatuses = append(or.UndocumentedStatuses, status)
+				continue
+			}
+			schema := jsonSchema(resp)
+			if schema == nil || st.fields[status] == nil {
+				continue
+			}
+			documented, open := map[string]bool{}, map[string]bool{}
+			schemaFields(schema, "", documented, open, 0)
+			for field := range st.fields[status] {
+				observedAll[field] = true
+				if !documented[field] && !underOpen(field, open) {
+					undocumented[field] = true
+				}
+			}
+			for field := range documented {
+				documentedAll[field] = true
+			}
+		}
+		missing := map[string]bool{}
+		for field := range documentedAll {
+			if !observedAll[field] {
+				missing[field] = true
+			}
+		}
+		or.UndocumentedFields = sortedSet(undocumented)
+		or.MissingFields = sortedSet(missing)
+
+		var missingHeaders []string
+		for _, status := range statuses {
+			if resp := documentedResponse(st.op, status); resp != nil {
+				for name := range resp.Headers {
+					if !st.headers[http.CanonicalHeaderKey(name)] {
+						missingHeaders = append(missingHeaders, name)
+					}
+				}
+			}
+		}
+		or.MissingHeaders = sortedSet(toSet(missingHeaders))
+
+		report.Operations = append(report.Operations, or)
+	}
+
+	for k, n := range c.unmatched {
+		report.Unmatched = append(report.Unmatched, StatusCount{Request: k, Count: n})
+	}
+	sort.Slice(report.Unmatched, func(i, j int) bool { return report.Unmatched[i].Request < report.Unmatched[j].Request })
+	return report
+}
+
+// documentedResponse returns the response documented for status, accepting
+// range keys like "4XX" and falling back to the default response
+func documentedResponse(op *openapi3.Operation, status int) *openapi3.Response {
+	if op.Responses == nil {
+		return nil
+	}
+	if ref := op.Responses.Value(strconv.Itoa(status)); ref != nil {
+		return ref.Value
+	}
+	if ref := op.Responses.Value(strconv.Itoa(status/100) + "XX"); ref != nil {
+		return ref.Value
+	}
+	if ref := op.Responses.Default(); ref != nil {
+		return ref.Value
+	}
+	return nil
+}
+
+func jsonSchema(resp *openapi3.Response) *openapi3.Schema {
+	for mediaType, media := range resp.Content {
+		if strings.Contains(mediaType, "json") && media.Schema != nil {
+			return media.Schema.Value
+		}
+	}
+	return nil
+}
+
+// collectFields records the path of every object field in v, using
+// "a.b" for nested objects and "a[].b" for array items
+func collectFields(v interface{}, prefix string, out map[string]bool) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			p := joinField(prefix, k)
+			out[p] = true
+			collectFields(child, p, out)
+		}
+	case []interface{}:
+		for _, child := range t {
+			collectFields(child, prefix+"[]", out)
+		}
+	}
+}
+
+// schemaFields records documented field paths; open collects prefixes whose
+// objects accept arbitrary properties
+func schemaFields(s *openapi3.Schema, prefix string, out, open map[string]bool, depth int) {
+	if s == nil || depth > 10 {
+		return
+	}
+	for _, group := range []openapi3.SchemaRefs{s.AllOf, s.OneOf, s.AnyOf} {
+		for _, ref := range group {
+			if ref != nil {
+				schemaFields(ref.Value, prefix, out, open, depth+1)
+			}
+		}
+	}
+	if s.Items != nil {
+		schemaFields(s.Items.Value, prefix+"[]", out, open, depth+1)
+	}
+	for name, ref := range s.Properties {
+		p := joinField(prefix, name)
+		out[p] = true
+		if ref != nil {
+			schemaFields(ref.Value, p, out, open, depth+1)
+		}
+	}
+	if s.AdditionalProperties.Schema != nil || (s.AdditionalProperties.Has != nil && *s.AdditionalProperties.Has) ||
+		(len(s.Properties) == 0 && s.Items == nil && len(s.AllOf)+len(s.OneOf)+len(s.AnyOf) == 0 && (s.Type == nil || s.Type.Includes(openapi3.TypeObject))) {
+		open[prefix] = true
+	}
+}
+
+func underOpen(field string, open map[string]bool) bool {
+	for prefix := range open {
+		if prefix == "" || field == prefix || strings.HasPrefix(field, prefix+".") || strings.HasPrefix(field, prefix+"[]") {
+			return true
+		}
+	}
+	return false
+}
+
+func joinField(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+func sortedSet(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,92 @@
+package drift
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const testSpec = `openapi: 3.0.0
+info:
+  title: Sample API
+  version: 0.1.9
+paths:
+  /models/{model_id}:
+    get:
+      operationId: getModel
+      responses:
+        '200':
+          description: A model
+          headers:
+            X-Request-Id:
+              schema:
+                type: string
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  id:
+                    type: string
+                  name:
+                    type: string
+                  tags:
+                    type: array
+                    items:
+                      type: object
+                      properties:
+                        key:
+                          type: string
+                  labels:
+                    type: object
+                    additionalProperties:
+                      type: string
+`
+
+func TestCollector_Report(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(testSpec))
+	if err != nil {
+		t.Fatalf("load spec: %v", err)
+	}
+	c := NewCollector(doc)
+
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	c.Observe("GET", "/models/m1", 200, header, `{"id":"m1","tags":[{"key":"a","value":"b"}],"labels":{"team":"x"},"owner":"bob"}`)
+	c.Observe("GET", "/models/m2", 200, header, `{"id":"m2"}`)
+	c.Observe("GET", "/models/m3", 404, header, `{"error":"not found"}`)
+	c.Observe("GET", "/unknown", 200, header, `{}`)
+
+	report := c.Report()
+	if len(report.Operations) != 1 {
+		t.Fatalf("expected 1 operation, got %d", len(report.Operations))
+	}
+	op := report.Operations[0]
+	if op.Operation != "GET /models/{model_id}" || op.Observed != 3 {
+		t.Errorf("unexpected operation summary: %+v", op)
+	}
+	if !reflect.DeepEqual(op.UndocumentedStatuses, []int{404}) {
+		t.Errorf("expected undocumented 404, got %v", op.UndocumentedStatuses)
+	}
+	if !reflect.DeepEqual(op.UndocumentedFields, []string{"owner", "tags[].value"}) {
+		t.Errorf("unexpected undocumented fields: %v", op.UndocumentedFields)
+	}
+	if !reflect.DeepEqual(op.MissingFields, []string{"name"}) {
+		t.Errorf("unexpected missing fields: %v", op.MissingFields)
+	}
+	if !reflect.DeepEqual(op.MissingHeaders, []string{"X-Request-Id"}) {
+		t.Errorf("unexpected missing headers: %v", op.MissingHeaders)
+	}
+	if len(report.Unmatched) != 1 || report.Unmatched[0].Request != "GET /unknown" {
+		t.Errorf("expected unmatched GET /unknown, got %v", report.Unmatched)
+	}
+
+	md := strings.Join(report.MarkdownLines(), "\n")
+	for _, want := range []string{"Undocumented status code: `404`", "Undocumented field: `owner`", "Documented field never observed: `name`"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown report missing %q:\n%s", want, md)
+		}
+	}
+}
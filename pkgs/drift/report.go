@@ -0,0 +1,108 @@
+package drift
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StatusCount is how often a status code (or unmatched request) was observed
+type StatusCount struct {
+	Status  int    `json:"status,omitempty"`
+	Request string `json:"request,omitempty"`
+	Count   int    `json:"count"`
+}
+
+// OperationReport lists the differences between one operation's spec and its traffic
+type OperationReport struct {
+	Operation            string        `json:"operation"`
+	OperationID          string        `json:"operationId,omitempty"`
+	Observed             int           `json:"observed"`
+	Statuses             []StatusCount `json:"statuses"`
+	UndocumentedStatuses []int         `json:"undocumentedStatuses,omitempty"`
+	UndocumentedFields   []string      `json:"undocumentedFields,omitempty"`
+	MissingFields        []string      `json:"missingFields,omitempty"`
+	MissingHeaders       []string      `json:"missingHeaders,omitempty"`
+}
+
+// Drifted reports whether any difference was found for the operation
+func (o *OperationReport) Drifted() bool {
+	return len(o.UndocumentedStatuses)+len(o.UndocumentedFields)+len(o.MissingFields)+len(o.MissingHeaders) > 0
+}
+
+// Report is the drift found across all observed operations
+type Report struct {
+	Operations []*OperationReport `json:"operations"`
+	// Unmatched lists requests that no spec operation serves
+	Unmatched []StatusCount `json:"unmatched,omitempty"`
+}
+
+// WriteJSON renders the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteMarkdown renders the report as a Markdown document
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	_, err := io.WriteString(w, strings.Join(r.MarkdownLines(), "\n")+"\n")
+	return err
+}
+
+// MarkdownLines renders the report as Markdown, one line per element
+func (r *Report) MarkdownLines() []string {
+	lines := []string{"# Spec drift report", ""}
+	if len(r.Operations) == 0 && len(r.Unmatched) == 0 {
+		return append(lines, "No responses observed yet.")
+	}
+
+	drifted := 0
+	for _, op := range r.Operations {
+		if op.Drifted() {
+			drifted++
+		}
+	}
+	lines = append(lines, fmt.Sprintf("%d of %d observed operations drifted from the spec.", drifted, len(r.Operations)), "")
+
+	for _, op := range r.Operations {
+		title := "## " + op.Operation
+		if op.OperationID != "" {
+			title += " (" + op.OperationID + ")"
+		}
+		lines = append(lines, title, "")
+
+		var statuses []string
+		for _, s := range op.Statuses {
+			statuses = append(statuses, fmt.Sprintf("%d x%d", s.Status, s.Count))
+		}
+		lines = append(lines, fmt.Sprintf("- Observed %d responses: %s", op.Observed, strings.Join(statuses, ", ")))
+		if !op.Drifted() {
+			lines = append(lines, "- No drift detected", "")
+			continue
+		}
+		for _, s := range op.UndocumentedStatuses {
+			lines = append(lines, fmt.Sprintf("- Undocumented status code: `%d`", s))
+		}
+		for _, f := range op.UndocumentedFields {
+			lines = append(lines, fmt.Sprintf("- Undocumented field: `%s`", f))
+		}
+		for _, f := range op.MissingFields {
+			lines = append(lines, fmt.Sprintf("- Documented field never observed: `%s`", f))
+		}
+		for _, h := range op.MissingHeaders {
+			lines = append(lines, fmt.Sprintf("- Documented header never observed: `%s`", h))
+		}
+		lines = append(lines, "")
+	}
+
+	if len(r.Unmatched) > 0 {
+		lines = append(lines, "## Requests not in the spec", "")
+		for _, u := range r.Unmatched {
+			lines = append(lines, fmt.Sprintf("- `%s` x%d", u.Request, u.Count))
+		}
+		lines = append(lines, "")
+	}
+	return lines
+}
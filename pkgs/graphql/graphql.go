@@ -0,0 +1,325 @@
+// Package graphql reads a GraphQL schema from an introspection result and
+// generates query skeletons and variables for its root fields
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// IntrospectionQuery asks a server for its schema
+const IntrospectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types {
+      kind
+      name
+      description
+      fields(includeDeprecated: true) {
+        name
+        description
+        args { ...InputValue }
+        type { ...TypeRef }
+      }
+      inputFields { ...InputValue }
+      enumValues(includeDeprecated: true) { name }
+      possibleTypes { kind name }
+    }
+  }
+}
+
+fragment InputValue on __InputValue {
+  name
+  description
+  type { ...TypeRef }
+  defaultValue
+}
+
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } }
+}`
+
+// maxDepth is how deep skeletons select fields of nested objects
+const maxDepth = 2
+
+// TypeRef refers to a type, wrapped in NON_NULL and LIST kinds
+type TypeRef struct {
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	OfType *TypeRef `json:"ofType"`
+}
+
+// String writes the type the way GraphQL does, e.g. [ID!]!
+func (t *TypeRef) String() string {
+	switch t.Kind {
+	case "NON_NULL":
+		return t.OfType.String() + "!"
+	case "LIST":
+		return "[" + t.OfType.String() + "]"
+	}
+	return t.Name
+}
+
+// Named returns the name of the type under the wrappers
+func (t *TypeRef) Named() string {
+	for t.OfType != nil {
+		t = t.OfType
+	}
+	return t.Name
+}
+
+// Required reports whether the type is non-null
+func (t *TypeRef) Required() bool {
+	return t.Kind == "NON_NULL"
+}
+
+// InputValue is an argument or an input object field
+type InputValue struct {
+	Name         string  `json:"name"`
+	Description  string  `json:"description"`
+	Type         TypeRef `json:"type"`
+	DefaultValue *string `json:"defaultValue"`
+}
+
+// Field is a field of an object or interface
+type Field struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Args        []InputValue `json:"args"`
+	Type        TypeRef      `json:"type"`
+}
+
+// Type is a named type of the schema
+type Type struct {
+	Kind          string                  `json:"kind"`
+	Name          string                  `json:"name"`
+	Description   string                  `json:"description"`
+	Fields        []Field                 `json:"fields"`
+	InputFields   []InputValue            `json:"inputFields"`
+	EnumValues    []struct{ Name string } `json:"enumValues"`
+	PossibleTypes []TypeRef               `json:"possibleTypes"`
+}
+
+type namedType struct {
+	Name string `json:"name"`
+}
+
+// Schema is the result of an introspection query
+type Schema struct {
+	QueryType        *namedType `json:"queryType"`
+	MutationType     *namedType `json:"mutationType"`
+	SubscriptionType *namedType `json:"subscriptionType"`
+	Types            []Type     `json:"types"`
+
+	byName map[string]*Type
+}
+
+// Operation is a root field: a query, mutation or subscription
+type Operation struct {
+	// Type is "query", "mutation" or "subscription"
+	Type  string
+	Field Field
+}
+
+// Parse reads the schema from the response to IntrospectionQuery
+func Parse(body []byte) (*Schema, error) {
+	var resp struct {
+		Data *struct {
+			Schema *Schema `json:"__schema"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid introspection response: %v", err)
+	}
+	if len(resp.Errors) > 0 {
+		var messages []string
+		for _, e := range resp.Errors {
+			messages = append(messages, e.Message)
+		}
+		return nil, errors.New(strings.Join(messages, "; "))
+	}
+	if resp.Data == nil || resp.Data.Schema == nil {
+		return nil, errors.New("introspection response has no schema")
+	}
+	s := resp.Data.Schema
+	s.byName = map[string]*Type{}
+	for i := range s.Types {
+		s.byName[s.Types[i].Name] = &s.Types[i]
+	}
+	return s, nil
+}
+
+// Type returns the named type, or nil
+func (s *Schema) Type(name string) *Type {
+	return s.byName[name]
+}
+
+// Operations lists the root fields: queries, then mutations, then
+// subscriptions, each in schema order. Fields without a name, which only a
+// malformed introspection result has, are left out.
+func (s *Schema) Operations() []Operation {
+	var ops []Operation
+	for _, root := range []struct {
+		kind string
+		t    *namedType
+	}{{"query", s.QueryType}, {"mutation", s.MutationType}, {"subscription", s.SubscriptionType}} {
+		if root.t == nil || s.Type(root.t.Name) == nil {
+			continue
+		}
+		for _, f := range s.Type(root.t.Name).Fields {
+			if f.Name != "" {
+				ops = append(ops, Operation{Type: root.kind, Field: f})
+			}
+		}
+	}
+	return ops
+}
+
+// Query generates a one-line operation for op that declares every argument
+// as a variable and selects the fields of the result up to maxDepth levels
+// of nested objects, e.g.
+//
+//	query User($id: ID!) { user(id: $id) { id name } }
+func (s *Schema) Query(op Operation) string {
+	var b strings.Builder
+	b.WriteString(op.Type)
+	if name := op.Field.Name; name != "" {
+		b.WriteString(" " + strings.ToUpper(name[:1]) + name[1:])
+	}
+	if len(op.Field.Args) > 0 {
+		var vars, args []string
+		for _, a := range op.Field.Args {
+			vars = append(vars, "$"+a.Name+": "+a.Type.String())
+			args = append(args, a.Name+": $"+a.Name)
+		}
+		b.WriteString("(" + strings.Join(vars, ", ") + ") { " + op.Field.Name + "(" + strings.Join(args, ", ") + ")")
+	} else {
+		b.WriteString(" { " + op.Field.Name)
+	}
+	if sel := s.selection(&op.Field.Type, 0); sel != "" {
+		b.WriteString(" " + sel)
+	}
+	b.WriteString(" }")
+	return b.String()
+}
+
+// selection selects the fields of t, or returns "" for scalars and enums
+func (s *Schema) selection(t *TypeRef, depth int) string {
+	named := s.Type(t.Named())
+	if named == nil {
+		return ""
+	}
+	switch named.Kind {
+	case "OBJECT", "INTERFACE":
+		var fields []string
+		for _, f := range named.Fields {
+			if f.Name == "" || requiresArgs(f) {
+				continue
+			}
+			if !s.isObject(&f.Type) {
+				fields = append(fields, f.Name)
+			} else if depth < maxDepth {
+				if sel := s.selection(&f.Type, depth+1); sel != "" {
+					fields = append(fields, f.Name+" "+sel)
+				}
+			}
+		}
+		if len(fields) == 0 {
+			fields = []string{"__typename"}
+		}
+		return "{ " + strings.Join(fields, " ") + " }"
+	case "UNION":
+		fields := []string{"__typename"}
+		if depth < maxDepth {
+			for _, p := range named.PossibleTypes {
+				fields = append(fields, "... on "+p.Name+" "+s.selection(&p, depth+1))
+			}
+		}
+		return "{ " + strings.Join(fields, " ") + " }"
+	}
+	return ""
+}
+
+func (s *Schema) isObject(t *TypeRef) bool {
+	named := s.Type(t.Named())
+	return named != nil && (named.Kind == "OBJECT" || named.Kind == "INTERFACE" || named.Kind == "UNION")
+}
+
+// requiresArgs reports whether a field can't be selected without arguments
+func requiresArgs(f Field) bool {
+	for _, a := range f.Args {
+		if a.Type.Required() && a.DefaultValue == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Variables generates the variables of op as compact JSON, with placeholder
+// values for the required arguments; optional ones are left out
+func (s *Schema) Variables(op Operation) string {
+	vars := map[string]interface{}{}
+	for _, a := range op.Field.Args {
+		if a.Type.Required() && a.DefaultValue == nil {
+			vars[a.Name] = s.value(&a.Type, 0)
+		}
+	}
+	data, _ := json.Marshal(vars)
+	return string(data)
+}
+
+// value returns a placeholder value of type t
+func (s *Schema) value(t *TypeRef, depth int) interface{} {
+	switch t.Kind {
+	case "NON_NULL":
+		return s.value(t.OfType, depth)
+	case "LIST":
+		return []interface{}{s.value(t.OfType, depth)}
+	}
+	named := s.Type(t.Name)
+	switch {
+	case t.Name == "Int" || t.Name == "Float":
+		return 0
+	case t.Name == "Boolean":
+		return false
+	case named == nil:
+		return ""
+	case named.Kind == "ENUM" && len(named.EnumValues) > 0:
+		return named.EnumValues[0].Name
+	case named.Kind == "INPUT_OBJECT":
+		obj := map[string]interface{}{}
+		if depth < maxDepth {
+			for _, f := range named.InputFields {
+				if f.Type.Required() && f.DefaultValue == nil {
+					obj[f.Name] = s.value(&f.Type, depth+1)
+				}
+			}
+		}
+		return obj
+	}
+	return ""
+}
+
+// Request builds the JSON body of a GraphQL request; variables is a JSON
+// object, or empty for none
+func Request(query, variables string) (string, error) {
+	body := map[string]interface{}{"query": query}
+	if strings.TrimSpace(variables) != "" {
+		var vars map[string]interface{}
+		if err := json.Unmarshal([]byte(variables), &vars); err != nil {
+			return "", fmt.Errorf("variables must be a JSON object: %v", err)
+		}
+		body["variables"] = vars
+	}
+	data, err := json.Marshal(body)
+	return string(data), err
+}
@@ -0,0 +1,160 @@
+package graphql
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func named(kind, name string) TypeRef { return TypeRef{Kind: kind, Name: name} }
+func nonNull(t TypeRef) TypeRef       { return TypeRef{Kind: "NON_NULL", OfType: &t} }
+func list(t TypeRef) TypeRef          { return TypeRef{Kind: "LIST", OfType: &t} }
+
+func arg(name string, t TypeRef) InputValue { return InputValue{Name: name, Type: t} }
+func field(name string, t TypeRef, args ...InputValue) Field {
+	return Field{Name: name, Type: t, Args: args}
+}
+
+var (
+	idType     = named("SCALAR", "ID")
+	stringType = named("SCALAR", "String")
+	intType    = named("SCALAR", "Int")
+	userType   = named("OBJECT", "User")
+)
+
+// introspection returns the response to IntrospectionQuery for a small
+// schema of users and posts
+func introspection(t *testing.T) []byte {
+	schema := Schema{
+		QueryType:        &namedType{Name: "Query"},
+		MutationType:     &namedType{Name: "Mutation"},
+		SubscriptionType: &namedType{Name: "Subscription"},
+		Types: []Type{
+			{Kind: "OBJECT", Name: "Query", Fields: []Field{
+				field("user", userType, arg("id", nonNull(idType))),
+				field("users", nonNull(list(nonNull(userType))), arg("first", intType), arg("filter", named("INPUT_OBJECT", "UserFilter"))),
+				field("search", list(named("UNION", "SearchResult")), arg("term", nonNull(stringType))),
+			}},
+			{Kind: "OBJECT", Name: "Mutation", Fields: []Field{
+				field("createUser", userType, arg("input", nonNull(named("INPUT_OBJECT", "CreateUserInput")))),
+			}},
+			{Kind: "OBJECT", Name: "Subscription", Fields: []Field{
+				field("userCreated", nonNull(userType)),
+			}},
+			{Kind: "OBJECT", Name: "User", Fields: []Field{
+				field("id", nonNull(idType)),
+				field("name", stringType),
+				field("role", named("ENUM", "Role")),
+				field("friends", list(userType), arg("first", nonNull(intType))),
+				field("posts", list(named("OBJECT", "Post"))),
+			}},
+			{Kind: "OBJECT", Name: "Post", Fields: []Field{
+				field("id", nonNull(idType)),
+				field("author", userType),
+			}},
+			{Kind: "UNION", Name: "SearchResult", PossibleTypes: []TypeRef{userType, named("OBJECT", "Post")}},
+			{Kind: "ENUM", Name: "Role", EnumValues: []struct{ Name string }{{"ADMIN"}, {"MEMBER"}}},
+			{Kind: "INPUT_OBJECT", Name: "UserFilter", InputFields: []InputValue{arg("name", stringType)}},
+			{Kind: "INPUT_OBJECT", Name: "CreateUserInput", InputFields: []InputValue{
+				arg("name", nonNull(stringType)),
+				arg("role", nonNull(named("ENUM", "Role"))),
+				arg("tags", list(stringType)),
+			}},
+			{Kind: "SCALAR", Name: "ID"}, {Kind: "SCALAR", Name: "String"}, {Kind: "SCALAR", Name: "Int"},
+		},
+	}
+	data, err := json.Marshal(map[string]interface{}{"data": map[string]interface{}{"__schema": schema}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestOperations(t *testing.T) {
+	s, err := Parse(introspection(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := s.Operations()
+	var got []string
+	for _, op := range ops {
+		got = append(got, op.Type+" "+op.Field.Name)
+	}
+	want := []string{"query user", "query users", "query search", "mutation createUser", "subscription userCreated"}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected operations %v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected operations %v", got)
+		}
+	}
+
+	tests := []struct {
+		op        Operation
+		query     string
+		variables string
+	}{
+		{ops[0],
+			"query User($id: ID!) { user(id: $id) { id name role posts { id author { id name role } } } }",
+			`{"id":""}`},
+		{ops[1],
+			"query Users($first: Int, $filter: UserFilter) { users(first: $first, filter: $filter) { id name role posts { id author { id name role } } } }",
+			`{}`},
+		{ops[2],
+			"query Search($term: String!) { search(term: $term) { __typename ... on User { id name role posts { id } } ... on Post { id author { id name role } } } }",
+			`{"term":""}`},
+		{ops[3],
+			"mutation CreateUser($input: CreateUserInput!) { createUser(input: $input) { id name role posts { id author { id name role } } } }",
+			`{"input":{"name":"","role":"ADMIN"}}`},
+	}
+	for _, tt := range tests {
+		if got := s.Query(tt.op); got != tt.query {
+			t.Errorf("%s: unexpected query\n got %s\nwant %s", tt.op.Field.Name, got, tt.query)
+		}
+		if got := s.Variables(tt.op); got != tt.variables {
+			t.Errorf("%s: unexpected variables %s", tt.op.Field.Name, got)
+		}
+	}
+}
+
+func TestEmptyFieldNames(t *testing.T) {
+	s, err := Parse([]byte(`{"data":{"__schema":{"queryType":{"name":"Query"},"types":[
+		{"kind":"OBJECT","name":"Query","fields":[
+			{"name":"","type":{"kind":"SCALAR","name":"String"}},
+			{"name":"me","type":{"kind":"OBJECT","name":"User"}}]},
+		{"kind":"OBJECT","name":"User","fields":[
+			{"name":"","type":{"kind":"SCALAR","name":"String"}},
+			{"name":"id","type":{"kind":"SCALAR","name":"ID"}}]},
+		{"kind":"SCALAR","name":"String"},{"kind":"SCALAR","name":"ID"}]}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := s.Operations()
+	if len(ops) != 1 || ops[0].Field.Name != "me" {
+		t.Fatalf("fields without a name should be left out, got %v", ops)
+	}
+	if got := s.Query(ops[0]); got != "query Me { me { id } }" {
+		t.Errorf("unexpected query %s", got)
+	}
+	// an operation built by hand without a name must not panic either
+	s.Query(Operation{Type: "query"})
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse([]byte(`{"errors":[{"message":"introspection disabled"}]}`)); err == nil || err.Error() != "introspection disabled" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := Parse([]byte(`<html>`)); err == nil {
+		t.Error("expected an invalid response error")
+	}
+}
+
+func TestRequest(t *testing.T) {
+	body, err := Request("query { me { id } }", `{"id": 1}`)
+	if err != nil || body != `{"query":"query { me { id } }","variables":{"id":1}}` {
+		t.Errorf("unexpected body %s, %v", body, err)
+	}
+	if _, err := Request("query { me }", "[1]"); err == nil {
+		t.Error("expected variables to be rejected")
+	}
+}
@@ -0,0 +1,384 @@
+// Package grpcclient calls gRPC methods with JSON requests and responses.
+// Methods are discovered through server reflection or loaded from a
+// descriptor set, and messages are built dynamically from their descriptors.
+package grpcclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Method is an RPC method of a service
+type Method struct {
+	Desc protoreflect.MethodDescriptor
+}
+
+// FullName returns the method as it is called, "/package.Service/Method"
+func (m *Method) FullName() string {
+	return "/" + string(m.Desc.Parent().FullName()) + "/" + string(m.Desc.Name())
+}
+
+// Kind describes how the method streams: unary, server streaming, client
+// streaming or bidi streaming
+func (m *Method) Kind() string {
+	switch {
+	case m.Desc.IsStreamingClient() && m.Desc.IsStreamingServer():
+		return "bidi streaming"
+	case m.Desc.IsStreamingClient():
+		return "client streaming"
+	case m.Desc.IsStreamingServer():
+		return "server streaming"
+	}
+	return "unary"
+}
+
+// Client is a connection to a gRPC server with the methods it serves
+type Client struct {
+	Target  string
+	conn    *grpc.ClientConn
+	types   *dynamicpb.Types
+	methods []*Method
+}
+
+// Dial connects to target and loads its methods from descriptorSet, a
+// FileDescriptorSet as written by protoc -o or buf build, or through server
+// reflection when descriptorSet is empty. Targets starting with grpcs:// use
+// TLS; grpc:// and bare host:port targets are plaintext.
+func Dial(ctx context.Context, target, descriptorSet string) (*Client, error) {
+	creds := insecure.NewCredentials()
+	address := strings.TrimPrefix(target, "grpc://")
+	if rest, ok := strings.CutPrefix(target, "grpcs://"); ok {
+		address = rest
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	var files *protoregistry.Files
+	var services []string
+	if descriptorSet != "" {
+		files, err = LoadDescriptorSet(descriptorSet)
+	} else {
+		files, services, err = Reflect(ctx, conn)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Client{
+		Target:  target,
+		conn:    conn,
+		types:   dynamicpb.NewTypes(files),
+		methods: methods(files, services),
+	}, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Methods returns the methods of all services, sorted by full name
+func (c *Client) Methods() []*Method {
+	return c.methods
+}
+
+// Method returns the method with the given full name, or nil
+func (c *Client) Method(fullName string) *Method {
+	for _, m := range c.methods {
+		if m.FullName() == fullName {
+			return m
+		}
+	}
+	return nil
+}
+
+// Template returns the request message of m as JSON with every field set to
+// its zero value, as a starting point for editing
+func (c *Client) Template(m *Method) string {
+	data, err := indent(protojson.MarshalOptions{EmitUnpopulated: true, Resolver: c.types}, dynamicpb.NewMessage(m.Desc.Input()))
+	if err != nil {
+		return "{}"
+	}
+	return data
+}
+
+// indent marshals msg as JSON indented by two spaces; protojson's own
+// multiline output varies its spacing on purpose
+func indent(options protojson.MarshalOptions, msg proto.Message) (string, error) {
+	data, err := options.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Call invokes m with body, a JSON request message, sending headers as
+// metadata. Client and bidi streaming methods take a JSON array and send
+// each element as a message. onMessage receives every response message as
+// JSON as it arrives. The returned error carries the gRPC status.
+func (c *Client) Call(ctx context.Context, m *Method, body string, headers map[string]string, onMessage func(string)) (header, trailer metadata.MD, err error) {
+	requests, err := c.requests(m, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range headers {
+		ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(k), v)
+	}
+	desc := &grpc.StreamDesc{
+		StreamName:    string(m.Desc.Name()),
+		ServerStreams: m.Desc.IsStreamingServer(),
+		ClientStreams: m.Desc.IsStreamingClient(),
+	}
+	stream, err := c.conn.NewStream(ctx, desc, m.FullName())
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, req := range requests {
+		if err := stream.SendMsg(req); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, nil, err
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, nil, err
+	}
+	marshal := protojson.MarshalOptions{Resolver: c.types}
+	for {
+		resp := dynamicpb.NewMessage(m.Desc.Output())
+		if err = stream.RecvMsg(resp); err != nil {
+			break
+		}
+		data, marshalErr := indent(marshal, resp)
+		if marshalErr != nil {
+			return nil, nil, marshalErr
+		}
+		if onMessage != nil {
+			onMessage(data)
+		}
+	}
+	header, _ = stream.Header()
+	trailer = stream.Trailer()
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return header, trailer, err
+}
+
+// requests decodes the request messages of m from body
+func (c *Client) requests(m *Method, body string) ([]proto.Message, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		body = "{}"
+	}
+	raw := []json.RawMessage{json.RawMessage(body)}
+	if m.Desc.IsStreamingClient() && strings.HasPrefix(body, "[") {
+		if err := json.Unmarshal([]byte(body), &raw); err != nil {
+			return nil, fmt.Errorf("invalid request messages: %v", err)
+		}
+	}
+	unmarshal := protojson.UnmarshalOptions{Resolver: c.types}
+	var msgs []proto.Message
+	for i, r := range raw {
+		msg := dynamicpb.NewMessage(m.Desc.Input())
+		if err := unmarshal.Unmarshal(r, msg); err != nil {
+			if len(raw) > 1 {
+				return nil, fmt.Errorf("invalid request message %d: %v", i+1, err)
+			}
+			return nil, fmt.Errorf("invalid request message: %v", err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// LoadDescriptorSet loads a FileDescriptorSet written by protoc
+// --include_imports -o or buf build -o
+func LoadDescriptorSet(path string) (*protoregistry.Files, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %v", err)
+	}
+	return protodesc.NewFiles(&set)
+}
+
+// Reflect lists the services of a server through the v1 reflection service
+// and loads the files that declare them
+func Reflect(ctx context.Context, conn *grpc.ClientConn) (*protoregistry.Files, []string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	ask := func(req *rpb.ServerReflectionRequest) (*rpb.ServerReflectionResponse, error) {
+		if err := stream.Send(req); err != nil {
+			return nil, err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return nil, fmt.Errorf("reflection: %s", e.GetErrorMessage())
+		}
+		return resp, nil
+	}
+
+	resp, err := ask(&rpb.ServerReflectionRequest{MessageRequest: &rpb.ServerReflectionRequest_ListServices{}})
+	if err != nil {
+		return nil, nil, err
+	}
+	var services []string
+	for _, s := range resp.GetListServicesResponse().GetService() {
+		services = append(services, s.GetName())
+	}
+
+	protos := map[string]*descriptorpb.FileDescriptorProto{}
+	add := func(resp *rpb.ServerReflectionResponse) error {
+		for _, data := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fd := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(data, fd); err != nil {
+				return fmt.Errorf("reflection: invalid file descriptor: %v", err)
+			}
+			protos[fd.GetName()] = fd
+		}
+		return nil
+	}
+	for _, service := range services {
+		resp, err := ask(&rpb.ServerReflectionRequest{MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service}})
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := add(resp); err != nil {
+			return nil, nil, err
+		}
+	}
+	// Servers usually send the dependencies along; fetch any that are missing
+	for missing := missingDependencies(protos); len(missing) > 0; missing = missingDependencies(protos) {
+		for _, name := range missing {
+			resp, err := ask(&rpb.ServerReflectionRequest{MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: name}})
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := add(resp); err != nil {
+				return nil, nil, err
+			}
+			if protos[name] == nil {
+				return nil, nil, fmt.Errorf("reflection: file %s not found", name)
+			}
+		}
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, fd := range protos {
+		set.File = append(set.File, fd)
+	}
+	files, err := protodesc.NewFiles(set)
+	return files, services, err
+}
+
+// missingDependencies returns the imports of protos not loaded yet
+func missingDependencies(protos map[string]*descriptorpb.FileDescriptorProto) []string {
+	var missing []string
+	for _, fd := range protos {
+		for _, dep := range fd.GetDependency() {
+			if protos[dep] == nil {
+				missing = append(missing, dep)
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// methods lists the methods of the given services, or of every service in
+// files when services is empty, leaving out the reflection services
+func methods(files *protoregistry.Files, services []string) []*Method {
+	wanted := map[string]bool{}
+	for _, s := range services {
+		wanted[s] = true
+	}
+	var list []*Method
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		for i := 0; i < fd.Services().Len(); i++ {
+			sd := fd.Services().Get(i)
+			name := string(sd.FullName())
+			if strings.HasPrefix(name, "grpc.reflection.") || (len(wanted) > 0 && !wanted[name]) {
+				continue
+			}
+			for j := 0; j < sd.Methods().Len(); j++ {
+				list = append(list, &Method{Desc: sd.Methods().Get(j)})
+			}
+		}
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool { return list[i].FullName() < list[j].FullName() })
+	return list
+}
+
+// HTTPStatus maps a gRPC status code to the HTTP status grpc-gateway
+// responds with, so gRPC results are colored like HTTP ones
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,132 @@
+package grpcclient
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// startServer serves the health service on a local port, with reflection
+// when reflect is set
+func startServer(t *testing.T, reflect bool) (string, *health.Server) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if md, _ := metadata.FromIncomingContext(ctx); len(md.Get("authorization")) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "missing credentials")
+		}
+		return handler(ctx, req)
+	}))
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("orders", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	if reflect {
+		reflection.Register(server)
+	}
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String(), healthServer
+}
+
+func TestReflectionUnary(t *testing.T) {
+	addr, _ := startServer(t, true)
+	ctx := context.Background()
+	c, err := Dial(ctx, "grpc://"+addr, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var names []string
+	for _, m := range c.Methods() {
+		names = append(names, m.FullName()+" "+m.Kind())
+	}
+	want := "/grpc.health.v1.Health/Check unary,/grpc.health.v1.Health/Watch server streaming"
+	if got := strings.Join(names, ","); got != want {
+		t.Fatalf("unexpected methods %s", got)
+	}
+	check := c.Method("/grpc.health.v1.Health/Check")
+	if tmpl := c.Template(check); !strings.Contains(tmpl, `"service": ""`) {
+		t.Errorf("unexpected template %s", tmpl)
+	}
+
+	if _, _, err := c.Call(ctx, check, `{"service": "orders"}`, nil, nil); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected the missing metadata to be rejected, got %v", err)
+	}
+	var got []string
+	_, _, err = c.Call(ctx, check, `{"service": "orders"}`, map[string]string{"Authorization": "Bearer token"}, func(msg string) {
+		got = append(got, msg)
+	})
+	if err != nil || len(got) != 1 || !strings.Contains(got[0], `"SERVING"`) {
+		t.Errorf("unexpected response %v, %v", got, err)
+	}
+	if HTTPStatus(codes.Unauthenticated) != 401 || HTTPStatus(codes.Internal) != 500 {
+		t.Error("unexpected HTTP status mapping")
+	}
+	if _, _, err := c.Call(ctx, check, `{"unknown": 1}`, nil, nil); err == nil || !strings.Contains(err.Error(), "invalid request message") {
+		t.Errorf("expected an invalid request error, got %v", err)
+	}
+}
+
+func TestDescriptorSetStreaming(t *testing.T) {
+	addr, healthServer := startServer(t, false)
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		protodesc.ToFileDescriptorProto(healthpb.File_grpc_health_v1_health_proto),
+	}}
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "health.pb")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Dial(context.Background(), addr, ""); err == nil {
+		t.Error("expected reflection to fail on a server without it")
+	}
+	c, err := Dial(context.Background(), addr, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	messages := make(chan string, 4)
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := c.Call(ctx, c.Method("/grpc.health.v1.Health/Watch"), `{"service": "orders"}`, nil, func(msg string) {
+			messages <- msg
+		})
+		done <- err
+	}()
+	if msg := <-messages; !strings.Contains(msg, `"SERVING"`) {
+		t.Errorf("unexpected first message %s", msg)
+	}
+	healthServer.SetServingStatus("orders", healthpb.HealthCheckResponse_NOT_SERVING)
+	if msg := <-messages; !strings.Contains(msg, `"NOT_SERVING"`) {
+		t.Errorf("unexpected second message %s", msg)
+	}
+	cancel()
+	if err := <-done; status.Code(err) != codes.Canceled {
+		t.Errorf("expected the stream to be canceled, got %v", err)
+	}
+}
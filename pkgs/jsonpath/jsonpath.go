@@ -0,0 +1,259 @@
+package jsonpath
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// step is a single segment of a compiled path
+type step struct {
+	key      string
+	index    int
+	wildcard bool
+	isIndex  bool
+	// recursive applies the step to a node and all its descendants (..)
+	recursive bool
+}
+
+// Eval evaluates a JSONPath expression against a decoded JSON document
+// and returns every matching value. Supported syntax: $, .key, ['key'],
+// [n] (negative counts from the end), [*], .* and recursive descent with
+// ..key, ..* or ..[n]. Filter expressions and slices are not supported.
+func Eval(expr string, doc interface{}) ([]interface{}, error) {
+	steps, err := compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	current := []interface{}{doc}
+	for _, s := range steps {
+		var next []interface{}
+		for _, node := range current {
+			if !s.recursive {
+				next = append(next, s.apply(node)...)
+				continue
+			}
+			for _, d := range descendants(node) {
+				next = append(next, s.apply(d)...)
+			}
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// descendants returns node and everything below it in document order, with
+// map keys sorted
+func descendants(node interface{}) []interface{} {
+	out := []interface{}{node}
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			out = append(out, descendants(v[k])...)
+		}
+	case []interface{}:
+		for _, child := range v {
+			out = append(out, descendants(child)...)
+		}
+	}
+	return out
+}
+
+func (s step) apply(node interface{}) []interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if s.wildcard {
+			var out []interface{}
+			for _, k := range sortedKeys(v) {
+				out = append(out, v[k])
+			}
+			return out
+		}
+		if s.isIndex {
+			return nil
+		}
+		if val, ok := v[s.key]; ok {
+			return []interface{}{val}
+		}
+	case []interface{}:
+		if s.wildcard {
+			return v
+		}
+		if !s.isIndex {
+			return nil
+		}
+		idx := s.index
+		if idx < 0 {
+			idx += len(v)
+		}
+		if idx >= 0 && idx < len(v) {
+			return []interface{}{v[idx]}
+		}
+	}
+	return nil
+}
+
+func compile(expr string) ([]step, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("jsonpath must start with $: %q", expr)
+	}
+	rest := expr[1:]
+
+	var steps []step
+	recursive := false
+	add := func(s step) {
+		s.recursive, recursive = recursive, false
+		steps = append(steps, s)
+	}
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			if strings.HasPrefix(rest, "..") {
+				if recursive || len(rest) == 2 {
+					return nil, fmt.Errorf("incomplete recursive descent in jsonpath %q", expr)
+				}
+				recursive = true
+				if rest[2] == '[' {
+					rest = rest[2:]
+					continue
+				}
+			}
+			rest = strings.TrimPrefix(rest[1:], ".")
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			if name == "" {
+				return nil, fmt.Errorf("empty key in jsonpath %q", expr)
+			}
+			if name == "*" {
+				add(step{wildcard: true})
+			} else {
+				add(step{key: name})
+			}
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed bracket in jsonpath %q", expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				add(step{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				add(step{key: inner[1 : len(inner)-1]})
+			case strings.HasPrefix(inner, "?"):
+				return nil, fmt.Errorf("filter expressions are not supported in jsonpath %q; use a jq expression with select instead", expr)
+			case strings.Contains(inner, ":"):
+				return nil, fmt.Errorf("slices are not supported in jsonpath %q", expr)
+			default:
+				idx, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q in jsonpath %q", inner, expr)
+				}
+				add(step{index: idx, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q in jsonpath %q", rest[0], expr)
+		}
+	}
+	return steps, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Remove deletes every value matching expr from doc and returns the result.
+// Matching map keys are deleted and matching array elements are dropped.
+// Maps are modified in place; removing the root returns nil.
+func Remove(expr string, doc interface{}) (interface{}, error) {
+	steps, err := compile(expr)
+	if err != nil {
+		return doc, err
+	}
+	if len(steps) == 0 {
+		return nil, nil
+	}
+	return remove(steps, doc), nil
+}
+
+func remove(steps []step, node interface{}) interface{} {
+	s, last := steps[0], len(steps) == 1
+	if s.recursive {
+		// remove at this level, then below every child that is left
+		flat := append([]step{s}, steps[1:]...)
+		flat[0].recursive = false
+		node = remove(flat, node)
+		switch v := node.(type) {
+		case map[string]interface{}:
+			for _, k := range sortedKeys(v) {
+				v[k] = remove(steps, v[k])
+			}
+		case []interface{}:
+			for i := range v {
+				v[i] = remove(steps, v[i])
+			}
+		}
+		return node
+	}
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if s.isIndex {
+			return v
+		}
+		keys := []string{s.key}
+		if s.wildcard {
+			keys = sortedKeys(v)
+		}
+		for _, k := range keys {
+			child, ok := v[k]
+			if !ok {
+				continue
+			}
+			if last {
+				delete(v, k)
+			} else {
+				v[k] = remove(steps[1:], child)
+			}
+		}
+		return v
+	case []interface{}:
+		if s.wildcard {
+			if last {
+				return []interface{}{}
+			}
+			for i := range v {
+				v[i] = remove(steps[1:], v[i])
+			}
+			return v
+		}
+		if !s.isIndex {
+			return v
+		}
+		idx := s.index
+		if idx < 0 {
+			idx += len(v)
+		}
+		if idx < 0 || idx >= len(v) {
+			return v
+		}
+		if last {
+			return append(v[:idx:idx], v[idx+1:]...)
+		}
+		v[idx] = remove(steps[1:], v[idx])
+		return v
+	}
+	return node
+}
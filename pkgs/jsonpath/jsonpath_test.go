@@ -0,0 +1,116 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const doc = `{
+  "store": {
+    "name": "corner",
+    "books": [
+      {"title": "a", "price": 8, "author": {"name": "ann"}},
+      {"title": "b", "price": 12, "tags": ["x", "y"]}
+    ]
+  },
+  "odd key": 1
+}`
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestEval(t *testing.T) {
+	for _, tc := range []struct {
+		expr string
+		want string
+	}{
+		{"$", ""},
+		{"$.store.name", `["corner"]`},
+		{"$['odd key']", `[1]`},
+		{`$["store"]["name"]`, `["corner"]`},
+		{"$.store.books[0].title", `["a"]`},
+		{"$.store.books[-1].title", `["b"]`},
+		{"$.store.books[5].title", `null`},
+		{"$.store.books[*].price", `[8,12]`},
+		{"$.store.books[1].*", `[12,["x","y"],"b"]`},
+		{"$.missing", `null`},
+		{"$..name", `["corner","ann"]`},
+		{"$..price", `[8,12]`},
+		{"$..tags[*]", `["x","y"]`},
+		{"$..[0].title", `["a"]`},
+		{"$.store..title", `["a","b"]`},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			d := decode(t, doc)
+			got, err := Eval(tc.expr, d)
+			if err != nil {
+				t.Fatalf("Eval: %v", err)
+			}
+			if tc.expr == "$" {
+				if len(got) != 1 || !reflect.DeepEqual(got[0], d) {
+					t.Errorf("$ should return the document, got %v", got)
+				}
+				return
+			}
+			if data, _ := json.Marshal(got); string(data) != tc.want && !(tc.want == "null" && len(got) == 0) {
+				t.Errorf("got %s, want %s", data, tc.want)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	for _, tc := range []struct {
+		expr string
+		want string
+	}{
+		{"store.name", "must start with $"},
+		{"$.", "empty key"},
+		{"$..", "incomplete recursive descent"},
+		{"$...a", "empty key"},
+		{"$.books[0", "unclosed bracket"},
+		{"$.books[x]", "invalid index"},
+		{"$.books[?(@.price > 10)]", "filter expressions are not supported"},
+		{"$.books[0:2]", "slices are not supported"},
+		{"$a", "unexpected"},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			if _, err := Eval(tc.expr, nil); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestRemove(t *testing.T) {
+	for _, tc := range []struct {
+		expr string
+		want string
+	}{
+		{"$.store.name", `{"odd key":1,"store":{"books":[{"author":{"name":"ann"},"price":8,"title":"a"},{"price":12,"tags":["x","y"],"title":"b"}]}}`},
+		{"$.store.books[0]", `{"odd key":1,"store":{"books":[{"price":12,"tags":["x","y"],"title":"b"}],"name":"corner"}}`},
+		{"$.store.books[*].price", `{"odd key":1,"store":{"books":[{"author":{"name":"ann"},"title":"a"},{"tags":["x","y"],"title":"b"}],"name":"corner"}}`},
+		{"$..name", `{"odd key":1,"store":{"books":[{"author":{},"price":8,"title":"a"},{"price":12,"tags":["x","y"],"title":"b"}]}}`},
+		{"$..tags[0]", `{"odd key":1,"store":{"books":[{"author":{"name":"ann"},"price":8,"title":"a"},{"price":12,"tags":["y"],"title":"b"}],"name":"corner"}}`},
+		{"$.missing", `{"odd key":1,"store":{"books":[{"author":{"name":"ann"},"price":8,"title":"a"},{"price":12,"tags":["x","y"],"title":"b"}],"name":"corner"}}`},
+		{"$", `null`},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			got, err := Remove(tc.expr, decode(t, doc))
+			if err != nil {
+				t.Fatalf("Remove: %v", err)
+			}
+			if data, _ := json.Marshal(got); string(data) != tc.want {
+				t.Errorf("got  %s\nwant %s", data, tc.want)
+			}
+		})
+	}
+}
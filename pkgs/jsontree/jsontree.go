@@ -0,0 +1,323 @@
+package jsontree
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Kinds of JSON values
+const (
+	KindObject = "object"
+	KindArray  = "array"
+	KindString = "string"
+	KindNumber = "number"
+	KindBool   = "boolean"
+	KindNull   = "null"
+)
+
+// Node is one JSON value in document order
+type Node struct {
+	// Key is the member name, or the index for array elements
+	Key    string
+	Kind   string
+	Parent *Node
+	// Pointer is the RFC 6901 JSON pointer of the node, "" for the root
+	Pointer  string
+	Children []*Node
+	Expanded bool
+	Depth    int
+	// raw is the JSON text of a scalar value
+	raw string
+}
+
+// Parse decodes data keeping the order of object members
+func Parse(data []byte) (*Node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	root, err := parseValue(dec, nil, "", -1)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return root, nil
+}
+
+func parseValue(dec *json.Decoder, parent *Node, key string, depth int) (*Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	n := &Node{Key: key, Parent: parent, Depth: depth}
+	if parent != nil {
+		n.Pointer = parent.Pointer + "/" + escapePointer(key)
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '{' {
+			n.Kind = KindObject
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				child, err := parseValue(dec, n, keyTok.(string), depth+1)
+				if err != nil {
+					return nil, err
+				}
+				n.Children = append(n.Children, child)
+			}
+		} else {
+			n.Kind = KindArray
+			for i := 0; dec.More(); i++ {
+				child, err := parseValue(dec, n, strconv.Itoa(i), depth+1)
+				if err != nil {
+					return nil, err
+				}
+				n.Children = append(n.Children, child)
+			}
+		}
+		if _, err := dec.Token(); err != nil { // closing delimiter
+			return nil, err
+		}
+	case string:
+		n.Kind = KindString
+		data, _ := json.Marshal(v)
+		n.raw = string(data)
+	case json.Number:
+		n.Kind = KindNumber
+		n.raw = v.String()
+	case bool:
+		n.Kind = KindBool
+		n.raw = strconv.FormatBool(v)
+	case nil:
+		n.Kind = KindNull
+		n.raw = "null"
+	}
+	return n, nil
+}
+
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+// IsContainer reports whether the node is an object or array
+func (n *Node) IsContainer() bool {
+	return n.Kind == KindObject || n.Kind == KindArray
+}
+
+// JSON returns the node's value as indented JSON
+func (n *Node) JSON() string {
+	var b strings.Builder
+	n.write(&b, "")
+	return b.String()
+}
+
+func (n *Node) write(b *strings.Builder, indent string) {
+	if !n.IsContainer() {
+		b.WriteString(n.raw)
+		return
+	}
+	open, closing := "{", "}"
+	if n.Kind == KindArray {
+		open, closing = "[", "]"
+	}
+	if len(n.Children) == 0 {
+		b.WriteString(open + closing)
+		return
+	}
+	b.WriteString(open + "\n")
+	for i, child := range n.Children {
+		b.WriteString(indent + "  ")
+		if n.Kind == KindObject {
+			key, _ := json.Marshal(child.Key)
+			b.Write(key)
+			b.WriteString(": ")
+		}
+		child.write(b, indent+"  ")
+		if i < len(n.Children)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(indent + closing)
+}
+
+// summary describes a container, e.g. "object · 3 keys", or returns the
+// JSON text of a scalar
+func (n *Node) summary() string {
+	switch n.Kind {
+	case KindObject:
+		return fmt.Sprintf("object · %d %s", len(n.Children), plural(len(n.Children), "key"))
+	case KindArray:
+		return fmt.Sprintf("array · %d %s", len(n.Children), plural(len(n.Children), "item"))
+	}
+	return n.raw
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// Tree is the foldable view of a document. Rows are the visible nodes; the
+// root itself is not shown unless it is a scalar.
+type Tree struct {
+	Root    *Node
+	visible []*Node
+}
+
+// New creates a tree showing levels levels of nesting; 1 shows only the
+// top-level members
+func New(root *Node, levels int) *Tree {
+	t := &Tree{Root: root}
+	root.Expanded = true
+	walk(root, func(n *Node) {
+		if n.Depth >= 0 && n.Depth < levels-1 {
+			n.Expanded = true
+		}
+	})
+	t.refresh()
+	return t
+}
+
+func walk(n *Node, fn func(*Node)) {
+	fn(n)
+	for _, child := range n.Children {
+		walk(child, fn)
+	}
+}
+
+func (t *Tree) refresh() {
+	t.visible = t.visible[:0]
+	if !t.Root.IsContainer() {
+		t.visible = append(t.visible, t.Root)
+		return
+	}
+	var add func(n *Node)
+	add = func(n *Node) {
+		for _, child := range n.Children {
+			t.visible = append(t.visible, child)
+			if child.Expanded {
+				add(child)
+			}
+		}
+	}
+	add(t.Root)
+}
+
+// Len returns the number of visible rows
+func (t *Tree) Len() int {
+	return len(t.visible)
+}
+
+// Node returns the node shown on row i, nil when out of range
+func (t *Tree) Node(i int) *Node {
+	if i < 0 || i >= len(t.visible) {
+		return nil
+	}
+	return t.visible[i]
+}
+
+// Rows renders the visible nodes with termui color markup by JSON type
+func (t *Tree) Rows() []string {
+	rows := make([]string, 0, len(t.visible))
+	for _, n := range t.visible {
+		rows = append(rows, renderRow(n))
+	}
+	return rows
+}
+
+func renderRow(n *Node) string {
+	indent := ""
+	if n.Depth > 0 {
+		indent = strings.Repeat("  ", n.Depth)
+	}
+	marker := "  "
+	if n.IsContainer() && len(n.Children) > 0 {
+		marker = "▸ "
+		if n.Expanded {
+			marker = "▾ "
+		}
+	}
+	label := ""
+	if n.Parent != nil {
+		label = n.Key + ": "
+	}
+	return indent + marker + label + colorize(n)
+}
+
+func colorize(n *Node) string {
+	color := ""
+	switch n.Kind {
+	case KindObject, KindArray:
+		color = "blue"
+	case KindString:
+		color = "green"
+	case KindNumber:
+		color = "cyan"
+	case KindBool:
+		color = "yellow"
+	case KindNull:
+		color = "magenta"
+	}
+	text := n.summary()
+	// brackets inside the text would be read as termui markup
+	if strings.ContainsAny(text, "[]()") {
+		return text
+	}
+	return "[" + text + "](fg:" + color + ")"
+}
+
+// Toggle folds or unfolds the container on row i
+func (t *Tree) Toggle(i int) {
+	if n := t.Node(i); n != nil && n.IsContainer() {
+		n.Expanded = !n.Expanded
+		t.refresh()
+	}
+}
+
+// Expand unfolds the container on row i
+func (t *Tree) Expand(i int) {
+	if n := t.Node(i); n != nil && n.IsContainer() && !n.Expanded {
+		n.Expanded = true
+		t.refresh()
+	}
+}
+
+// Collapse folds the container on row i, or when it is already folded or a
+// scalar, moves to its parent. It returns the row to select.
+func (t *Tree) Collapse(i int) int {
+	n := t.Node(i)
+	if n == nil {
+		return i
+	}
+	if n.IsContainer() && n.Expanded {
+		n.Expanded = false
+		t.refresh()
+		return i
+	}
+	for j := i - 1; j >= 0; j-- {
+		if t.visible[j] == n.Parent {
+			return j
+		}
+	}
+	return i
+}
+
+// SetAll expands or collapses every container
+func (t *Tree) SetAll(expanded bool) {
+	walk(t.Root, func(n *Node) {
+		if n != t.Root {
+			n.Expanded = expanded
+		}
+	})
+	t.refresh()
+}
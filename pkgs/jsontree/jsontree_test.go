@@ -0,0 +1,70 @@
+package jsontree
+
+import (
+	"strings"
+	"testing"
+)
+
+const doc = `{"name":"rex","owner":{"id":7,"a/b":null},"tags":["x","y"],"ok":true}`
+
+func TestParseKeepsOrderAndPointers(t *testing.T) {
+	root, err := Parse([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, c := range root.Children {
+		keys = append(keys, c.Key)
+	}
+	if strings.Join(keys, ",") != "name,owner,tags,ok" {
+		t.Errorf("member order lost: %v", keys)
+	}
+	slash := root.Children[1].Children[1]
+	if slash.Pointer != "/owner/a~1b" || slash.Kind != KindNull {
+		t.Errorf("unexpected node %+v", slash)
+	}
+	if got := root.Children[2].Children[1].Pointer; got != "/tags/1" {
+		t.Errorf("unexpected array pointer %q", got)
+	}
+	if got := root.Children[1].JSON(); got != "{\n  \"id\": 7,\n  \"a/b\": null\n}" {
+		t.Errorf("unexpected JSON %q", got)
+	}
+
+	if _, err := Parse([]byte(`{"a":1} trailing`)); err == nil {
+		t.Error("expected an error for trailing data")
+	}
+}
+
+func TestTreeFolding(t *testing.T) {
+	root, _ := Parse([]byte(doc))
+	tree := New(root, 1)
+
+	rows := tree.Rows()
+	if len(rows) != 4 {
+		t.Fatalf("expected top-level members only, got %v", rows)
+	}
+	if !strings.Contains(rows[1], "▸ owner: [object · 2 keys](fg:blue)") || !strings.Contains(rows[0], `name: ["rex"](fg:green)`) {
+		t.Errorf("unexpected rows %v", rows)
+	}
+
+	tree.Toggle(1)
+	if tree.Len() != 6 || tree.Node(2).Key != "id" || !strings.Contains(tree.Rows()[1], "▾ owner") {
+		t.Errorf("owner not expanded: %v", tree.Rows())
+	}
+	if got := tree.Collapse(2); got != 1 {
+		t.Errorf("collapsing a scalar should select its parent, got row %d", got)
+	}
+	tree.Collapse(1)
+	if tree.Len() != 4 {
+		t.Errorf("owner not collapsed: %v", tree.Rows())
+	}
+
+	tree.SetAll(true)
+	if tree.Len() != 8 {
+		t.Errorf("expected every node visible, got %v", tree.Rows())
+	}
+	tree.SetAll(false)
+	if tree.Len() != 4 {
+		t.Errorf("expected top level only, got %v", tree.Rows())
+	}
+}
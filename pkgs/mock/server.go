@@ -0,0 +1,182 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"org.subh/api-term/pkgs/api/example"
+	"org.subh/api-term/pkgs/api/parser"
+)
+
+// Options tunes how the mock server answers. Override maps are keyed by
+// operationId or by "METHOD /path/template".
+type Options struct {
+	StatusOverrides  map[string]int
+	Latency          time.Duration
+	LatencyOverrides map[string]time.Duration
+	// SkipValidation disables validation of incoming requests against the spec
+	SkipValidation bool
+}
+
+// Server serves every operation in an OpenAPI document with example responses
+type Server struct {
+	Doc       *openapi3.T
+	Options   Options
+	basePaths []string
+}
+
+// NewServer creates a mock Server for doc
+func NewServer(doc *openapi3.T, opts Options) *Server {
+	s := &Server{Doc: doc, Options: opts}
+	for _, srv := range doc.Servers {
+		if u, err := url.Parse(srv.URL); err == nil && strings.Trim(u.Path, "/") != "" {
+			s.basePaths = append(s.basePaths, "/"+strings.Trim(u.Path, "/"))
+		}
+	}
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := s.stripBasePath(r.URL.Path)
+	op, template := parser.FindOperation(s.Doc, r.Method, path)
+	if op == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no operation for %s %s", r.Method, r.URL.Path))
+		return
+	}
+	key := strings.ToUpper(r.Method) + " " + template
+
+	if delay := s.latencyFor(op, key); delay > 0 {
+		time.Sleep(delay)
+	}
+
+	if !s.Options.SkipValidation {
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: parser.PathParams(template, path),
+			Route: &routers.Route{
+				Spec:      s.Doc,
+				Path:      template,
+				PathItem:  s.Doc.Paths.Value(template),
+				Method:    strings.ToUpper(r.Method),
+				Operation: op,
+			},
+			Options: &openapi3filter.Options{
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			},
+		}
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	status, response := s.responseFor(op, key)
+	if response == nil || len(response.Content) == 0 {
+		w.WriteHeader(status)
+		return
+	}
+
+	mediaType, media := pickMedia(response.Content)
+	body := example.ForMedia(media)
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	if text, ok := body.(string); ok && !strings.Contains(mediaType, "json") {
+		fmt.Fprint(w, text)
+		return
+	}
+	json.NewEncoder(w).Encode(body)
+}
+
+func (s *Server) stripBasePath(path string) string {
+	for _, base := range s.basePaths {
+		if path == base {
+			return "/"
+		}
+		if strings.HasPrefix(path, base+"/") {
+			return strings.TrimPrefix(path, base)
+		}
+	}
+	return path
+}
+
+func (s *Server) latencyFor(op *openapi3.Operation, key string) time.Duration {
+	if d, ok := s.Options.LatencyOverrides[op.OperationID]; ok && op.OperationID != "" {
+		return d
+	}
+	if d, ok := s.Options.LatencyOverrides[key]; ok {
+		return d
+	}
+	return s.Options.Latency
+}
+
+// responseFor picks the status to answer with: an override if configured,
+// otherwise the lowest documented 2xx, otherwise the lowest documented code
+func (s *Server) responseFor(op *openapi3.Operation, key string) (int, *openapi3.Response) {
+	override, ok := s.Options.StatusOverrides[op.OperationID]
+	if !ok || op.OperationID == "" {
+		override, ok = s.Options.StatusOverrides[key]
+	}
+	if op.Responses == nil {
+		if ok {
+			return override, nil
+		}
+		return http.StatusOK, nil
+	}
+	if ok {
+		if ref := op.Responses.Status(override); ref != nil {
+			return override, ref.Value
+		}
+		return override, nil
+	}
+
+	var codes []int
+	for code := range op.Responses.Map() {
+		if n, err := strconv.Atoi(code); err == nil {
+			codes = append(codes, n)
+		}
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		if code >= 200 && code < 300 {
+			return code, op.Responses.Status(code).Value
+		}
+	}
+	if len(codes) > 0 {
+		return codes[0], op.Responses.Status(codes[0]).Value
+	}
+	if ref := op.Responses.Default(); ref != nil {
+		return http.StatusOK, ref.Value
+	}
+	return http.StatusOK, nil
+}
+
+// pickMedia prefers a JSON media type and falls back to the first one by name
+func pickMedia(content openapi3.Content) (string, *openapi3.MediaType) {
+	names := make([]string, 0, len(content))
+	for name := range content {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.Contains(name, "json") {
+			return name, content[name]
+		}
+	}
+	return names[0], content[names[0]]
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,133 @@
+package mock
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const testSpec = `openapi: 3.0.0
+info:
+  title: Sample API
+  version: 0.1.9
+servers:
+  - url: http://localhost:8080/v1
+paths:
+  /models:
+    get:
+      operationId: listModels
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            maximum: 100
+      responses:
+        '200':
+          description: Models
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    id:
+                      type: string
+                      format: uuid
+                    name:
+                      type: string
+        '500':
+          description: Failure
+          content:
+            application/json:
+              example:
+                error: boom
+  /models/{model_id}:
+    get:
+      parameters:
+        - name: model_id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        '200':
+          description: A model
+          content:
+            application/json:
+              example:
+                id: m1
+                name: Model1
+`
+
+func newTestServer(t *testing.T, opts Options) *httptest.Server {
+	t.Helper()
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(testSpec))
+	if err != nil {
+		t.Fatalf("load spec: %v", err)
+	}
+	return httptest.NewServer(NewServer(doc, opts))
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestServer_Responses(t *testing.T) {
+	ts := newTestServer(t, Options{})
+	defer ts.Close()
+
+	status, body := get(t, ts.URL+"/v1/models/m1")
+	if status != http.StatusOK || !strings.Contains(body, `"name":"Model1"`) {
+		t.Errorf("expected documented example, got %d %s", status, body)
+	}
+
+	status, body = get(t, ts.URL+"/v1/models")
+	var models []map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &models); err != nil || status != http.StatusOK {
+		t.Fatalf("expected generated array, got %d %s", status, body)
+	}
+	if len(models) != 1 || models[0]["id"] != "00000000-0000-4000-8000-000000000000" {
+		t.Errorf("unexpected generated data: %v", models)
+	}
+
+	if status, _ := get(t, ts.URL+"/v1/unknown"); status != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown path, got %d", status)
+	}
+	if status, body := get(t, ts.URL+"/v1/models?limit=1000"); status != http.StatusBadRequest {
+		t.Errorf("expected validation failure, got %d %s", status, body)
+	}
+}
+
+func TestServer_Overrides(t *testing.T) {
+	ts := newTestServer(t, Options{
+		StatusOverrides:  map[string]int{"listModels": 500},
+		LatencyOverrides: map[string]time.Duration{"GET /models/{model_id}": 50 * time.Millisecond},
+	})
+	defer ts.Close()
+
+	status, body := get(t, ts.URL+"/v1/models")
+	if status != http.StatusInternalServerError || !strings.Contains(body, "boom") {
+		t.Errorf("expected overridden 500 response, got %d %s", status, body)
+	}
+
+	start := time.Now()
+	get(t, ts.URL+"/v1/models/m1")
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected injected latency, took %s", elapsed)
+	}
+}
@@ -0,0 +1,335 @@
+// Package paginate finds the next page of a list response, from Link
+// headers, cursor or next fields in the body, or page/offset parameters.
+package paginate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"org.subh/api-term/pkgs/api/model"
+	"org.subh/api-term/pkgs/query"
+)
+
+// Styles of pagination
+const (
+	StyleLink   = "link"
+	StyleCursor = "cursor"
+	StylePage   = "page"
+	StyleOffset = "offset"
+)
+
+// Rule describes how an operation paginates
+type Rule struct {
+	Style string `yaml:"style"`
+	// Param is the query parameter carrying the cursor, page number or offset
+	Param string `yaml:"param,omitempty"`
+	// Next is a jq or JSONPath expression for the next cursor, or for the
+	// next page URL in the link style; empty uses the Link header
+	Next string `yaml:"next,omitempty"`
+	// Items selects the items of a page for fetch-all; empty guesses
+	Items string `yaml:"items,omitempty"`
+	// Limit is the page size parameter of the offset style
+	Limit string `yaml:"limit,omitempty"`
+}
+
+// Config holds rules keyed by operationId or "METHOD /path"
+type Config map[string]*Rule
+
+// LoadConfig reads pagination rules from a YAML file
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid pagination config: %v", err)
+	}
+	for key, r := range c {
+		if r == nil {
+			return nil, fmt.Errorf("pagination rule %s is empty", key)
+		}
+		switch r.Style {
+		case StyleLink:
+		case StyleCursor, StylePage, StyleOffset:
+			if r.Param == "" {
+				return nil, fmt.Errorf("pagination rule %s: %s style needs a param", key, r.Style)
+			}
+		default:
+			return nil, fmt.Errorf("pagination rule %s: unknown style %q", key, r.Style)
+		}
+	}
+	return c, nil
+}
+
+// Rule returns the configured rule for an operation, if any
+func (c Config) Rule(operationID, method, path string) *Rule {
+	if r, ok := c[operationID]; ok && operationID != "" {
+		return r
+	}
+	return c[strings.ToUpper(method)+" "+path]
+}
+
+// Request is the request for a page: query parameters to send to the same
+// operation, or a complete URL taken from a link
+type Request struct {
+	Params map[string]string
+	URL    string
+}
+
+var (
+	cursorFields = []string{"next_cursor", "nextCursor", "next_page_token", "nextPageToken", "next", "cursor", "after"}
+	cursorParams = []string{"cursor", "page_token", "pageToken", "after", "starting_after", "next", "token"}
+	containers   = []string{"", "meta", "pagination", "paging", "links", "page_info", "pageInfo"}
+	limitParams  = []string{"limit", "page_size", "pageSize", "per_page", "perPage", "size", "count"}
+)
+
+// Detect guesses the rule for a response when none is configured, returning
+// nil when the response doesn't look paginated
+func Detect(ep *model.Endpoint, header http.Header, body string) *Rule {
+	if link := Links(header)["next"]; link != "" {
+		return &Rule{Style: StyleLink}
+	}
+	var doc interface{}
+	if json.Unmarshal([]byte(body), &doc) == nil {
+		if path, value := nextField(doc); path != "" {
+			if s, ok := value.(string); ok && (strings.HasPrefix(s, "http") || strings.HasPrefix(s, "/")) {
+				return &Rule{Style: StyleLink, Next: path}
+			}
+			param := findParam(ep, cursorParams)
+			if param == "" {
+				param = "cursor"
+			}
+			return &Rule{Style: StyleCursor, Param: param, Next: path}
+		}
+	}
+	if param := findParam(ep, []string{"page", "page_number", "pageNumber"}); param != "" {
+		return &Rule{Style: StylePage, Param: param}
+	}
+	if param := findParam(ep, []string{"offset", "skip", "start"}); param != "" {
+		return &Rule{Style: StyleOffset, Param: param, Limit: findParam(ep, limitParams)}
+	}
+	return nil
+}
+
+// nextField finds a non-empty cursor or next field at the top level or in
+// a pagination container, returning its JSONPath
+func nextField(doc interface{}) (string, interface{}) {
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return "", nil
+	}
+	for _, container := range containers {
+		obj, prefix := root, "$"
+		if container != "" {
+			if obj, ok = root[container].(map[string]interface{}); !ok {
+				continue
+			}
+			prefix = "$." + container
+		}
+		for _, field := range cursorFields {
+			switch v := obj[field].(type) {
+			case string:
+				if v != "" {
+					return prefix + "." + field, v
+				}
+			case float64:
+				return prefix + "." + field, v
+			}
+		}
+	}
+	return "", nil
+}
+
+func findParam(ep *model.Endpoint, names []string) string {
+	if ep == nil {
+		return ""
+	}
+	for _, name := range names {
+		for _, p := range ep.Parameters {
+			if p.In == "query" && strings.EqualFold(p.Name, name) {
+				return p.Name
+			}
+		}
+	}
+	return ""
+}
+
+var linkPattern = regexp.MustCompile(`<([^>]*)>\s*((?:;\s*[^;,]+)*)`)
+var relPattern = regexp.MustCompile(`rel="?([^";]+)"?`)
+
+// Links parses RFC 8288 Link headers into URLs by relation
+func Links(header http.Header) map[string]string {
+	links := map[string]string{}
+	for _, value := range header.Values("Link") {
+		for _, m := range linkPattern.FindAllStringSubmatch(value, -1) {
+			if rel := relPattern.FindStringSubmatch(m[2]); rel != nil {
+				for _, r := range strings.Fields(rel[1]) {
+					links[strings.ToLower(r)] = m[1]
+				}
+			}
+		}
+	}
+	return links
+}
+
+// NextPage returns the request for the page after the one requested with params,
+// or false on the last page. Relative link URLs are resolved against
+// pageURL, the URL of the current page.
+func (r *Rule) NextPage(params map[string]string, pageURL string, header http.Header, body string) (*Request, bool) {
+	switch r.Style {
+	case StyleLink:
+		next := Links(header)["next"]
+		if r.Next != "" {
+			s, _ := r.value(body)
+			next = s
+		}
+		if next == "" {
+			return nil, false
+		}
+		if base, err := url.Parse(pageURL); err == nil && pageURL != "" {
+			if ref, err := base.Parse(next); err == nil {
+				next = ref.String()
+			}
+		}
+		return &Request{URL: next}, true
+	case StyleCursor:
+		cursor, ok := r.value(body)
+		if !ok || cursor == "" {
+			return nil, false
+		}
+		return &Request{Params: with(params, r.Param, cursor)}, true
+	case StylePage:
+		page := 1
+		if n, err := strconv.Atoi(params[r.Param]); err == nil {
+			page = n
+		}
+		if r.empty(body) {
+			return nil, false
+		}
+		return &Request{Params: with(params, r.Param, strconv.Itoa(page+1))}, true
+	case StyleOffset:
+		offset, _ := strconv.Atoi(params[r.Param])
+		size := len(r.PageItems(body))
+		if n, err := strconv.Atoi(params[r.Limit]); err == nil && r.Limit != "" {
+			if size < n {
+				return nil, false
+			}
+			size = n
+		}
+		if size == 0 {
+			return nil, false
+		}
+		return &Request{Params: with(params, r.Param, strconv.Itoa(offset+size))}, true
+	}
+	return nil, false
+}
+
+// value evaluates the Next expression, returning it as a string
+func (r *Rule) value(body string) (string, bool) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		return "", false
+	}
+	values, err := query.Eval(r.Next, doc)
+	if err != nil || len(values) == 0 || values[0] == nil {
+		return "", false
+	}
+	switch v := values[0].(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// empty reports whether a page has no items, which ends page-numbered lists
+func (r *Rule) empty(body string) bool {
+	var doc interface{}
+	if json.Unmarshal([]byte(body), &doc) != nil {
+		return true
+	}
+	_, ok := itemsOf(doc, r.Items)
+	return ok && len(r.PageItems(body)) == 0
+}
+
+// PageItems returns the items of a page: the Items expression, the body
+// when it is an array, or its first array member such as data or items
+func (r *Rule) PageItems(body string) []interface{} {
+	var doc interface{}
+	if json.Unmarshal([]byte(body), &doc) != nil {
+		return nil
+	}
+	items, _ := itemsOf(doc, r.Items)
+	return items
+}
+
+var itemFields = []string{"data", "items", "results", "records", "entries", "values", "content"}
+
+func itemsOf(doc interface{}, expr string) ([]interface{}, bool) {
+	if expr != "" {
+		values, err := query.Eval(expr, doc)
+		if err != nil || len(values) == 0 {
+			return nil, false
+		}
+		if arr, ok := values[0].([]interface{}); ok {
+			return arr, true
+		}
+		return values, true
+	}
+	switch v := doc.(type) {
+	case []interface{}:
+		return v, true
+	case map[string]interface{}:
+		for _, field := range itemFields {
+			if arr, ok := v[field].([]interface{}); ok {
+				return arr, true
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if arr, ok := v[k].([]interface{}); ok {
+				return arr, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func with(params map[string]string, key, value string) map[string]string {
+	out := make(map[string]string, len(params)+1)
+	for k, v := range params {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
+
+// Describe summarizes a rule for the Response pane title
+func (r *Rule) Describe() string {
+	switch r.Style {
+	case StyleLink:
+		if r.Next != "" {
+			return "next link in " + r.Next
+		}
+		return "Link header"
+	case StyleCursor:
+		return fmt.Sprintf("cursor %s from %s", r.Param, r.Next)
+	case StyleOffset:
+		return "offset " + r.Param
+	}
+	return "page " + r.Param
+}
@@ -0,0 +1,128 @@
+package paginate
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"org.subh/api-term/pkgs/api/model"
+)
+
+var listModels = &model.Endpoint{
+	Method: "GET",
+	Path:   "/models",
+	Parameters: []*model.Parameter{
+		{Name: "page", In: "query"},
+		{Name: "offset", In: "query"},
+		{Name: "limit", In: "query"},
+		{Name: "after", In: "query"},
+	},
+}
+
+func TestLinkHeader(t *testing.T) {
+	header := http.Header{}
+	header.Add("Link", `<https://api.example.com/models?page=3>; rel="next", </models?page=1>; rel="prev first"`)
+	links := Links(header)
+	if links["next"] != "https://api.example.com/models?page=3" || links["first"] != "/models?page=1" {
+		t.Errorf("unexpected links %v", links)
+	}
+
+	rule := Detect(listModels, header, `[]`)
+	if rule == nil || rule.Style != StyleLink {
+		t.Fatalf("expected the link style, got %+v", rule)
+	}
+	next, ok := rule.NextPage(nil, "https://api.example.com/models?page=2", header, `[]`)
+	if !ok || next.URL != "https://api.example.com/models?page=3" {
+		t.Errorf("unexpected next page %+v", next)
+	}
+	if _, ok := rule.NextPage(nil, "", http.Header{}, `[]`); ok {
+		t.Error("expected no page after the last one")
+	}
+}
+
+func TestCursorAndBodyLinks(t *testing.T) {
+	body := `{"data":[{"id":"a"},{"id":"b"}],"meta":{"next_cursor":"c2"}}`
+	rule := Detect(listModels, http.Header{}, body)
+	if rule == nil || rule.Style != StyleCursor || rule.Param != "after" || rule.Next != "$.meta.next_cursor" {
+		t.Fatalf("unexpected rule %+v", rule)
+	}
+	next, ok := rule.NextPage(map[string]string{"limit": "2"}, "", nil, body)
+	if !ok || next.Params["after"] != "c2" || next.Params["limit"] != "2" {
+		t.Errorf("unexpected next page %+v", next)
+	}
+	if _, ok := rule.NextPage(nil, "", nil, `{"data":[],"meta":{"next_cursor":null}}`); ok {
+		t.Error("expected the cursor to end the list")
+	}
+	if items := rule.PageItems(body); len(items) != 2 {
+		t.Errorf("unexpected items %v", items)
+	}
+
+	rule = Detect(listModels, http.Header{}, `{"results":[1],"next":"/models?page=2"}`)
+	next, ok = rule.NextPage(nil, "http://localhost:8080/models", nil, `{"results":[1],"next":"/models?page=2"}`)
+	if !ok || next.URL != "http://localhost:8080/models?page=2" {
+		t.Errorf("relative next link not resolved: %+v", next)
+	}
+}
+
+func TestPageAndOffsetParams(t *testing.T) {
+	rule := Detect(listModels, http.Header{}, `{"items":[1,2]}`)
+	if rule.Style != StylePage {
+		t.Fatalf("expected the page style, got %+v", rule)
+	}
+	next, ok := rule.NextPage(map[string]string{"page": "2"}, "", nil, `{"items":[1,2]}`)
+	if !ok || next.Params["page"] != "3" {
+		t.Errorf("unexpected next page %+v", next)
+	}
+	if _, ok := rule.NextPage(map[string]string{"page": "3"}, "", nil, `{"items":[]}`); ok {
+		t.Error("an empty page should end the list")
+	}
+
+	offset := &Rule{Style: StyleOffset, Param: "offset", Limit: "limit"}
+	next, ok = offset.NextPage(map[string]string{"offset": "10", "limit": "2"}, "", nil, `[1,2]`)
+	if !ok || next.Params["offset"] != "12" {
+		t.Errorf("unexpected next offset %+v", next)
+	}
+	if _, ok := offset.NextPage(map[string]string{"offset": "12", "limit": "2"}, "", nil, `[1]`); ok {
+		t.Error("a short page should end the list")
+	}
+
+	if rule := Detect(&model.Endpoint{Method: "GET", Path: "/x"}, http.Header{}, `{"a":1}`); rule != nil {
+		t.Errorf("expected no pagination, got %+v", rule)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pagination.yaml")
+	os.WriteFile(path, []byte(`
+listTrainingJobs:
+  style: cursor
+  param: page_token
+  next: .nextPageToken
+  items: .jobs
+"GET /models":
+  style: offset
+  param: offset
+  limit: limit
+`), 0o644)
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := c.Rule("listTrainingJobs", "GET", "/training-jobs"); r == nil || r.Param != "page_token" {
+		t.Errorf("rule by operationId not found: %+v", r)
+	}
+	if r := c.Rule("", "get", "/models"); r == nil || r.Style != StyleOffset {
+		t.Errorf("rule by method and path not found: %+v", r)
+	}
+	r := c.Rule("listTrainingJobs", "GET", "/training-jobs")
+	next, ok := r.NextPage(nil, "", nil, `{"jobs":[{"id":1}],"nextPageToken":"t2"}`)
+	if !ok || next.Params["page_token"] != "t2" || len(r.PageItems(`{"jobs":[{"id":1}]}`)) != 1 {
+		t.Errorf("configured rule not applied: %+v", next)
+	}
+
+	os.WriteFile(path, []byte("x:\n  style: cursor\n"), 0o644)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected an error for a cursor rule without a param")
+	}
+}
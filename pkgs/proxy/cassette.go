@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// redactedHeaders are replaced before an exchange is written to a cassette
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "X-Api-Key"}
+
+// RecordedRequest is the request half of an exchange
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the response half of an exchange
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Exchange is a single request/response pair seen by the proxy
+type Exchange struct {
+	// Operation is the matched spec operation ("METHOD /path/template"), empty if unmatched
+	Operation   string           `json:"operation,omitempty"`
+	OperationID string           `json:"operationId,omitempty"`
+	Request     RecordedRequest  `json:"request"`
+	Response    RecordedResponse `json:"response"`
+	RecordedAt  time.Time        `json:"recordedAt"`
+	Duration    time.Duration    `json:"durationNs"`
+}
+
+// Cassette is an ordered recording of exchanges against one target
+type Cassette struct {
+	Target    string      `json:"target"`
+	Exchanges []*Exchange `json:"exchanges"`
+}
+
+// Record appends an exchange with its credentials headers redacted
+func (c *Cassette) Record(ex *Exchange) {
+	ex.Request.Header = redactHeader(ex.Request.Header)
+	ex.Response.Header = redactHeader(ex.Response.Header)
+	c.Exchanges = append(c.Exchanges, ex)
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Save writes the cassette as indented JSON
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func redactHeader(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range redactedHeaders {
+		if out.Get(name) != "" {
+			out.Set(name, "REDACTED")
+		}
+	}
+	return out
+}
+
+// key identifies a request for replay matching
+func (r *RecordedRequest) key(withQuery bool) string {
+	k := strings.ToUpper(r.Method) + " " + r.Path
+	if withQuery && r.Query != "" {
+		k += "?" + r.Query
+	}
+	return k
+}
@@ -0,0 +1,150 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const testSpec = `openapi: 3.0.0
+info:
+  title: Sample API
+  version: 0.1.9
+paths:
+  /models/{model_id}:
+    get:
+      operationId: getModel
+      responses:
+        '200':
+          description: A model
+`
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	calls := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/models/m1" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		fmt.Fprintf(w, `{"path":%q,"call":%d}`, r.URL.Path, calls)
+	}))
+	defer upstream.Close()
+
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(testSpec))
+	if err != nil {
+		t.Fatalf("load spec: %v", err)
+	}
+	target, _ := url.Parse(upstream.URL)
+	cassettePath := filepath.Join(t.TempDir(), "cassette.json")
+
+	var seen []*Exchange
+	recorder := NewRecorder(target, doc, cassettePath)
+	recorder.OnExchange = func(ex *Exchange) { seen = append(seen, ex) }
+	proxyServer := httptest.NewServer(recorder)
+
+	get(t, proxyServer.URL+"/models/m1")
+	get(t, proxyServer.URL+"/models/m1")
+	if status, _ := get(t, proxyServer.URL+"/other"); status != http.StatusNotFound {
+		t.Errorf("expected proxied 404, got %d", status)
+	}
+	proxyServer.Close()
+
+	if len(seen) != 3 {
+		t.Fatalf("expected 3 recorded exchanges, got %d", len(seen))
+	}
+	if seen[0].Operation != "GET /models/{model_id}" || seen[0].OperationID != "getModel" {
+		t.Errorf("expected exchange matched to getModel, got %q %q", seen[0].Operation, seen[0].OperationID)
+	}
+	if seen[2].Operation != "" {
+		t.Errorf("expected unmatched exchange, got %q", seen[2].Operation)
+	}
+	if got := seen[0].Request.Header.Get("Authorization"); got != "REDACTED" {
+		t.Errorf("expected Authorization to be redacted, got %q", got)
+	}
+
+	cassette, err := LoadCassette(cassettePath)
+	if err != nil {
+		t.Fatalf("LoadCassette: %v", err)
+	}
+	replay := httptest.NewServer(NewReplayer(cassette))
+	defer replay.Close()
+
+	for _, want := range []string{
+		`{"path":"/models/m1","call":1}`,
+		`{"path":"/models/m1","call":2}`,
+		`{"path":"/models/m1","call":2}`,
+	} {
+		if status, body := get(t, replay.URL+"/models/m1"); status != http.StatusOK || body != want {
+			t.Errorf("expected replayed %s, got %d %s", want, status, body)
+		}
+	}
+	if status, _ := get(t, replay.URL+"/models/m2"); status != http.StatusNotFound {
+		t.Errorf("expected 404 for unrecorded request, got %d", status)
+	}
+	if calls != 3 {
+		t.Errorf("replay must not reach upstream, got %d upstream calls", calls)
+	}
+}
+
+func TestRecorderReportsErrors(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	target, _ := url.Parse(upstream.URL)
+	upstream.Close()
+
+	var errs []error
+	recorder := NewRecorder(target, nil, filepath.Join(t.TempDir(), "missing", "cassette.json"))
+	recorder.OnError = func(err error) { errs = append(errs, err) }
+	proxyServer := httptest.NewServer(recorder)
+	defer proxyServer.Close()
+
+	if status, _ := get(t, proxyServer.URL+"/models/m1"); status != http.StatusBadGateway {
+		t.Errorf("expected 502 for an unreachable target, got %d", status)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected the proxy and cassette errors, got %v", errs)
+	}
+	if !strings.HasPrefix(errs[0].Error(), "GET /models/m1: ") || !strings.HasPrefix(errs[1].Error(), "failed to save cassette: ") {
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
+
+func TestCassetteRecord(t *testing.T) {
+	cassette := &Cassette{}
+	cassette.Record(&Exchange{
+		Request:  RecordedRequest{Method: "GET", Path: "/models/{model_id}", Header: http.Header{"Authorization": {"Bearer secret"}}},
+		Response: RecordedResponse{Status: 200, Header: http.Header{"Set-Cookie": {"session=1"}}},
+	})
+	path := filepath.Join(t.TempDir(), "history.json")
+	if err := cassette.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := loaded.Exchanges[0]
+	if ex.Request.Header.Get("Authorization") != "REDACTED" || ex.Response.Header.Get("Set-Cookie") != "REDACTED" {
+		t.Errorf("expected credentials to be redacted, got %v %v", ex.Request.Header, ex.Response.Header)
+	}
+}
@@ -0,0 +1,147 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"org.subh/api-term/pkgs/api/parser"
+)
+
+// Recorder is a reverse proxy that records every exchange into a cassette
+type Recorder struct {
+	Target   *url.URL
+	Cassette *Cassette
+	// Doc is used to match exchanges to spec operations; may be nil
+	Doc *openapi3.T
+	// Path is where the cassette is saved after each exchange; empty disables saving
+	Path string
+	// OnExchange is called after each exchange is recorded
+	OnExchange func(*Exchange)
+	// OnError is called when the target can't be reached or the cassette
+	// can't be saved; errors are logged if nil
+	OnError func(error)
+
+	proxy *httputil.ReverseProxy
+	mu    sync.Mutex
+}
+
+// NewRecorder creates a Recorder forwarding to target
+func NewRecorder(target *url.URL, doc *openapi3.T, path string) *Recorder {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		r.Host = target.Host
+		// let the transport negotiate gzip so recorded bodies are stored decoded
+		r.Header.Del("Accept-Encoding")
+	}
+	rec := &Recorder{
+		Target:   target,
+		Cassette: &Cassette{Target: target.String()},
+		Doc:      doc,
+		Path:     path,
+		proxy:    proxy,
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		rec.reportError(fmt.Errorf("%s %s: %v", r.Method, r.URL.Path, err))
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	return rec
+}
+
+// ServeHTTP forwards the request to the target and records the exchange
+func (rec *Recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reqBody, _ := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(reqBody))
+
+	ex := &Exchange{
+		Request: RecordedRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.Query().Encode(),
+			Header: redactHeader(r.Header),
+			Body:   string(reqBody),
+		},
+		RecordedAt: time.Now(),
+	}
+	ex.Operation, ex.OperationID = MatchOperation(rec.Doc, r.Method, r.URL.Path)
+
+	cw := &captureWriter{ResponseWriter: w, status: http.StatusOK}
+	rec.proxy.ServeHTTP(cw, r)
+
+	ex.Duration = time.Since(ex.RecordedAt)
+	ex.Response = RecordedResponse{
+		Status: cw.status,
+		Header: redactHeader(w.Header()),
+		Body:   cw.body.String(),
+	}
+	rec.record(ex)
+}
+
+func (rec *Recorder) record(ex *Exchange) {
+	rec.mu.Lock()
+	rec.Cassette.Exchanges = append(rec.Cassette.Exchanges, ex)
+	var err error
+	if rec.Path != "" {
+		err = rec.Cassette.Save(rec.Path)
+	}
+	rec.mu.Unlock()
+
+	if err != nil {
+		rec.reportError(fmt.Errorf("failed to save cassette: %v", err))
+	}
+	if rec.OnExchange != nil {
+		rec.OnExchange(ex)
+	}
+}
+
+func (rec *Recorder) reportError(err error) {
+	if rec.OnError != nil {
+		rec.OnError(err)
+	} else {
+		log.Print(err)
+	}
+}
+
+// MatchOperation returns the "METHOD /template" label and operationId of the
+// spec operation serving a request, or empty strings if none matches
+func MatchOperation(doc *openapi3.T, method, path string) (string, string) {
+	op, template := parser.FindOperation(doc, method, path)
+	if op == nil {
+		return "", ""
+	}
+	return strings.ToUpper(method) + " " + template, op.OperationID
+}
+
+// captureWriter tees the response status and body while passing them through
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (cw *captureWriter) WriteHeader(status int) {
+	cw.status = status
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *captureWriter) Write(p []byte) (int, error) {
+	cw.body.Write(p)
+	return cw.ResponseWriter.Write(p)
+}
+
+func (cw *captureWriter) Flush() {
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Replayer is a stub server that answers requests from a cassette. Requests
+// are matched on method, path and query first, then on method and path alone.
+// Repeated requests replay the recorded exchanges in order and then stick to
+// the last one.
+type Replayer struct {
+	Cassette *Cassette
+	// OnExchange is called with the replayed exchange for every matched request
+	OnExchange func(*Exchange)
+
+	mu       sync.Mutex
+	byKey    map[string][]*Exchange
+	cursors  map[string]int
+	fallback map[string][]*Exchange
+}
+
+// NewReplayer creates a Replayer for the cassette
+func NewReplayer(c *Cassette) *Replayer {
+	r := &Replayer{
+		Cassette: c,
+		byKey:    make(map[string][]*Exchange),
+		cursors:  make(map[string]int),
+		fallback: make(map[string][]*Exchange),
+	}
+	for _, ex := range c.Exchanges {
+		full := ex.Request.key(true)
+		r.byKey[full] = append(r.byKey[full], ex)
+		path := ex.Request.key(false)
+		r.fallback[path] = append(r.fallback[path], ex)
+	}
+	return r
+}
+
+// ServeHTTP writes the recorded response matching the request, or a 404
+func (rp *Replayer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := RecordedRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query().Encode()}
+	ex := rp.next(req)
+	if ex == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "no recorded exchange for " + req.key(true)})
+		return
+	}
+
+	for name, values := range ex.Response.Header {
+		if name == "Content-Length" {
+			continue
+		}
+		for _, v := range values {
+			w.Header().Add(name, v)
+		}
+	}
+	w.WriteHeader(ex.Response.Status)
+	w.Write([]byte(ex.Response.Body))
+
+	if rp.OnExchange != nil {
+		replayed := *ex
+		replayed.RecordedAt = time.Now()
+		rp.OnExchange(&replayed)
+	}
+}
+
+func (rp *Replayer) next(req RecordedRequest) *Exchange {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	for _, table := range []struct {
+		cursorKey string
+		key       string
+		exchanges map[string][]*Exchange
+	}{
+		{"full " + req.key(true), req.key(true), rp.byKey},
+		{"path " + req.key(false), req.key(false), rp.fallback},
+	} {
+		candidates := table.exchanges[table.key]
+		if len(candidates) == 0 {
+			continue
+		}
+		cursor := rp.cursors[table.cursorKey]
+		if cursor >= len(candidates) {
+			cursor = len(candidates) - 1
+		}
+		rp.cursors[table.cursorKey] = cursor + 1
+		return candidates[cursor]
+	}
+	return nil
+}
@@ -0,0 +1,821 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// node is one piece of a compiled jq expression. eval returns every value
+// the expression produces for a single input.
+type node interface {
+	eval(in interface{}) ([]interface{}, error)
+}
+
+type identityNode struct{}
+
+func (identityNode) eval(in interface{}) ([]interface{}, error) {
+	return []interface{}{in}, nil
+}
+
+type literalNode struct{ value interface{} }
+
+func (n literalNode) eval(interface{}) ([]interface{}, error) {
+	return []interface{}{n.value}, nil
+}
+
+type fieldNode struct{ name string }
+
+func (n fieldNode) eval(in interface{}) ([]interface{}, error) {
+	switch v := in.(type) {
+	case nil:
+		return []interface{}{nil}, nil
+	case map[string]interface{}:
+		return []interface{}{v[n.name]}, nil
+	}
+	return nil, fmt.Errorf("cannot index %s with %q", typeName(in), n.name)
+}
+
+type indexNode struct{ index node }
+
+func (n indexNode) eval(in interface{}) ([]interface{}, error) {
+	keys, err := n.index.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, key := range keys {
+		switch k := key.(type) {
+		case string:
+			v, err := fieldNode{k}.eval(in)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v...)
+		case float64:
+			switch v := in.(type) {
+			case nil:
+				out = append(out, nil)
+			case []interface{}:
+				i := int(k)
+				if i < 0 {
+					i += len(v)
+				}
+				if i < 0 || i >= len(v) {
+					out = append(out, nil)
+				} else {
+					out = append(out, v[i])
+				}
+			default:
+				return nil, fmt.Errorf("cannot index %s with a number", typeName(in))
+			}
+		default:
+			return nil, fmt.Errorf("cannot index %s with %s", typeName(in), typeName(key))
+		}
+	}
+	return out, nil
+}
+
+type sliceNode struct{ from, to node }
+
+func (n sliceNode) eval(in interface{}) ([]interface{}, error) {
+	if in == nil {
+		return []interface{}{nil}, nil
+	}
+	length := 0
+	switch v := in.(type) {
+	case []interface{}:
+		length = len(v)
+	case string:
+		length = len(v)
+	default:
+		return nil, fmt.Errorf("cannot slice %s", typeName(in))
+	}
+	bound := func(b node, def int) (int, error) {
+		if b == nil {
+			return def, nil
+		}
+		vals, err := b.eval(in)
+		if err != nil {
+			return 0, err
+		}
+		if len(vals) != 1 {
+			return 0, fmt.Errorf("slice bounds must be single values")
+		}
+		f, ok := vals[0].(float64)
+		if !ok {
+			return 0, fmt.Errorf("slice bounds must be numbers")
+		}
+		i := int(f)
+		if i < 0 {
+			i += length
+		}
+		return min(max(i, 0), length), nil
+	}
+	from, err := bound(n.from, 0)
+	if err != nil {
+		return nil, err
+	}
+	to, err := bound(n.to, length)
+	if err != nil {
+		return nil, err
+	}
+	to = max(to, from)
+	if s, ok := in.(string); ok {
+		return []interface{}{s[from:to]}, nil
+	}
+	return []interface{}{in.([]interface{})[from:to]}, nil
+}
+
+type iterateNode struct{}
+
+func (iterateNode) eval(in interface{}) ([]interface{}, error) {
+	switch v := in.(type) {
+	case []interface{}:
+		return v, nil
+	case map[string]interface{}:
+		return objectValues(v), nil
+	}
+	return nil, fmt.Errorf("cannot iterate over %s", typeName(in))
+}
+
+type recurseNode struct{}
+
+func (recurseNode) eval(in interface{}) ([]interface{}, error) {
+	var out []interface{}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		out = append(out, v)
+		switch c := v.(type) {
+		case []interface{}:
+			for _, item := range c {
+				walk(item)
+			}
+		case map[string]interface{}:
+			for _, item := range objectValues(c) {
+				walk(item)
+			}
+		}
+	}
+	walk(in)
+	return out, nil
+}
+
+// tryNode is expr? and drops errors
+type tryNode struct{ body node }
+
+func (n tryNode) eval(in interface{}) ([]interface{}, error) {
+	out, err := n.body.eval(in)
+	if err != nil {
+		return nil, nil
+	}
+	return out, nil
+}
+
+type pipeNode struct{ left, right node }
+
+func (n pipeNode) eval(in interface{}) ([]interface{}, error) {
+	left, err := n.left.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, v := range left {
+		right, err := n.right.eval(v)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, right...)
+	}
+	return out, nil
+}
+
+type commaNode struct{ left, right node }
+
+func (n commaNode) eval(in interface{}) ([]interface{}, error) {
+	left, err := n.left.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+// altNode is a // b: the truthy outputs of a, or else the outputs of b
+type altNode struct{ left, right node }
+
+func (n altNode) eval(in interface{}) ([]interface{}, error) {
+	left, err := n.left.eval(in)
+	var out []interface{}
+	if err == nil {
+		for _, v := range left {
+			if truthy(v) {
+				out = append(out, v)
+			}
+		}
+	}
+	if len(out) > 0 {
+		return out, nil
+	}
+	return n.right.eval(in)
+}
+
+type logicNode struct {
+	op          string
+	left, right node
+}
+
+func (n logicNode) eval(in interface{}) ([]interface{}, error) {
+	left, err := n.left.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, l := range left {
+		if n.op == "and" && !truthy(l) || n.op == "or" && truthy(l) {
+			out = append(out, n.op == "or")
+			continue
+		}
+		right, err := n.right.eval(in)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range right {
+			out = append(out, truthy(r))
+		}
+	}
+	return out, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n binaryNode) eval(in interface{}) ([]interface{}, error) {
+	right, err := n.right.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	left, err := n.left.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, r := range right {
+		for _, l := range left {
+			v, err := binary(n.op, l, r)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+func binary(op string, l, r interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return compare(l, r) == 0, nil
+	case "!=":
+		return compare(l, r) != 0, nil
+	case "<":
+		return compare(l, r) < 0, nil
+	case "<=":
+		return compare(l, r) <= 0, nil
+	case ">":
+		return compare(l, r) > 0, nil
+	case ">=":
+		return compare(l, r) >= 0, nil
+	}
+	if op == "+" {
+		if l == nil {
+			return r, nil
+		}
+		if r == nil {
+			return l, nil
+		}
+	}
+	switch lv := l.(type) {
+	case float64:
+		if rv, ok := r.(float64); ok {
+			switch op {
+			case "+":
+				return lv + rv, nil
+			case "-":
+				return lv - rv, nil
+			case "*":
+				return lv * rv, nil
+			case "/":
+				if rv == 0 {
+					return nil, fmt.Errorf("division by zero")
+				}
+				return lv / rv, nil
+			case "%":
+				if int(rv) == 0 {
+					return nil, fmt.Errorf("division by zero")
+				}
+				return float64(int(lv) % int(rv)), nil
+			}
+		}
+	case string:
+		if rv, ok := r.(string); ok && op == "+" {
+			return lv + rv, nil
+		}
+	case []interface{}:
+		if rv, ok := r.([]interface{}); ok {
+			switch op {
+			case "+":
+				return append(append([]interface{}{}, lv...), rv...), nil
+			case "-":
+				var out []interface{}
+				for _, item := range lv {
+					if !contains(rv, item) {
+						out = append(out, item)
+					}
+				}
+				return orEmpty(out), nil
+			}
+		}
+	case map[string]interface{}:
+		if rv, ok := r.(map[string]interface{}); ok && (op == "+" || op == "*") {
+			out := make(map[string]interface{}, len(lv)+len(rv))
+			for k, v := range lv {
+				out[k] = v
+			}
+			for k, v := range rv {
+				out[k] = v
+			}
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("%s and %s cannot be combined with %s", typeName(l), typeName(r), op)
+}
+
+type arrayNode struct{ body node }
+
+func (n arrayNode) eval(in interface{}) ([]interface{}, error) {
+	if n.body == nil {
+		return []interface{}{[]interface{}{}}, nil
+	}
+	items, err := n.body.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{orEmpty(items)}, nil
+}
+
+type objectEntry struct {
+	key, value node
+}
+
+type objectNode struct{ entries []objectEntry }
+
+// eval builds every combination when keys or values produce several outputs,
+// like jq does
+func (n objectNode) eval(in interface{}) ([]interface{}, error) {
+	results := []map[string]interface{}{{}}
+	for _, entry := range n.entries {
+		keys, err := entry.key.eval(in)
+		if err != nil {
+			return nil, err
+		}
+		values, err := entry.value.eval(in)
+		if err != nil {
+			return nil, err
+		}
+		var next []map[string]interface{}
+		for _, obj := range results {
+			for _, k := range keys {
+				key, ok := k.(string)
+				if !ok {
+					return nil, fmt.Errorf("object keys must be strings, got %s", typeName(k))
+				}
+				for _, v := range values {
+					copied := make(map[string]interface{}, len(obj)+1)
+					for ck, cv := range obj {
+						copied[ck] = cv
+					}
+					copied[key] = v
+					next = append(next, copied)
+				}
+			}
+		}
+		results = next
+	}
+	out := make([]interface{}, len(results))
+	for i, r := range results {
+		out[i] = r
+	}
+	return out, nil
+}
+
+type funcNode struct {
+	name string
+	args []node
+}
+
+type function struct {
+	args int
+	fn   func(in interface{}, args []node) ([]interface{}, error)
+}
+
+var functions map[string]function
+
+func init() {
+	functions = map[string]function{
+		"not":      {0, one(func(in interface{}) (interface{}, error) { return !truthy(in), nil })},
+		"length":   {0, one(length)},
+		"keys":     {0, one(keys)},
+		"values":   {0, values},
+		"type":     {0, one(func(in interface{}) (interface{}, error) { return typeName(in), nil })},
+		"add":      {0, one(add)},
+		"reverse":  {0, one(reverse)},
+		"sort":     {0, one(func(in interface{}) (interface{}, error) { return sortBy(in, nil) })},
+		"unique":   {0, one(unique)},
+		"first":    {0, one(func(in interface{}) (interface{}, error) { return indexNode{literalNode{float64(0)}}.first(in) })},
+		"last":     {0, one(func(in interface{}) (interface{}, error) { return indexNode{literalNode{float64(-1)}}.first(in) })},
+		"tostring": {0, one(tostring)},
+		"tonumber": {0, one(tonumber)},
+		"empty":    {0, func(interface{}, []node) ([]interface{}, error) { return nil, nil }},
+		"select":   {1, selectFn},
+		"map":      {1, mapFn},
+		"sort_by":  {1, func(in interface{}, args []node) ([]interface{}, error) { return single(sortBy(in, args[0])) }},
+		"has":      {1, hasFn},
+		"join":     {1, joinFn},
+	}
+}
+
+func (n funcNode) eval(in interface{}) ([]interface{}, error) {
+	return functions[n.name].fn(in, n.args)
+}
+
+// one adapts a function with a single output
+func one(fn func(interface{}) (interface{}, error)) func(interface{}, []node) ([]interface{}, error) {
+	return func(in interface{}, _ []node) ([]interface{}, error) {
+		return single(fn(in))
+	}
+}
+
+func single(v interface{}, err error) ([]interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{v}, nil
+}
+
+func (n indexNode) first(in interface{}) (interface{}, error) {
+	out, err := n.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+func length(in interface{}) (interface{}, error) {
+	switch v := in.(type) {
+	case nil:
+		return float64(0), nil
+	case bool:
+		return nil, fmt.Errorf("boolean has no length")
+	case float64:
+		return math.Abs(v), nil
+	case string:
+		return float64(len([]rune(v))), nil
+	case []interface{}:
+		return float64(len(v)), nil
+	case map[string]interface{}:
+		return float64(len(v)), nil
+	}
+	return nil, fmt.Errorf("%s has no length", typeName(in))
+}
+
+func keys(in interface{}) (interface{}, error) {
+	switch v := in.(type) {
+	case map[string]interface{}:
+		out := make([]interface{}, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			out = append(out, k)
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			out[i] = float64(i)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("%s has no keys", typeName(in))
+}
+
+// values is select(. != null)
+func values(in interface{}, _ []node) ([]interface{}, error) {
+	if in == nil {
+		return nil, nil
+	}
+	return []interface{}{in}, nil
+}
+
+func add(in interface{}) (interface{}, error) {
+	items, err := iterateNode{}.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var sum interface{}
+	for _, item := range items {
+		if sum, err = binary("+", sum, item); err != nil {
+			return nil, err
+		}
+	}
+	return sum, nil
+}
+
+func reverse(in interface{}) (interface{}, error) {
+	switch v := in.(type) {
+	case nil:
+		return []interface{}{}, nil
+	case string:
+		r := []rune(v)
+		for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+			r[i], r[j] = r[j], r[i]
+		}
+		return string(r), nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[len(v)-1-i] = item
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("cannot reverse %s", typeName(in))
+}
+
+func sortBy(in interface{}, by node) (interface{}, error) {
+	arr, ok := in.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot sort %s", typeName(in))
+	}
+	sortKeys := make([]interface{}, len(arr))
+	for i, item := range arr {
+		sortKeys[i] = item
+		if by != nil {
+			k, err := by.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			sortKeys[i] = k
+		}
+	}
+	idx := make([]int, len(arr))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		return compare(sortKeys[idx[a]], sortKeys[idx[b]]) < 0
+	})
+	out := make([]interface{}, len(arr))
+	for i, j := range idx {
+		out[i] = arr[j]
+	}
+	return out, nil
+}
+
+func unique(in interface{}) (interface{}, error) {
+	sorted, err := sortBy(in, nil)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, item := range sorted.([]interface{}) {
+		if len(out) == 0 || compare(out[len(out)-1], item) != 0 {
+			out = append(out, item)
+		}
+	}
+	return orEmpty(out), nil
+}
+
+func tostring(in interface{}) (interface{}, error) {
+	if s, ok := in.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(in)
+	return string(data), err
+}
+
+func tonumber(in interface{}) (interface{}, error) {
+	switch v := in.(type) {
+	case float64:
+		return v, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %q as a number", v)
+		}
+		return f, nil
+	}
+	return nil, fmt.Errorf("%s cannot be parsed as a number", typeName(in))
+}
+
+func selectFn(in interface{}, args []node) ([]interface{}, error) {
+	conds, err := args[0].eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, c := range conds {
+		if truthy(c) {
+			out = append(out, in)
+		}
+	}
+	return out, nil
+}
+
+func mapFn(in interface{}, args []node) ([]interface{}, error) {
+	return arrayNode{pipeNode{iterateNode{}, args[0]}}.eval(in)
+}
+
+func hasFn(in interface{}, args []node) ([]interface{}, error) {
+	keys, err := args[0].eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, k := range keys {
+		switch v := in.(type) {
+		case map[string]interface{}:
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("cannot check whether an object has a %s key", typeName(k))
+			}
+			_, found := v[key]
+			out = append(out, found)
+		case []interface{}:
+			i, ok := k.(float64)
+			if !ok {
+				return nil, fmt.Errorf("cannot check whether an array has a %s key", typeName(k))
+			}
+			out = append(out, i >= 0 && int(i) < len(v))
+		default:
+			return nil, fmt.Errorf("cannot check whether %s has a key", typeName(in))
+		}
+	}
+	return out, nil
+}
+
+func joinFn(in interface{}, args []node) ([]interface{}, error) {
+	seps, err := args[0].eval(in)
+	if err != nil {
+		return nil, err
+	}
+	items, err := iterateNode{}.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, s := range seps {
+		sep, ok := s.(string)
+		if !ok {
+			return nil, fmt.Errorf("join separator must be a string")
+		}
+		parts := make([]string, len(items))
+		for i, item := range items {
+			switch v := item.(type) {
+			case nil:
+			case string:
+				parts[i] = v
+			case float64, bool:
+				parts[i], _ = tostringValue(v)
+			default:
+				return nil, fmt.Errorf("cannot join %s", typeName(item))
+			}
+		}
+		out = append(out, strings.Join(parts, sep))
+	}
+	return out, nil
+}
+
+func tostringValue(v interface{}) (string, error) {
+	s, err := tostring(v)
+	if err != nil {
+		return "", err
+	}
+	return s.(string), nil
+}
+
+func truthy(v interface{}) bool {
+	return v != nil && v != false
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// typeOrder ranks types the way jq sorts them
+var typeOrder = map[string]int{"null": 0, "boolean": 1, "number": 2, "string": 3, "array": 4, "object": 5}
+
+func compare(a, b interface{}) int {
+	ta, tb := typeName(a), typeName(b)
+	if ta != tb {
+		return typeOrder[ta] - typeOrder[tb]
+	}
+	switch av := a.(type) {
+	case bool:
+		bv := b.(bool)
+		if av == bv {
+			return 0
+		}
+		if !av {
+			return -1
+		}
+		return 1
+	case float64:
+		bv := b.(float64)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(av, b.(string))
+	case []interface{}:
+		bv := b.([]interface{})
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if c := compare(av[i], bv[i]); c != 0 {
+				return c
+			}
+		}
+		return len(av) - len(bv)
+	case map[string]interface{}:
+		bv := b.(map[string]interface{})
+		ka, _ := keys(av)
+		kb, _ := keys(bv)
+		if c := compare(ka, kb); c != 0 {
+			return c
+		}
+		for _, k := range sortedKeys(av) {
+			if c := compare(av[k], bv[k]); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+func contains(items []interface{}, v interface{}) bool {
+	for _, item := range items {
+		if compare(item, v) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// objectValues returns the member values ordered by key, since decoded maps
+// have no order of their own
+func objectValues(m map[string]interface{}) []interface{} {
+	out := make([]interface{}, 0, len(m))
+	for _, k := range sortedKeys(m) {
+		out = append(out, m[k])
+	}
+	return out
+}
+
+// orEmpty keeps an empty result an empty JSON array rather than null
+func orEmpty(items []interface{}) []interface{} {
+	if items == nil {
+		return []interface{}{}
+	}
+	return items
+}
@@ -0,0 +1,137 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokDot
+	tokRecurse
+	tokField
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+type lexer struct {
+	src    string
+	pos    int
+	tokens []token
+	err    error
+}
+
+func newLexer(src string) *lexer {
+	l := &lexer{src: src}
+	for l.err == nil {
+		t := l.next()
+		l.tokens = append(l.tokens, t)
+		if t.kind == tokEOF {
+			break
+		}
+	}
+	return l
+}
+
+// twoCharOps are matched before single characters
+var twoCharOps = []string{"==", "!=", "<=", ">=", "//"}
+
+func (l *lexer) next() token {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: start}
+	}
+	c := l.src[l.pos]
+	switch {
+	case c == '.':
+		l.pos++
+		if l.pos < len(l.src) && l.src[l.pos] == '.' {
+			l.pos++
+			return token{kind: tokRecurse, text: "..", pos: start}
+		}
+		if l.pos < len(l.src) && isIdentStart(l.src[l.pos]) {
+			name := l.ident()
+			return token{kind: tokField, text: name, pos: start}
+		}
+		return token{kind: tokDot, text: ".", pos: start}
+	case c == '"':
+		return l.str()
+	case c >= '0' && c <= '9':
+		for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || l.src[l.pos] == '.' || l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+			l.pos++
+		}
+		text := l.src[start:l.pos]
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			l.err = fmt.Errorf("invalid number %q at %d", text, start)
+		}
+		return token{kind: tokNumber, text: text, num: n, pos: start}
+	case isIdentStart(c):
+		return token{kind: tokIdent, text: l.ident(), pos: start}
+	}
+	for _, op := range twoCharOps {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokOp, text: op, pos: start}
+		}
+	}
+	if strings.IndexByte("|,()[]{}:;?<>+-*/%", c) >= 0 {
+		l.pos++
+		return token{kind: tokOp, text: string(c), pos: start}
+	}
+	l.err = fmt.Errorf("unexpected %q at %d", c, start)
+	return token{kind: tokEOF, pos: start}
+}
+
+func (l *lexer) ident() string {
+	start := l.pos
+	for l.pos < len(l.src) && (isIdentStart(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+		l.pos++
+	}
+	return l.src[start:l.pos]
+}
+
+func (l *lexer) str() token {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.src) && l.src[l.pos] != '"' {
+		if l.src[l.pos] == '\\' {
+			l.pos++
+		}
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		l.err = fmt.Errorf("unterminated string at %d", start)
+		return token{kind: tokEOF, pos: start}
+	}
+	l.pos++
+	s, err := strconv.Unquote(l.src[start:l.pos])
+	if err != nil {
+		l.err = fmt.Errorf("invalid string at %d: %v", start, err)
+	}
+	return token{kind: tokString, text: s, pos: start}
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
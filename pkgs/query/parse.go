@@ -0,0 +1,397 @@
+package query
+
+import "fmt"
+
+type parser struct {
+	lex *lexer
+	pos int
+}
+
+func (p *parser) peek() token {
+	return p.lex.tokens[p.pos]
+}
+
+func (p *parser) advance() token {
+	t := p.lex.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == op
+}
+
+func (p *parser) isIdent(name string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && t.text == name
+}
+
+func (p *parser) expect(op string) error {
+	if !p.isOp(op) {
+		return p.errorf("expected %q", op)
+	}
+	p.advance()
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	t := p.peek()
+	where := "end of expression"
+	if t.kind != tokEOF {
+		where = fmt.Sprintf("%q at %d", t.text, t.pos)
+	}
+	return fmt.Errorf(format+" near "+where, args...)
+}
+
+func (p *parser) parse() (node, error) {
+	if p.lex.err != nil {
+		return nil, p.lex.err
+	}
+	n, err := p.pipe()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("unexpected token")
+	}
+	return n, nil
+}
+
+func (p *parser) pipe() (node, error) {
+	left, err := p.comma()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("|") {
+		p.advance()
+		right, err := p.pipe()
+		if err != nil {
+			return nil, err
+		}
+		return pipeNode{left, right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) comma() (node, error) {
+	left, err := p.alternative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(",") {
+		p.advance()
+		right, err := p.alternative()
+		if err != nil {
+			return nil, err
+		}
+		left = commaNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) alternative() (node, error) {
+	left, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("//") {
+		p.advance()
+		right, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		left = altNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.isIdent("or") {
+		p.advance()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{"or", left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.compare()
+	if err != nil {
+		return nil, err
+	}
+	for p.isIdent("and") {
+		p.advance()
+		right, err := p.compare()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{"and", left, right}
+	}
+	return left, nil
+}
+
+var compareOps = []string{"==", "!=", "<", "<=", ">", ">="}
+
+func (p *parser) compare() (node, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range compareOps {
+		if p.isOp(op) {
+			p.advance()
+			right, err := p.additive()
+			if err != nil {
+				return nil, err
+			}
+			return binaryNode{op, left, right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) additive() (node, error) {
+	left, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		op := p.advance().text
+		right, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op, left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) multiplicative() (node, error) {
+	left, err := p.postfix()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*") || p.isOp("/") || p.isOp("%") {
+		op := p.advance().text
+		right, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op, left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) postfix() (node, error) {
+	n, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokField:
+			p.advance()
+			n = pipeNode{n, fieldNode{t.text}}
+		case t.kind == tokDot && p.lex.tokens[p.pos+1].kind == tokString:
+			p.advance()
+			n = pipeNode{n, fieldNode{p.advance().text}}
+		case t.kind == tokDot && p.lex.tokens[p.pos+1].kind == tokOp && p.lex.tokens[p.pos+1].text == "[":
+			p.advance()
+		case p.isOp("["):
+			suffix, err := p.bracket()
+			if err != nil {
+				return nil, err
+			}
+			n = pipeNode{n, suffix}
+		case p.isOp("?"):
+			p.advance()
+			n = tryNode{n}
+		default:
+			return n, nil
+		}
+	}
+}
+
+// bracket parses [], [expr] and [from:to] after a value
+func (p *parser) bracket() (node, error) {
+	p.advance()
+	if p.isOp("]") {
+		p.advance()
+		return iterateNode{}, nil
+	}
+	var from, to node
+	var err error
+	if !p.isOp(":") {
+		if from, err = p.pipe(); err != nil {
+			return nil, err
+		}
+	}
+	if p.isOp(":") {
+		p.advance()
+		if !p.isOp("]") {
+			if to, err = p.pipe(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return sliceNode{from, to}, nil
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return indexNode{from}, nil
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokDot:
+		p.advance()
+		if p.peek().kind == tokString {
+			return fieldNode{p.advance().text}, nil
+		}
+		return identityNode{}, nil
+	case tokRecurse:
+		p.advance()
+		return recurseNode{}, nil
+	case tokField:
+		p.advance()
+		return fieldNode{t.text}, nil
+	case tokNumber:
+		p.advance()
+		return literalNode{t.num}, nil
+	case tokString:
+		p.advance()
+		return literalNode{t.text}, nil
+	case tokIdent:
+		return p.call()
+	case tokOp:
+		switch t.text {
+		case "(":
+			p.advance()
+			n, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		case "[":
+			p.advance()
+			if p.isOp("]") {
+				p.advance()
+				return arrayNode{}, nil
+			}
+			n, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			return arrayNode{n}, p.expect("]")
+		case "{":
+			return p.object()
+		case "-":
+			p.advance()
+			n, err := p.postfix()
+			if err != nil {
+				return nil, err
+			}
+			return binaryNode{"-", literalNode{float64(0)}, n}, nil
+		}
+	}
+	return nil, p.errorf("unexpected token")
+}
+
+func (p *parser) call() (node, error) {
+	name := p.advance().text
+	switch name {
+	case "true":
+		return literalNode{true}, nil
+	case "false":
+		return literalNode{false}, nil
+	case "null":
+		return literalNode{nil}, nil
+	case "not":
+		return funcNode{name: name}, nil
+	}
+	var args []node
+	if p.isOp("(") {
+		p.advance()
+		for {
+			arg, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if !p.isOp(";") {
+				break
+			}
+			p.advance()
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	fn, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	if len(args) != fn.args {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", name, fn.args, len(args))
+	}
+	return funcNode{name: name, args: args}, nil
+}
+
+// object parses {a, b: .c, "d": 1, (.k): .v}
+func (p *parser) object() (node, error) {
+	p.advance()
+	var entries []objectEntry
+	for !p.isOp("}") {
+		var entry objectEntry
+		t := p.peek()
+		switch {
+		case t.kind == tokIdent || t.kind == tokString:
+			p.advance()
+			entry.key = literalNode{t.text}
+			entry.value = fieldNode{t.text}
+		case p.isOp("("):
+			p.advance()
+			key, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			entry.key = key
+		default:
+			return nil, p.errorf("expected an object key")
+		}
+		if p.isOp(":") {
+			p.advance()
+			value, err := p.alternative()
+			if err != nil {
+				return nil, err
+			}
+			entry.value = value
+		} else if entry.value == nil {
+			return nil, p.errorf("expected \":\"")
+		}
+		entries = append(entries, entry)
+		if !p.isOp(",") {
+			break
+		}
+		p.advance()
+	}
+	return objectNode{entries}, p.expect("}")
+}
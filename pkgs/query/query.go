@@ -0,0 +1,84 @@
+// Package query filters decoded JSON with JSONPath or a jq-like language.
+//
+// Expressions starting with $ are JSONPath (see package jsonpath). Anything
+// else is a subset of jq: paths (.a.b, .[0], .[], .[1:3], .., .a[]?), pipes,
+// commas, //, array and object construction, literals, arithmetic,
+// comparisons, and/or/not, and the functions length, keys, values, map,
+// select, first, last, sort, sort_by, unique, reverse, type, add, has, join,
+// tostring, tonumber and empty.
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"org.subh/api-term/pkgs/jsonpath"
+)
+
+// Query is a compiled expression
+type Query struct {
+	expr string
+	jq   node
+}
+
+// Compile parses expr
+func Compile(expr string) (*Query, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		expr = "."
+	}
+	q := &Query{expr: expr}
+	if strings.HasPrefix(expr, "$") {
+		if _, err := jsonpath.Eval(expr, nil); err != nil {
+			return nil, err
+		}
+		return q, nil
+	}
+	p := &parser{lex: newLexer(expr)}
+	n, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	q.jq = n
+	return q, nil
+}
+
+// Run applies the query to doc and returns every output value
+func (q *Query) Run(doc interface{}) ([]interface{}, error) {
+	if q.jq == nil {
+		return jsonpath.Eval(q.expr, doc)
+	}
+	return q.jq.eval(doc)
+}
+
+// Eval compiles and runs expr against doc
+func Eval(expr string, doc interface{}) ([]interface{}, error) {
+	q, err := Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return q.Run(doc)
+}
+
+// Apply runs expr on a JSON body and returns the results as indented JSON,
+// one value after another like jq prints them
+func Apply(expr string, body []byte) (string, error) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "", fmt.Errorf("response is not JSON: %v", err)
+	}
+	results, err := Eval(expr, doc)
+	if err != nil {
+		return "", err
+	}
+	out := make([]string, 0, len(results))
+	for _, r := range results {
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		out = append(out, string(data))
+	}
+	return strings.Join(out, "\n"), nil
+}
@@ -0,0 +1,96 @@
+package query
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const pets = `{
+  "count": 3,
+  "next": null,
+  "items": [
+    {"id": 1, "name": "rex", "tags": ["dog", "good"], "age": 4},
+    {"id": 2, "name": "tom", "tags": ["cat"], "age": 9},
+    {"id": 3, "name": "kit", "tags": [], "age": 1}
+  ]
+}`
+
+func evalJSON(t *testing.T, expr string) string {
+	t.Helper()
+	var doc interface{}
+	if err := json.Unmarshal([]byte(pets), &doc); err != nil {
+		t.Fatal(err)
+	}
+	results, err := Eval(expr, doc)
+	if err != nil {
+		t.Fatalf("%s: %v", expr, err)
+	}
+	var parts []string
+	for _, r := range results {
+		data, _ := json.Marshal(r)
+		parts = append(parts, string(data))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestJQ(t *testing.T) {
+	cases := []struct{ expr, want string }{
+		{".count", "3"},
+		{".items[0].name", `"rex"`},
+		{".items[-1].id", "3"},
+		{`.["count"]`, "3"},
+		{".items[].name", `"rex" "tom" "kit"`},
+		{".items[1:].[0].id", "2"},
+		{".items | length", "3"},
+		{".items | map(.id)", "[1,2,3]"},
+		{".items[] | select(.age > 3) | .name", `"rex" "tom"`},
+		{".items[] | select(.name == \"tom\" or .id == 3) | .id", "2 3"},
+		{".items | map(select(.tags | length > 0)) | length", "2"},
+		{"[.items[] | {name, n: (.tags | length)}] | first", `{"n":2,"name":"rex"}`},
+		{".items | sort_by(.age) | map(.name) | join(\", \")", `"kit, rex, tom"`},
+		{"[.items[].tags[]] | unique", `["cat","dog","good"]`},
+		{".items | map(.age) | add", "14"},
+		{"keys", `["count","items","next"]`},
+		{".next // \"none\"", `"none"`},
+		{".items[0] | has(\"tags\"), has(\"x\")", "true false"},
+		{".missing.deeper", "null"},
+		{".count | tostring | type", `"string"`},
+		{".items[0].name?, .count.x?", `"rex"`},
+		{"[.items[] | .age * 2 - 1]", "[7,17,1]"},
+	}
+	for _, c := range cases {
+		if got := evalJSON(t, c.expr); got != c.want {
+			t.Errorf("%s = %s, want %s", c.expr, got, c.want)
+		}
+	}
+	if got := evalJSON(t, "."); !strings.HasPrefix(got, `{"count":3`) {
+		t.Errorf("identity changed the document: %s", got)
+	}
+}
+
+func TestJSONPathAndErrors(t *testing.T) {
+	if got := evalJSON(t, "$.items[*].id"); got != "1 2 3" {
+		t.Errorf("JSONPath result %s", got)
+	}
+	for _, expr := range []string{".items[", "nope", ".a | length(1)", `"unterminated`, "$.items[", ".count | .x"} {
+		var doc interface{}
+		json.Unmarshal([]byte(pets), &doc)
+		if _, err := Eval(expr, doc); err == nil {
+			t.Errorf("expected an error for %q", expr)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	got, err := Apply(".items[0] | {id, name}", []byte(pets))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "{\n  \"id\": 1,\n  \"name\": \"rex\"\n}" {
+		t.Errorf("unexpected output %q", got)
+	}
+	if _, err := Apply(".", []byte("not json")); err == nil {
+		t.Error("expected an error for a non-JSON body")
+	}
+}
@@ -0,0 +1,268 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"org.subh/api-term/pkgs/jsonpath"
+)
+
+// Replacement is written in place of redacted field values
+const Replacement = "REDACTED"
+
+// Policy describes what must not leave the machine
+type Policy struct {
+	// Fields are case-insensitive regular expressions matched against JSON
+	// keys, header names and parameter names; matching values are replaced
+	Fields []string `yaml:"fields,omitempty" json:"fields,omitempty"`
+	// Patterns are replaced wherever they appear in text
+	Patterns []Pattern `yaml:"patterns,omitempty" json:"patterns,omitempty"`
+	// Exclude lists JSONPath expressions removed from JSON bodies entirely
+	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+	// Preview asks for confirmation, showing exactly what will be sent,
+	// before every call to an AI provider
+	Preview bool `yaml:"preview,omitempty" json:"preview,omitempty"`
+	// NoDefaults drops the built-in fields and patterns
+	NoDefaults bool `yaml:"noDefaults,omitempty" json:"noDefaults,omitempty"`
+}
+
+// Pattern is a named regular expression for sensitive values in free text
+type Pattern struct {
+	Name  string `yaml:"name" json:"name"`
+	Regex string `yaml:"regex" json:"regex"`
+	// Luhn only redacts matches that pass the card number checksum, which
+	// keeps ordinary long numbers such as IDs intact
+	Luhn bool `yaml:"luhn,omitempty" json:"luhn,omitempty"`
+}
+
+// DefaultFields cover credentials and common personal data
+var DefaultFields = []string{
+	"passw(or)?d", "secret", "token", "authorization", "cookie", "api[-_]?key",
+	"session", "ssn", "card.?number", "cvv",
+}
+
+// DefaultPatterns catch credentials and personal data in values
+var DefaultPatterns = []Pattern{
+	{Name: "email", Regex: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`},
+	{Name: "card", Regex: `\b(?:\d[ -]?){12,18}\d\b`, Luhn: true},
+	{Name: "jwt", Regex: `\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`},
+	{Name: "bearer", Regex: `(?i)\bbearer\s+[A-Za-z0-9._~+/=-]+`},
+}
+
+// LoadPolicy reads a policy from a YAML (or JSON) file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid redaction policy: %v", err)
+	}
+	return &p, nil
+}
+
+type compiledPattern struct {
+	name string
+	re   *regexp.Regexp
+	luhn bool
+}
+
+// Redactor applies a compiled Policy
+type Redactor struct {
+	Policy   *Policy
+	fields   []*regexp.Regexp
+	patterns []compiledPattern
+}
+
+// New compiles p, adding the defaults unless p.NoDefaults is set. A nil
+// policy uses the defaults only.
+func New(p *Policy) (*Redactor, error) {
+	if p == nil {
+		p = &Policy{}
+	}
+	fields, patterns := p.Fields, p.Patterns
+	if !p.NoDefaults {
+		fields = append(append([]string{}, DefaultFields...), fields...)
+		patterns = append(append([]Pattern{}, DefaultPatterns...), patterns...)
+	}
+	for _, expr := range p.Exclude {
+		if _, err := jsonpath.Eval(expr, nil); err != nil {
+			return nil, fmt.Errorf("exclude %q: %v", expr, err)
+		}
+	}
+
+	r := &Redactor{Policy: p}
+	for _, f := range fields {
+		re, err := regexp.Compile("(?i)" + f)
+		if err != nil {
+			return nil, fmt.Errorf("field pattern %q: %v", f, err)
+		}
+		r.fields = append(r.fields, re)
+	}
+	for _, pat := range patterns {
+		re, err := regexp.Compile(pat.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %s: %v", pat.Name, err)
+		}
+		r.patterns = append(r.patterns, compiledPattern{name: pat.Name, re: re, luhn: pat.Luhn})
+	}
+	return r, nil
+}
+
+// Default returns a Redactor for the built-in policy
+func Default() *Redactor {
+	r, _ := New(nil)
+	return r
+}
+
+// SensitiveField reports whether values of the named field must be hidden
+func (r *Redactor) SensitiveField(name string) bool {
+	for _, re := range r.fields {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Value redacts the value of a named header, parameter or field
+func (r *Redactor) Value(name, value string) string {
+	if r.SensitiveField(name) {
+		return Replacement
+	}
+	return r.Text(value)
+}
+
+// Text replaces pattern matches in free text
+func (r *Redactor) Text(s string) string {
+	for _, p := range r.patterns {
+		s = p.re.ReplaceAllStringFunc(s, func(match string) string {
+			if p.luhn && !luhnValid(match) {
+				return match
+			}
+			return Replacement + "(" + p.name + ")"
+		})
+	}
+	return s
+}
+
+// Body redacts a request or response body. JSON bodies lose their excluded
+// paths and sensitive fields and are re-encoded; anything else is treated as
+// text.
+func (r *Redactor) Body(body string) string {
+	if !strings.ContainsAny(body, "{[") {
+		return r.Text(body)
+	}
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil || dec.Decode(new(interface{})) != io.EOF {
+		return r.Text(body)
+	}
+	return encodeJSON(r.JSON(doc), true)
+}
+
+// Message redacts a message for an AI provider. JSON objects and arrays
+// embedded in it, such as a response pasted into a prompt, are redacted like
+// bodies; the text around them is treated as text.
+func (r *Redactor) Message(s string) string {
+	var out, text strings.Builder
+	for i := 0; i < len(s); {
+		j := strings.IndexAny(s[i:], "{[")
+		if j < 0 {
+			text.WriteString(s[i:])
+			break
+		}
+		j += i
+		dec := json.NewDecoder(strings.NewReader(s[j:]))
+		dec.UseNumber()
+		var doc interface{}
+		if err := dec.Decode(&doc); err != nil {
+			text.WriteString(s[i : j+1])
+			i = j + 1
+			continue
+		}
+		end := j + int(dec.InputOffset())
+		text.WriteString(s[i:j])
+		out.WriteString(r.Text(text.String()))
+		text.Reset()
+		out.WriteString(encodeJSON(r.JSON(doc), strings.Contains(s[j:end], "\n")))
+		i = end
+	}
+	out.WriteString(r.Text(text.String()))
+	return out.String()
+}
+
+// encodeJSON writes doc back, indented when the original was
+func encodeJSON(doc interface{}, indent bool) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	enc.Encode(doc)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// JSON redacts a decoded JSON document
+func (r *Redactor) JSON(doc interface{}) interface{} {
+	for _, expr := range r.Policy.Exclude {
+		doc, _ = jsonpath.Remove(expr, doc)
+	}
+	return r.walk(doc)
+}
+
+func (r *Redactor) walk(node interface{}) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if r.SensitiveField(k) {
+				v[k] = Replacement
+			} else {
+				v[k] = r.walk(child)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = r.walk(child)
+		}
+		return v
+	case string:
+		return r.Text(v)
+	case json.Number:
+		// a card number sent as a number is replaced by the redacted string
+		if s := r.Text(string(v)); s != string(v) {
+			return s
+		}
+	}
+	return node
+}
+
+// luhnValid checks the card number checksum of the digits in s
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,129 @@
+package redact
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactorDefaults(t *testing.T) {
+	r := Default()
+
+	text := "contact ann@example.com, card 4111 1111 1111 1111, order 1234567890123, Bearer abc.def"
+	got := r.Text(text)
+	for _, want := range []string{"REDACTED(email)", "REDACTED(card)", "order 1234567890123", "REDACTED(bearer)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+	if strings.Contains(got, "ann@example.com") || strings.Contains(got, "4111") {
+		t.Errorf("personal data left in %q", got)
+	}
+
+	if v := r.Value("X-Api-Key", "k1"); v != Replacement {
+		t.Errorf("api key header not redacted: %q", v)
+	}
+	if v := r.Value("Accept", "application/json"); v != "application/json" {
+		t.Errorf("harmless header changed: %q", v)
+	}
+}
+
+func TestRedactorPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	os.WriteFile(path, []byte(`
+fields: ["^dob$"]
+patterns:
+  - {name: employee, regex: 'EMP-\d+'}
+exclude:
+  - $.internal
+  - $.users[*].address
+preview: true
+`), 0o644)
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadPolicy: %v", err)
+	}
+	r, err := New(policy)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if !r.Policy.Preview {
+		t.Error("preview not loaded")
+	}
+
+	body := `{"internal":{"trace":"x"},"users":[{"name":"ann","dob":"1990-01-01","password":"p","address":"1 Main St","note":"see EMP-42"}]}`
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(r.Body(body)), &got); err != nil {
+		t.Fatalf("redacted body is not JSON: %v", err)
+	}
+	if _, ok := got["internal"]; ok {
+		t.Error("excluded path kept")
+	}
+	user := got["users"].([]interface{})[0].(map[string]interface{})
+	if _, ok := user["address"]; ok {
+		t.Error("excluded array field kept")
+	}
+	if user["dob"] != Replacement || user["password"] != Replacement || user["name"] != "ann" {
+		t.Errorf("fields not redacted as configured: %v", user)
+	}
+	if user["note"] != "see REDACTED(employee)" {
+		t.Errorf("custom pattern not applied: %v", user["note"])
+	}
+
+	if got := r.Body("plain text EMP-7"); got != "plain text REDACTED(employee)" {
+		t.Errorf("text body not redacted: %q", got)
+	}
+	if _, err := New(&Policy{Exclude: []string{"users"}}); err == nil {
+		t.Error("expected an invalid exclude path to be rejected")
+	}
+}
+
+func TestRedactorNoDefaults(t *testing.T) {
+	r, err := New(&Policy{NoDefaults: true, Fields: []string{"secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Text("ann@example.com"); got != "ann@example.com" {
+		t.Errorf("defaults applied despite noDefaults: %q", got)
+	}
+	if r.SensitiveField("token") || !r.SensitiveField("clientSecret") {
+		t.Error("only the configured fields should be sensitive")
+	}
+}
+
+func TestRedactorMessage(t *testing.T) {
+	r, err := New(&Policy{Fields: []string{"^dob$"}, Exclude: []string{"$.internal"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := "Explain this response for ann@example.com [Status: 200]:\n{\n  \"internal\": {\"trace\": \"x\"},\n  \"dob\": \"1990-01-01\",\n  \"name\": \"ann\"\n}\nand [1, 2]"
+	got := r.Message(msg)
+	want := "Explain this response for REDACTED(email) [Status: 200]:\n{\n  \"dob\": \"REDACTED\",\n  \"name\": \"ann\"\n}\nand [1,2]"
+	if got != want {
+		t.Errorf("Message() =\n%s\nwant\n%s", got, want)
+	}
+	if got := r.Message("no json {here"); got != "no json {here" {
+		t.Errorf("text without JSON changed: %q", got)
+	}
+}
+
+func TestRedactorMessageNumbers(t *testing.T) {
+	r := Default()
+	got := r.Message(`Charge {"amount":12.5,"card":4111111111111111}`)
+	if want := `Charge {"amount":12.5,"card":"REDACTED(card)"}`; got != want {
+		t.Errorf("Message() = %s, want %s", got, want)
+	}
+}
+
+func TestRedactorBodyKeepsValues(t *testing.T) {
+	got := Default().Body(`{"id":12345678901234567,"note":"a < b & c"}`)
+	want := "{\n  \"id\": 12345678901234567,\n  \"note\": \"a < b & c\"\n}"
+	if got != want {
+		t.Errorf("Body() =\n%s\nwant\n%s", got, want)
+	}
+	if got := Default().Body(`{"a":1} trailing ann@example.com`); got != `{"a":1} trailing REDACTED(email)` {
+		t.Errorf("body with trailing text not treated as text: %q", got)
+	}
+}
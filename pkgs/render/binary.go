@@ -0,0 +1,453 @@
+package render
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+var errTruncated = errors.New("unexpected end of data")
+
+// hexDump shows the first MaxHexBytes of body with size information
+func hexDump(mediaType string, body []byte) string {
+	header := fmt.Sprintf("%s · %s (%d bytes)", mediaType, Size(len(body)), len(body))
+	shown := body
+	if len(shown) > MaxHexBytes {
+		shown = shown[:MaxHexBytes]
+		header += fmt.Sprintf(", first %s shown", Size(MaxHexBytes))
+	}
+	return header + "\n\n" + hex.Dump(shown)
+}
+
+// decodeProtobuf decodes a message without its schema, like
+// protoc --decode_raw: fields are keyed by number, and length-delimited
+// fields become nested messages, strings or base64 bytes
+func decodeProtobuf(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	return decodeMessage(data, 0)
+}
+
+func decodeMessage(data []byte, depth int) (map[string]interface{}, error) {
+	msg := map[string]interface{}{}
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		data = data[n:]
+		field, wireType := key>>3, key&7
+		if field == 0 {
+			return nil, fmt.Errorf("invalid field number 0")
+		}
+		var value interface{}
+		switch wireType {
+		case 0: // varint
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, errTruncated
+			}
+			data, value = data[n:], v
+		case 1: // fixed64
+			if len(data) < 8 {
+				return nil, errTruncated
+			}
+			value, data = binary.LittleEndian.Uint64(data), data[8:]
+		case 5: // fixed32
+			if len(data) < 4 {
+				return nil, errTruncated
+			}
+			value, data = binary.LittleEndian.Uint32(data), data[4:]
+		case 2: // length-delimited
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return nil, errTruncated
+			}
+			chunk := data[n : n+int(size)]
+			data = data[n+int(size):]
+			value = decodeChunk(chunk, depth)
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d", wireType)
+		}
+		name := strconv.FormatUint(field, 10)
+		// repeated fields collect into an array
+		if prev, ok := msg[name]; ok {
+			if list, ok := prev.([]interface{}); ok {
+				msg[name] = append(list, value)
+			} else {
+				msg[name] = []interface{}{prev, value}
+			}
+		} else {
+			msg[name] = value
+		}
+	}
+	return msg, nil
+}
+
+// decodeChunk guesses what a length-delimited field holds. Printable text is
+// taken as a string first since short strings often parse as messages too.
+func decodeChunk(chunk []byte, depth int) interface{} {
+	if printable(chunk) {
+		return string(chunk)
+	}
+	if depth < 16 && len(chunk) > 0 {
+		if nested, err := decodeMessage(chunk, depth+1); err == nil {
+			return nested
+		}
+	}
+	if utf8.Valid(chunk) {
+		return string(chunk)
+	}
+	return base64.StdEncoding.EncodeToString(chunk)
+}
+
+func printable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// reader walks a byte slice for the msgpack and CBOR decoders
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) bytes(n uint64) ([]byte, error) {
+	if uint64(len(r.data)-r.pos) < n {
+		return nil, errTruncated
+	}
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+func (r *reader) byte() (byte, error) {
+	b, err := r.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *reader) uint(size int) (uint64, error) {
+	b, err := r.bytes(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func (r *reader) done() error {
+	if r.pos != len(r.data) {
+		return fmt.Errorf("%d trailing bytes", len(r.data)-r.pos)
+	}
+	return nil
+}
+
+// checkCount rejects collection sizes larger than the remaining data, so a
+// corrupt header can't ask for a huge allocation
+func checkCount(r *reader, n uint64) error {
+	if n > uint64(len(r.data)-r.pos) {
+		return errTruncated
+	}
+	return nil
+}
+
+func decodeMsgpack(data []byte) (interface{}, error) {
+	r := &reader{data: data}
+	v, err := r.msgpack()
+	if err != nil {
+		return nil, err
+	}
+	return v, r.done()
+}
+
+func (r *reader) msgpack() (interface{}, error) {
+	b, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case b <= 0x7f:
+		return uint64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xf0 == 0x80:
+		return r.msgpackMap(uint64(b & 0x0f))
+	case b&0xf0 == 0x90:
+		return r.msgpackArray(uint64(b & 0x0f))
+	case b&0xe0 == 0xa0:
+		return r.msgpackString(uint64(b & 0x1f))
+	}
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6: // bin 8/16/32
+		n, err := r.uint(1 << (b - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		raw, err := r.bytes(n)
+		return base64.StdEncoding.EncodeToString(raw), err
+	case 0xc7, 0xc8, 0xc9: // ext 8/16/32
+		n, err := r.uint(1 << (b - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return r.msgpackExt(n)
+	case 0xca:
+		v, err := r.uint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := r.uint(8)
+		return math.Float64frombits(v), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return r.uint(1 << (b - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		v, err := r.uint(size)
+		shift := 64 - 8*size
+		return int64(v<<shift) >> shift, err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8: // fixext 1/2/4/8/16
+		return r.msgpackExt(1 << (b - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.uint(1 << (b - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return r.msgpackString(n)
+	case 0xdc, 0xdd:
+		n, err := r.uint(2 << (b - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return r.msgpackArray(n)
+	case 0xde, 0xdf:
+		n, err := r.uint(2 << (b - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return r.msgpackMap(n)
+	}
+	return nil, fmt.Errorf("invalid msgpack byte 0x%02x", b)
+}
+
+func (r *reader) msgpackString(n uint64) (interface{}, error) {
+	b, err := r.bytes(n)
+	return string(b), err
+}
+
+func (r *reader) msgpackArray(n uint64) (interface{}, error) {
+	if err := checkCount(r, n); err != nil {
+		return nil, err
+	}
+	out := make([]interface{}, 0, n)
+	for i := uint64(0); i < n; i++ {
+		v, err := r.msgpack()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (r *reader) msgpackMap(n uint64) (interface{}, error) {
+	if err := checkCount(r, n); err != nil {
+		return nil, err
+	}
+	out := make(map[interface{}]interface{}, n)
+	for i := uint64(0); i < n; i++ {
+		k, err := r.msgpack()
+		if err != nil {
+			return nil, err
+		}
+		v, err := r.msgpack()
+		if err != nil {
+			return nil, err
+		}
+		out[hashable(k)] = v
+	}
+	return out, nil
+}
+
+func (r *reader) msgpackExt(n uint64) (interface{}, error) {
+	typ, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := r.bytes(n)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"ext": int8(typ), "data": base64.StdEncoding.EncodeToString(raw)}, nil
+}
+
+func decodeCBOR(data []byte) (interface{}, error) {
+	r := &reader{data: data}
+	v, err := r.cbor()
+	if err != nil {
+		return nil, err
+	}
+	return v, r.done()
+}
+
+// cborBreak marks the end of an indefinite-length item
+var cborBreak = errors.New("break")
+
+func (r *reader) cbor() (interface{}, error) {
+	b, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	major, info := b>>5, b&0x1f
+	if b == 0xff {
+		return nil, cborBreak
+	}
+	indefinite := info == 31 && major >= 2 && major <= 5
+	var arg uint64
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		if arg, err = r.uint(1 << (info - 24)); err != nil {
+			return nil, err
+		}
+	case indefinite:
+	default:
+		return nil, fmt.Errorf("invalid CBOR additional info %d", info)
+	}
+
+	switch major {
+	case 0:
+		return arg, nil
+	case 1:
+		return -1 - int64(arg), nil
+	case 2, 3:
+		var buf []byte
+		if indefinite {
+			for {
+				chunk, err := r.cbor()
+				if err == cborBreak {
+					break
+				}
+				if err != nil {
+					return nil, err
+				}
+				switch c := chunk.(type) {
+				case string:
+					buf = append(buf, c...)
+				case []byte:
+					buf = append(buf, c...)
+				}
+			}
+		} else if buf, err = r.bytes(arg); err != nil {
+			return nil, err
+		}
+		if major == 3 {
+			return string(buf), nil
+		}
+		return base64.StdEncoding.EncodeToString(buf), nil
+	case 4:
+		out := []interface{}{}
+		for i := uint64(0); indefinite || i < arg; i++ {
+			v, err := r.cbor()
+			if indefinite && err == cborBreak {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	case 5:
+		out := map[interface{}]interface{}{}
+		for i := uint64(0); indefinite || i < arg; i++ {
+			k, err := r.cbor()
+			if indefinite && err == cborBreak {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			v, err := r.cbor()
+			if err != nil {
+				return nil, err
+			}
+			out[hashable(k)] = v
+		}
+		return out, nil
+	case 6: // tagged value, shown as the value itself
+		return r.cbor()
+	}
+	// major type 7: simple values and floats
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		return halfFloat(uint16(arg)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(arg))), nil
+	case 27:
+		return math.Float64frombits(arg), nil
+	}
+	return arg, nil
+}
+
+func halfFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			v = math.Inf(1)
+		} else {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -v
+	}
+	return v
+}
+
+// hashable turns decoded map keys that can't be Go map keys into strings
+func hashable(k interface{}) interface{} {
+	switch k.(type) {
+	case []interface{}, map[interface{}]interface{}, map[string]interface{}:
+		return fmt.Sprint(k)
+	}
+	return k
+}
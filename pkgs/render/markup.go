@@ -0,0 +1,116 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// indentMarkup re-indents XML, or HTML when html is set, one element per line
+func indentMarkup(body []byte, html bool) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	if html {
+		dec.Strict = false
+		dec.AutoClose = xml.HTMLAutoClose
+		dec.Entity = xml.HTMLEntity
+	}
+	var b strings.Builder
+	depth := 0
+	// open tracks whether the last line is a start tag still waiting for
+	// text or its end tag, so short elements stay on one line
+	open := false
+	line := func(s string) {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(strings.Repeat("  ", depth) + s)
+	}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if open {
+				depth++
+			}
+			line("<" + name(t.Name) + attrs(t.Attr) + ">")
+			open = true
+		case xml.EndElement:
+			if open {
+				// <a></a> or <a>text</a>; void HTML elements like <br> have no end tag
+				if !html || !isVoid(t.Name.Local) {
+					b.WriteString("</" + name(t.Name) + ">")
+				}
+				open = false
+				continue
+			}
+			depth--
+			line("</" + name(t.Name) + ">")
+		case xml.CharData:
+			text := strings.TrimSpace(string(t))
+			if text == "" {
+				continue
+			}
+			var escaped bytes.Buffer
+			xml.EscapeText(&escaped, []byte(text))
+			if open && !strings.Contains(text, "\n") {
+				b.WriteString(escaped.String())
+				continue
+			}
+			if open {
+				depth++
+				open = false
+			}
+			line(escaped.String())
+		case xml.Comment:
+			if open {
+				depth++
+				open = false
+			}
+			line("<!--" + string(t) + "-->")
+		case xml.ProcInst:
+			line(fmt.Sprintf("<?%s %s?>", t.Target, t.Inst))
+		case xml.Directive:
+			line("<!" + string(t) + ">")
+		}
+	}
+	if b.Len() == 0 {
+		return "", fmt.Errorf("no markup found")
+	}
+	return b.String(), nil
+}
+
+func isVoid(tag string) bool {
+	for _, v := range xml.HTMLAutoClose {
+		if strings.EqualFold(v, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func name(n xml.Name) string {
+	// the decoder resolves prefixes to namespace URLs; keep the local name
+	return n.Local
+}
+
+func attrs(list []xml.Attr) string {
+	var b strings.Builder
+	for _, a := range list {
+		var value bytes.Buffer
+		xml.EscapeText(&value, []byte(a.Value))
+		key := a.Name.Local
+		if a.Name.Space == "xmlns" {
+			key = "xmlns:" + key
+		}
+		fmt.Fprintf(&b, " %s=\"%s\"", key, value.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,261 @@
+// Package render turns response bodies into readable text chosen by their
+// Content-Type: indented JSON, XML/HTML and YAML, CSV/TSV tables, form
+// fields, protobuf/msgpack/CBOR decoded to JSON, and hex dumps for binary.
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats a body can be rendered as
+const (
+	FormatJSON     = "JSON"
+	FormatXML      = "XML"
+	FormatHTML     = "HTML"
+	FormatYAML     = "YAML"
+	FormatCSV      = "CSV"
+	FormatTSV      = "TSV"
+	FormatForm     = "form"
+	FormatProtobuf = "protobuf"
+	FormatMsgpack  = "msgpack"
+	FormatCBOR     = "CBOR"
+	FormatText     = "text"
+	FormatBinary   = "binary"
+)
+
+// MaxHexBytes caps how much of a binary body is hex dumped
+const MaxHexBytes = 4096
+
+// Result is a rendered body
+type Result struct {
+	// Format is one of the Format constants
+	Format string
+	// MediaType is the Content-Type without parameters, or the sniffed type
+	MediaType string
+	Text      string
+	// JSON is the body as JSON text when it has a JSON form, for the tree
+	// view and filters; empty otherwise
+	JSON string
+	// Binary is set for bodies better saved to a file than read, like
+	// images and PDFs
+	Binary bool
+	Size   int
+}
+
+// Body renders body according to contentType. Bodies that fail to decode as
+// their declared type are shown as text, or hex dumped when not UTF-8.
+func Body(contentType string, body []byte) *Result {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "" {
+		mediaType = sniff(body)
+	}
+	r := &Result{MediaType: mediaType, Size: len(body)}
+	r.Format = formatOf(mediaType)
+
+	var decoded interface{}
+	switch r.Format {
+	case FormatJSON:
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, body, "", "  "); err == nil {
+			r.Text, r.JSON = buf.String(), string(body)
+			return r
+		}
+	case FormatXML, FormatHTML:
+		if text, err := indentMarkup(body, r.Format == FormatHTML); err == nil {
+			r.Text = text
+			return r
+		}
+	case FormatYAML:
+		if text, value, err := indentYAML(body); err == nil {
+			r.Text = text
+			r.JSON = toJSON(value)
+			return r
+		}
+	case FormatCSV, FormatTSV:
+		comma := ','
+		if r.Format == FormatTSV {
+			comma = '\t'
+		}
+		if text, err := table(body, comma); err == nil {
+			r.Text = text
+			return r
+		}
+	case FormatForm:
+		if text, err := formFields(string(body)); err == nil {
+			r.Text = text
+			return r
+		}
+	case FormatProtobuf:
+		decoded, err = decodeProtobuf(body)
+	case FormatMsgpack:
+		decoded, err = decodeMsgpack(body)
+	case FormatCBOR:
+		decoded, err = decodeCBOR(body)
+	case FormatBinary:
+		r.Binary = true
+		r.Text = hexDump(mediaType, body)
+		return r
+	}
+	if err == nil && decoded != nil {
+		if js := toJSON(decoded); js != "" {
+			var buf bytes.Buffer
+			json.Indent(&buf, []byte(js), "", "  ")
+			r.Text, r.JSON = buf.String(), js
+			return r
+		}
+	}
+
+	// fall back to text, or a hex dump for bytes that aren't text
+	if isText(body) {
+		r.Format, r.Text = FormatText, string(body)
+		return r
+	}
+	r.Format, r.Binary = FormatBinary, true
+	r.Text = hexDump(mediaType, body)
+	return r
+}
+
+// formatOf maps a media type to a format
+func formatOf(mediaType string) string {
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || mediaType == "application/x-ndjson":
+		return FormatJSON
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return FormatHTML
+	case strings.HasSuffix(mediaType, "/xml") || strings.HasSuffix(mediaType, "+xml"):
+		return FormatXML
+	case strings.Contains(mediaType, "yaml"):
+		return FormatYAML
+	case mediaType == "text/csv":
+		return FormatCSV
+	case mediaType == "text/tab-separated-values":
+		return FormatTSV
+	case mediaType == "application/x-www-form-urlencoded":
+		return FormatForm
+	case strings.Contains(mediaType, "protobuf"):
+		return FormatProtobuf
+	case strings.Contains(mediaType, "msgpack"):
+		return FormatMsgpack
+	case mediaType == "application/cbor" || strings.HasSuffix(mediaType, "+cbor"):
+		return FormatCBOR
+	case strings.HasPrefix(mediaType, "text/"):
+		return FormatText
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "font/"),
+		mediaType == "application/pdf", mediaType == "application/zip",
+		mediaType == "application/gzip", mediaType == "application/octet-stream":
+		return FormatBinary
+	}
+	return FormatText
+}
+
+// sniff guesses the media type of a body sent without a usable Content-Type
+func sniff(body []byte) string {
+	if json.Valid(body) {
+		return "application/json"
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(body))
+	return mediaType
+}
+
+func isText(body []byte) bool {
+	return utf8.Valid(body) && !bytes.ContainsRune(body, 0)
+}
+
+// Title describes the rendered body for the Response pane title,
+// e.g. "XML" or "image/png · 12.3 KB"
+func (r *Result) Title() string {
+	if r.Binary {
+		return r.MediaType + " · " + Size(r.Size)
+	}
+	return r.Format
+}
+
+// Size formats a byte count for humans
+func Size(n int) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+}
+
+// Extension returns a file extension for the media type, used to name saved
+// bodies
+func Extension(mediaType string) string {
+	switch mediaType {
+	case "application/json":
+		return ".json"
+	case "text/plain":
+		return ".txt"
+	case "image/jpeg":
+		return ".jpg"
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+func indentYAML(body []byte) (string, interface{}, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(body, &node); err != nil {
+		return "", nil, err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return "", nil, err
+	}
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return "", nil, err
+	}
+	return strings.TrimRight(buf.String(), "\n"), value, nil
+}
+
+// toJSON encodes a decoded value, returning "" when it has no JSON form
+func toJSON(v interface{}) string {
+	data, err := json.Marshal(jsonable(v))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// jsonable converts maps with non-string keys, as produced by YAML, msgpack
+// and CBOR, into JSON objects
+func jsonable(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			out[k] = jsonable(val)
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			out[fmt.Sprint(k)] = jsonable(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = jsonable(val)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,98 @@
+package render
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBodyByContentType(t *testing.T) {
+	cases := []struct {
+		contentType, body, format string
+		contains                  []string
+	}{
+		{"application/json; charset=utf-8", `{"a":[1,2]}`, FormatJSON, []string{"{\n  \"a\": [\n    1,"}},
+		{"application/xml", `<?xml version="1.0"?><list><item id="1">a &amp; b</item><item id="2"/></list>`, FormatXML,
+			[]string{"<list>\n  <item id=\"1\">a &amp; b</item>\n  <item id=\"2\"></item>\n</list>"}},
+		{"text/html", `<html><body><p>Hi<br>there</p></body></html>`, FormatHTML, []string{"    <p>Hi\n      <br>\n      there\n    </p>"}},
+		{"application/yaml", "a:   1\nb: [x,   y]\n", FormatYAML, []string{"a: 1", "b: [x, y]"}},
+		{"text/csv", "id,name\n1,rex\n22,tom\n", FormatCSV, []string{"id │ name\n───┼─────\n1  │ rex\n22 │ tom", "2 rows × 2 columns"}},
+		{"text/tab-separated-values", "a\tb\n1\t2\n", FormatTSV, []string{"a │ b"}},
+		{"application/x-www-form-urlencoded", "name=rex+dog&tag=a%26b&empty=", FormatForm, []string{"name = rex dog\ntag = a&b\nempty = "}},
+		{"", `[1,2]`, FormatJSON, []string{"[\n  1,"}},
+		{"text/plain", "hello", FormatText, []string{"hello"}},
+		{"application/xml", "not <xml", FormatText, []string{"not <xml"}},
+	}
+	for _, c := range cases {
+		r := Body(c.contentType, []byte(c.body))
+		if r.Format != c.format {
+			t.Errorf("%s: format %s, want %s", c.contentType, r.Format, c.format)
+			continue
+		}
+		for _, want := range c.contains {
+			if !strings.Contains(r.Text, want) {
+				t.Errorf("%s: expected %q in\n%s", c.contentType, want, r.Text)
+			}
+		}
+	}
+
+	if r := Body("application/yaml", []byte("a: 1\nb: [x]\n")); r.JSON != `{"a":1,"b":["x"]}` {
+		t.Errorf("YAML has no JSON form: %q", r.JSON)
+	}
+}
+
+func TestBinaryFormats(t *testing.T) {
+	// {"id": 7, "name": "rex", "tags": ["a", "b"], "ok": true, "neg": -2}
+	msgpack := []byte{0x85, 0xa2, 'i', 'd', 0x07, 0xa4, 'n', 'a', 'm', 'e', 0xa3, 'r', 'e', 'x',
+		0xa4, 't', 'a', 'g', 's', 0x92, 0xa1, 'a', 0xa1, 'b', 0xa2, 'o', 'k', 0xc3, 0xa3, 'n', 'e', 'g', 0xfe}
+	cbor := []byte{0xa5, 0x62, 'i', 'd', 0x07, 0x64, 'n', 'a', 'm', 'e', 0x63, 'r', 'e', 'x',
+		0x64, 't', 'a', 'g', 's', 0x82, 0x61, 'a', 0x61, 'b', 0x62, 'o', 'k', 0xf5, 0x63, 'n', 'e', 'g', 0x21}
+	want := map[string]interface{}{"id": 7.0, "name": "rex", "tags": []interface{}{"a", "b"}, "ok": true, "neg": -2.0}
+
+	for contentType, body := range map[string][]byte{"application/msgpack": msgpack, "application/cbor": cbor} {
+		r := Body(contentType, body)
+		var got map[string]interface{}
+		if err := json.Unmarshal([]byte(r.JSON), &got); err != nil {
+			t.Fatalf("%s: %v (%q)", contentType, err, r.Text)
+		}
+		for k, v := range want {
+			if gotJSON, _ := json.Marshal(got[k]); string(gotJSON) != mustJSON(v) {
+				t.Errorf("%s: %s = %s, want %s", contentType, k, gotJSON, mustJSON(v))
+			}
+		}
+	}
+
+	// field 1 = 150, field 2 = "testing", field 3 = {1: 1}, field 4 repeated
+	proto := []byte{0x08, 0x96, 0x01, 0x12, 0x07, 't', 'e', 's', 't', 'i', 'n', 'g', 0x1a, 0x02, 0x08, 0x01, 0x20, 0x01, 0x20, 0x02}
+	r := Body("application/x-protobuf", proto)
+	if r.JSON != `{"1":150,"2":"testing","3":{"1":1},"4":[1,2]}` {
+		t.Errorf("unexpected protobuf JSON %s", r.JSON)
+	}
+
+	if r := Body("application/msgpack", []byte{0x92, 0x01}); r.Format != FormatBinary || !r.Binary {
+		t.Errorf("truncated msgpack should fall back to a hex dump, got %s", r.Format)
+	}
+}
+
+func TestHexDump(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 5000)...)
+	r := Body("image/png", png)
+	if !r.Binary || r.Title() != "image/png · 4.9 KB" {
+		t.Errorf("unexpected binary result %q", r.Title())
+	}
+	if !strings.HasPrefix(r.Text, "image/png · 4.9 KB (5008 bytes), first 4.0 KB shown\n\n00000000  89 50 4e 47") {
+		t.Errorf("unexpected dump header %q", r.Text[:80])
+	}
+	if Extension("image/png") != ".png" || Extension("application/x-unknown") != ".bin" {
+		t.Error("unexpected extensions")
+	}
+
+	if r := Body("", []byte{0x00, 0x01, 0xff}); !r.Binary || r.MediaType != "application/octet-stream" {
+		t.Errorf("unlabelled binary not sniffed: %+v", r)
+	}
+}
+
+func mustJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
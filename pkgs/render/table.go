@@ -0,0 +1,92 @@
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// maxCellWidth truncates long cells so a table stays readable
+const maxCellWidth = 40
+
+// table lays out CSV/TSV records as aligned columns with a rule under the
+// header row
+func table(body []byte, comma rune) (string, error) {
+	r := csv.NewReader(bytes.NewReader(body))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return "", err
+	}
+	if len(records) == 0 {
+		return "", fmt.Errorf("no records")
+	}
+
+	var widths []int
+	for _, rec := range records {
+		for i, cell := range rec {
+			rec[i] = truncate(cell)
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], runewidth.StringWidth(rec[i]))
+		}
+	}
+
+	var lines []string
+	for n, rec := range records {
+		cells := make([]string, len(rec))
+		for i, cell := range rec {
+			cells[i] = cell + strings.Repeat(" ", widths[i]-runewidth.StringWidth(cell))
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(cells, " │ "), " "))
+		if n == 0 {
+			rules := make([]string, len(widths))
+			for i, w := range widths {
+				rules[i] = strings.Repeat("─", w)
+			}
+			lines = append(lines, strings.Join(rules, "─┼─"))
+		}
+	}
+	lines = append(lines, "", fmt.Sprintf("%d rows × %d columns", len(records)-1, len(widths)))
+	return strings.Join(lines, "\n"), nil
+}
+
+func truncate(cell string) string {
+	cell = strings.ReplaceAll(cell, "\n", " ")
+	if runewidth.StringWidth(cell) <= maxCellWidth {
+		return cell
+	}
+	return runewidth.Truncate(cell, maxCellWidth, "…")
+}
+
+// formFields lists urlencoded fields one per line in their original order
+func formFields(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", fmt.Errorf("empty form")
+	}
+	var lines []string
+	for _, pair := range strings.Split(body, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		k, err := url.QueryUnescape(key)
+		if err != nil {
+			return "", err
+		}
+		v, err := url.QueryUnescape(value)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, k+" = "+v)
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,287 @@
+// Package respdiff compares two response bodies: structurally for JSON, by
+// path, and line by line for anything else.
+package respdiff
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of change
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change is one difference between two JSON documents
+type Change struct {
+	// Path is a JSONPath such as $.items[0].id
+	Path string
+	Kind string
+	Old  interface{}
+	New  interface{}
+}
+
+// Result lists the changes found, and how many were ignored
+type Result struct {
+	Changes []Change
+	Ignored int
+}
+
+// Ignore decides which paths are left out of a comparison. Each rule is a
+// member name glob like updatedAt or *At, matched at any depth, or a path
+// starting with $ where [*] matches any index, like $.items[*].etag.
+type Ignore struct {
+	names []string
+	paths []*regexp.Regexp
+}
+
+// NewIgnore compiles ignore rules
+func NewIgnore(rules []string) *Ignore {
+	ig := &Ignore{}
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		switch {
+		case rule == "":
+		case strings.HasPrefix(rule, "$"):
+			pattern := regexp.QuoteMeta(rule)
+			pattern = strings.ReplaceAll(pattern, `\[\*\]`, `\[\d+\]`)
+			pattern = strings.ReplaceAll(pattern, `\.\*`, `\.[^.\[]+`)
+			// a rule also covers everything below it
+			ig.paths = append(ig.paths, regexp.MustCompile("^"+pattern+`(\.|\[|$)`))
+		default:
+			ig.names = append(ig.names, rule)
+		}
+	}
+	return ig
+}
+
+// Match reports whether the member name at path p is ignored
+func (ig *Ignore) Match(p, name string) bool {
+	if ig == nil {
+		return false
+	}
+	for _, pattern := range ig.names {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	for _, re := range ig.paths {
+		if re.MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// JSON compares two decoded JSON documents. Object members are compared by
+// name and arrays by index.
+func JSON(old, new interface{}, ignore *Ignore) *Result {
+	r := &Result{}
+	r.compare("$", "", old, new, ignore)
+	return r
+}
+
+func (r *Result) compare(p, name string, old, new interface{}, ignore *Ignore) {
+	if p != "$" && ignore.Match(p, name) {
+		if !reflect.DeepEqual(old, new) {
+			r.Ignored++
+		}
+		return
+	}
+	switch o := old.(type) {
+	case map[string]interface{}:
+		if n, ok := new.(map[string]interface{}); ok {
+			keys := map[string]bool{}
+			for k := range o {
+				keys[k] = true
+			}
+			for k := range n {
+				keys[k] = true
+			}
+			for _, k := range sortedKeys(keys) {
+				child := p + member(k)
+				ov, inOld := o[k]
+				nv, inNew := n[k]
+				switch {
+				case !inNew:
+					r.add(child, k, Removed, ov, nil, ignore)
+				case !inOld:
+					r.add(child, k, Added, nil, nv, ignore)
+				default:
+					r.compare(child, k, ov, nv, ignore)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if n, ok := new.([]interface{}); ok {
+			for i := 0; i < len(o) || i < len(n); i++ {
+				child := p + "[" + strconv.Itoa(i) + "]"
+				switch {
+				case i >= len(n):
+					r.add(child, "", Removed, o[i], nil, ignore)
+				case i >= len(o):
+					r.add(child, "", Added, nil, n[i], ignore)
+				default:
+					r.compare(child, "", o[i], n[i], ignore)
+				}
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(old, new) {
+		r.Changes = append(r.Changes, Change{Path: p, Kind: Changed, Old: old, New: new})
+	}
+}
+
+func (r *Result) add(p, name, kind string, old, new interface{}, ignore *Ignore) {
+	if ignore.Match(p, name) {
+		r.Ignored++
+		return
+	}
+	r.Changes = append(r.Changes, Change{Path: p, Kind: kind, Old: old, New: new})
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func member(k string) string {
+	if identifier.MatchString(k) {
+		return "." + k
+	}
+	return "['" + strings.ReplaceAll(k, "'", `\'`) + "']"
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Summary renders a value compactly for a change row, truncated to max runes
+func Summary(v interface{}, max int) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "?"
+	}
+	s := []rune(string(data))
+	if len(s) > max {
+		return string(s[:max-1]) + "…"
+	}
+	return string(s)
+}
+
+// Line is one row of a side-by-side text diff. Kind is ' ' for equal lines,
+// '-' for lines only on the left, '+' for lines only on the right and '~'
+// for a changed pair.
+type Line struct {
+	Kind     byte
+	Old, New string
+}
+
+// maxCells bounds the LCS table; longer bodies are compared line by line
+const maxCells = 4_000_000
+
+// Text diffs two bodies line by line, pairing removed and added runs into
+// changed rows so they line up side by side
+func Text(old, new string) []Line {
+	a, b := strings.Split(old, "\n"), strings.Split(new, "\n")
+	var ops []Line
+	if len(a)*len(b) > maxCells {
+		for i := 0; i < len(a) || i < len(b); i++ {
+			switch {
+			case i >= len(b):
+				ops = append(ops, Line{Kind: '-', Old: a[i]})
+			case i >= len(a):
+				ops = append(ops, Line{Kind: '+', New: b[i]})
+			case a[i] == b[i]:
+				ops = append(ops, Line{Kind: ' ', Old: a[i], New: b[i]})
+			default:
+				ops = append(ops, Line{Kind: '-', Old: a[i]}, Line{Kind: '+', New: b[i]})
+			}
+		}
+	} else {
+		ops = lcs(a, b)
+	}
+	return pair(ops)
+}
+
+// lcs computes an edit script from the longest common subsequence
+func lcs(a, b []string) []Line {
+	n, m := len(a), len(b)
+	table := make([][]int32, n+1)
+	for i := range table {
+		table[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+	var ops []Line
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, Line{Kind: ' ', Old: a[i], New: b[j]})
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			ops = append(ops, Line{Kind: '-', Old: a[i]})
+			i++
+		default:
+			ops = append(ops, Line{Kind: '+', New: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, Line{Kind: '-', Old: a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, Line{Kind: '+', New: b[j]})
+	}
+	return ops
+}
+
+// pair merges each run of removed lines with the added run that follows
+func pair(ops []Line) []Line {
+	var out []Line
+	for i := 0; i < len(ops); {
+		if ops[i].Kind != '-' {
+			out = append(out, ops[i])
+			i++
+			continue
+		}
+		var removed, added []string
+		for ; i < len(ops) && ops[i].Kind == '-'; i++ {
+			removed = append(removed, ops[i].Old)
+		}
+		for ; i < len(ops) && ops[i].Kind == '+'; i++ {
+			added = append(added, ops[i].New)
+		}
+		for k := 0; k < len(removed) || k < len(added); k++ {
+			switch {
+			case k >= len(added):
+				out = append(out, Line{Kind: '-', Old: removed[k]})
+			case k >= len(removed):
+				out = append(out, Line{Kind: '+', New: added[k]})
+			default:
+				out = append(out, Line{Kind: '~', Old: removed[k], New: added[k]})
+			}
+		}
+	}
+	return out
+}
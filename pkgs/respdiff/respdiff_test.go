@@ -0,0 +1,64 @@
+package respdiff
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestJSON(t *testing.T) {
+	prod := decode(t, `{"id":1,"status":"active","updatedAt":"2026-01-01","items":[{"id":"a","etag":"1"},{"id":"b","etag":"2"}],"meta":{"region":"eu"},"a b":1}`)
+	staging := decode(t, `{"id":1,"status":"disabled","updatedAt":"2026-02-02","items":[{"id":"a","etag":"9"}],"meta":{"region":"eu","beta":true},"a b":2}`)
+
+	r := JSON(prod, staging, NewIgnore([]string{"*At", "$.items[*].etag"}))
+	want := []Change{
+		{Path: "$['a b']", Kind: Changed, Old: 1.0, New: 2.0},
+		{Path: "$.items[1]", Kind: Removed, Old: map[string]interface{}{"id": "b", "etag": "2"}},
+		{Path: "$.meta.beta", Kind: Added, New: true},
+		{Path: "$.status", Kind: Changed, Old: "active", New: "disabled"},
+	}
+	if len(r.Changes) != len(want) {
+		t.Fatalf("unexpected changes %+v", r.Changes)
+	}
+	for i, c := range r.Changes {
+		if c.Path != want[i].Path || c.Kind != want[i].Kind || Summary(c.Old, 80) != Summary(want[i].Old, 80) || Summary(c.New, 80) != Summary(want[i].New, 80) {
+			t.Errorf("change %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+	if r.Ignored != 2 {
+		t.Errorf("expected 2 ignored changes, got %d", r.Ignored)
+	}
+
+	if r := JSON(prod, prod, nil); len(r.Changes) != 0 {
+		t.Errorf("identical documents differ: %+v", r.Changes)
+	}
+	if got := Summary("a long string value", 8); got != `"a long…` {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
+
+func TestText(t *testing.T) {
+	lines := Text("a\nb\nc\nd", "a\nB\nc\nd\ne")
+	want := []Line{{' ', "a", "a"}, {'~', "b", "B"}, {' ', "c", "c"}, {' ', "d", "d"}, {'+', "", "e"}}
+	if len(lines) != len(want) {
+		t.Fatalf("unexpected lines %+v", lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, lines[i], want[i])
+		}
+	}
+
+	lines = Text("x\ny\nz", "z")
+	if len(lines) != 3 || lines[0].Kind != '-' || lines[1].Kind != '-' || lines[2].Kind != ' ' {
+		t.Errorf("unexpected removal %+v", lines)
+	}
+}
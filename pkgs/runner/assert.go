@@ -0,0 +1,167 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"org.subh/api-term/pkgs/api/client"
+	"org.subh/api-term/pkgs/api/parser"
+	"org.subh/api-term/pkgs/query"
+)
+
+// Check evaluates the assertions against resp and returns one message per failure
+func (a *Assertions) Check(resp *client.Response, doc *openapi3.T, req *Request) []string {
+	var failures []string
+
+	if a.Status != 0 && resp.StatusCode != a.Status {
+		failures = append(failures, fmt.Sprintf("status: expected %d, got %d", a.Status, resp.StatusCode))
+	}
+
+	headerNames := make([]string, 0, len(a.Headers))
+	for name := range a.Headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	for _, name := range headerNames {
+		want := a.Headers[name]
+		if got := resp.Header.Get(name); got != want {
+			failures = append(failures, fmt.Sprintf("header %s: expected %q, got %q", name, want, got))
+		}
+	}
+
+	if a.MaxLatency > 0 && resp.Duration > a.MaxLatency {
+		failures = append(failures, fmt.Sprintf("latency: %s exceeds %s", resp.Duration, a.MaxLatency))
+	}
+
+	if len(a.JSON) > 0 || a.Schema {
+		var body interface{}
+		if err := json.Unmarshal([]byte(resp.Body), &body); err != nil {
+			return append(failures, fmt.Sprintf("body is not JSON: %v", err))
+		}
+		for _, ja := range a.JSON {
+			failures = append(failures, ja.check(body)...)
+		}
+		if a.Schema {
+			if msg := checkSchema(doc, req, resp.StatusCode, body); msg != "" {
+				failures = append(failures, msg)
+			}
+		}
+	}
+
+	return failures
+}
+
+func (ja JSONAssertion) check(body interface{}) []string {
+	values, err := query.Eval(ja.Path, body)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %v", ja.Path, err)}
+	}
+	if !strings.HasPrefix(strings.TrimSpace(ja.Path), "$") {
+		// jq yields null for missing members, so null counts as no value
+		values = nonNull(values)
+	}
+
+	if ja.Exists != nil {
+		if *ja.Exists && len(values) == 0 {
+			return []string{fmt.Sprintf("%s: expected a value, found none", ja.Path)}
+		}
+		if !*ja.Exists && len(values) > 0 {
+			return []string{fmt.Sprintf("%s: expected no value, found %d", ja.Path, len(values))}
+		}
+	}
+	if ja.Equals == nil && ja.Matches == "" {
+		return nil
+	}
+	if len(values) == 0 {
+		return []string{fmt.Sprintf("%s: no value found", ja.Path)}
+	}
+
+	var failures []string
+	if ja.Equals != nil {
+		want := normalize(ja.Equals)
+		for _, v := range values {
+			if !reflect.DeepEqual(v, want) {
+				failures = append(failures, fmt.Sprintf("%s: expected %s, got %s", ja.Path, toJSON(want), toJSON(v)))
+			}
+		}
+	}
+	if ja.Matches != "" {
+		re, err := regexp.Compile(ja.Matches)
+		if err != nil {
+			return append(failures, fmt.Sprintf("%s: invalid regex %q: %v", ja.Path, ja.Matches, err))
+		}
+		for _, v := range values {
+			if s := scalarString(v); !re.MatchString(s) {
+				failures = append(failures, fmt.Sprintf("%s: %q does not match %q", ja.Path, s, ja.Matches))
+			}
+		}
+	}
+	return failures
+}
+
+func nonNull(values []interface{}) []interface{} {
+	var out []interface{}
+	for _, v := range values {
+		if v != nil {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func checkSchema(doc *openapi3.T, req *Request, status int, body interface{}) string {
+	if doc == nil {
+		return "schema: no spec loaded for collection"
+	}
+	op, _ := parser.FindOperation(doc, req.Method, req.Path)
+	if op == nil {
+		return fmt.Sprintf("schema: no operation %s %s in spec", req.Method, req.Path)
+	}
+	schema := parser.ResponseSchema(op, status)
+	if schema == nil {
+		return fmt.Sprintf("schema: no JSON schema documented for status %d", status)
+	}
+	if err := schema.VisitJSON(body); err != nil {
+		return fmt.Sprintf("schema: %v", err)
+	}
+	return ""
+}
+
+// normalize round-trips v through JSON so YAML-decoded values compare equal
+// to JSON-decoded ones
+func normalize(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+func scalarString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		return toJSON(v)
+	}
+}
+
+func toJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"org.subh/api-term/pkgs/api/model"
+)
+
+// Collection is an ordered set of saved requests with their assertions
+type Collection struct {
+	Name     string            `yaml:"name" json:"name"`
+	BaseURL  string            `yaml:"baseURL" json:"baseURL"`
+	Spec     string            `yaml:"spec,omitempty" json:"spec,omitempty"`
+	Headers  map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Parallel bool              `yaml:"parallel,omitempty" json:"parallel,omitempty"`
+	Requests []*Request        `yaml:"requests" json:"requests"`
+}
+
+// Request is a single saved request
+type Request struct {
+	Name        string            `yaml:"name" json:"name"`
+	Method      string            `yaml:"method" json:"method"`
+	Path        string            `yaml:"path" json:"path"`
+	Params      map[string]string `yaml:"params,omitempty" json:"params,omitempty"`
+	Query       map[string]string `yaml:"query,omitempty" json:"query,omitempty"`
+	Headers     map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Body        string            `yaml:"body,omitempty" json:"body,omitempty"`
+	ContentType string            `yaml:"contentType,omitempty" json:"contentType,omitempty"`
+	Assert      *Assertions       `yaml:"assert,omitempty" json:"assert,omitempty"`
+}
+
+// Assertions describes what a response must look like for a request to pass
+type Assertions struct {
+	Status     int               `yaml:"status,omitempty" json:"status,omitempty"`
+	Headers    map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	JSON       []JSONAssertion   `yaml:"json,omitempty" json:"json,omitempty"`
+	Schema     bool              `yaml:"schema,omitempty" json:"schema,omitempty"`
+	MaxLatency time.Duration     `yaml:"maxLatency,omitempty" json:"maxLatency,omitempty"`
+}
+
+// JSONAssertion checks the values selected by a JSONPath or jq expression
+type JSONAssertion struct {
+	Path    string      `yaml:"path" json:"path"`
+	Equals  interface{} `yaml:"equals,omitempty" json:"equals,omitempty"`
+	Matches string      `yaml:"matches,omitempty" json:"matches,omitempty"`
+	Exists  *bool       `yaml:"exists,omitempty" json:"exists,omitempty"`
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// LoadCollection reads a collection from a YAML (or JSON) file
+func LoadCollection(path string) (*Collection, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCollection(data)
+}
+
+// ParseCollection decodes a collection and checks that every request is runnable
+func ParseCollection(data []byte) (*Collection, error) {
+	var c Collection
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid collection: %v", err)
+	}
+	for i, r := range c.Requests {
+		if r.Method == "" || r.Path == "" {
+			return nil, fmt.Errorf("request %d (%s): method and path are required", i+1, r.Name)
+		}
+		if r.Name == "" {
+			r.Name = r.Method + " " + r.Path
+		}
+	}
+	return &c, nil
+}
+
+// Endpoint builds the model endpoint for the request, treating every
+// {placeholder} in the path as a required path parameter
+func (r *Request) Endpoint() *model.Endpoint {
+	ep := &model.Endpoint{Method: r.Method, Path: r.Path}
+	for _, m := range pathParamPattern.FindAllStringSubmatch(r.Path, -1) {
+		ep.Parameters = append(ep.Parameters, &model.Parameter{
+			Name:     m[1],
+			In:       "path",
+			Required: true,
+		})
+	}
+	return ep
+}
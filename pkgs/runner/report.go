@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteReport renders the report in the given format: "tty", "junit" or "json"
+func WriteReport(w io.Writer, format string, report *Report) error {
+	switch format {
+	case "", "tty":
+		return WriteTTY(w, report)
+	case "junit":
+		return WriteJUnit(w, report)
+	case "json":
+		return WriteJSON(w, report)
+	default:
+		return fmt.Errorf("unknown report format: %s", format)
+	}
+}
+
+// WriteTTY renders a colored, human readable summary
+func WriteTTY(w io.Writer, report *Report) error {
+	for _, res := range report.Results {
+		mark := "\033[32mPASS\033[0m"
+		if !res.Passed() {
+			mark = "\033[31mFAIL\033[0m"
+		}
+		fmt.Fprintf(w, "%s %s (%s %s) [%d] %s\n", mark, res.Name, res.Method, res.Path, res.Status, res.Duration.Round(time.Millisecond))
+		if res.Error != "" {
+			fmt.Fprintf(w, "     error: %s\n", res.Error)
+		}
+		for _, f := range res.Failures {
+			fmt.Fprintf(w, "     - %s\n", f)
+		}
+	}
+	passed := len(report.Results) - report.Failed()
+	_, err := fmt.Fprintf(w, "\n%d passed, %d failed in %s\n", passed, report.Failed(), report.Duration.Round(time.Millisecond))
+	return err
+}
+
+// WriteJSON renders the report as indented JSON
+func WriteJSON(w io.Writer, report *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit renders the report as JUnit XML for CI systems
+func WriteJUnit(w io.Writer, report *Report) error {
+	suite := junitSuite{
+		Name: report.Collection,
+		Time: fmt.Sprintf("%.3f", report.Duration.Seconds()),
+	}
+	for _, res := range report.Results {
+		tc := junitCase{
+			Name:      res.Name,
+			Classname: res.Method + " " + res.Path,
+			Time:      fmt.Sprintf("%.3f", res.Duration.Seconds()),
+		}
+		if res.Error != "" {
+			tc.Error = &junitMessage{Message: res.Error, Text: res.Error}
+			suite.Errors++
+		} else if len(res.Failures) > 0 {
+			tc.Failure = &junitMessage{
+				Message: fmt.Sprintf("%d assertion(s) failed", len(res.Failures)),
+				Text:    strings.Join(res.Failures, "\n"),
+			}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
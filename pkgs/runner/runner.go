@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"sync"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"org.subh/api-term/pkgs/api/client"
+)
+
+// Result is the outcome of a single request in a collection run
+type Result struct {
+	Name     string        `json:"name"`
+	Method   string        `json:"method"`
+	Path     string        `json:"path"`
+	Status   int           `json:"status"`
+	Duration time.Duration `json:"durationNs"`
+	Failures []string      `json:"failures,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Passed reports whether the request completed and every assertion held
+func (r *Result) Passed() bool {
+	return r.Error == "" && len(r.Failures) == 0
+}
+
+// Report is the outcome of a whole collection run
+type Report struct {
+	Collection string        `json:"collection"`
+	Results    []*Result     `json:"results"`
+	Duration   time.Duration `json:"durationNs"`
+}
+
+// Failed returns the number of requests that did not pass
+func (r *Report) Failed() int {
+	failed := 0
+	for _, res := range r.Results {
+		if !res.Passed() {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Runner executes a collection against a live server
+type Runner struct {
+	Collection *Collection
+	// Doc is the OpenAPI document used for schema assertions; may be nil
+	Doc      *openapi3.T
+	Parallel bool
+}
+
+// NewRunner creates a Runner for the collection
+func NewRunner(c *Collection, doc *openapi3.T) *Runner {
+	return &Runner{
+		Collection: c,
+		Doc:        doc,
+		Parallel:   c.Parallel,
+	}
+}
+
+// Run executes every request, in order or in parallel, and evaluates its assertions
+func (r *Runner) Run() *Report {
+	report := &Report{
+		Collection: r.Collection.Name,
+		Results:    make([]*Result, len(r.Collection.Requests)),
+	}
+	start := time.Now()
+
+	if r.Parallel {
+		var wg sync.WaitGroup
+		for i, req := range r.Collection.Requests {
+			wg.Add(1)
+			go func(i int, req *Request) {
+				defer wg.Done()
+				report.Results[i] = r.runRequest(req)
+			}(i, req)
+		}
+		wg.Wait()
+	} else {
+		for i, req := range r.Collection.Requests {
+			report.Results[i] = r.runRequest(req)
+		}
+	}
+
+	report.Duration = time.Since(start)
+	return report
+}
+
+func (r *Runner) runRequest(req *Request) *Result {
+	result := &Result{Name: req.Name, Method: req.Method, Path: req.Path}
+
+	inputValues := map[string]string{}
+	for k, v := range req.Params {
+		inputValues[k] = v
+	}
+	for k, v := range req.Query {
+		inputValues[k] = v
+	}
+	headerValues := map[string]string{}
+	for k, v := range r.Collection.Headers {
+		headerValues[k] = v
+	}
+	for k, v := range req.Headers {
+		headerValues[k] = v
+	}
+
+	resp, err := client.Invoke(r.Collection.BaseURL, req.Endpoint(), inputValues, headerValues, req.Body, req.ContentType)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Status = resp.StatusCode
+	result.Duration = resp.Duration
+
+	if req.Assert != nil {
+		result.Failures = req.Assert.Check(resp, r.Doc, req)
+	}
+	return result
+}
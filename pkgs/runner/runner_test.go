@@ -0,0 +1,159 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const testSpec = `openapi: 3.0.0
+info:
+  title: Sample API
+  version: 0.1.9
+paths:
+  /models/{model_id}:
+    get:
+      parameters:
+        - name: model_id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        '200':
+          description: A model
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id, name]
+                properties:
+                  id:
+                    type: string
+                  name:
+                    type: string
+`
+
+func newTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/models/m1":
+			fmt.Fprint(w, `{"id":"m1","name":"Model1","tags":["a","b"]}`)
+		case "/models/m2":
+			fmt.Fprint(w, `{"id":"m2"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"not found"}`)
+		}
+	}))
+}
+
+func TestRunner_Assertions(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	collection, err := ParseCollection([]byte(`
+name: smoke
+requests:
+  - name: get m1
+    method: GET
+    path: /models/{model_id}
+    params:
+      model_id: m1
+    assert:
+      status: 200
+      headers:
+        Content-Type: application/json
+      json:
+        - path: $.name
+          equals: Model1
+        - path: $.tags[*]
+          matches: "^[a-z]$"
+        - path: $.missing
+          exists: false
+        - path: .tags | length
+          equals: 2
+        - path: .missing
+          exists: false
+      schema: true
+      maxLatency: 5s
+  - name: get m2
+    method: GET
+    path: /models/{model_id}
+    params:
+      model_id: m2
+    assert:
+      status: 200
+      schema: true
+  - name: missing
+    method: GET
+    path: /nope
+    assert:
+      status: 200
+`))
+	if err != nil {
+		t.Fatalf("ParseCollection: %v", err)
+	}
+	collection.BaseURL = ts.URL
+
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(testSpec))
+	if err != nil {
+		t.Fatalf("load spec: %v", err)
+	}
+
+	for _, parallel := range []bool{false, true} {
+		r := NewRunner(collection, doc)
+		r.Parallel = parallel
+		report := r.Run()
+
+		if len(report.Results) != 3 {
+			t.Fatalf("expected 3 results, got %d", len(report.Results))
+		}
+		if !report.Results[0].Passed() {
+			t.Errorf("expected %q to pass, failures: %v", report.Results[0].Name, report.Results[0].Failures)
+		}
+		if report.Results[1].Passed() || !strings.Contains(strings.Join(report.Results[1].Failures, "\n"), "schema") {
+			t.Errorf("expected schema failure for %q, got %v", report.Results[1].Name, report.Results[1].Failures)
+		}
+		if report.Results[2].Passed() || report.Results[2].Status != http.StatusNotFound {
+			t.Errorf("expected status failure for %q, got %v", report.Results[2].Name, report.Results[2].Failures)
+		}
+		if report.Failed() != 2 {
+			t.Errorf("expected 2 failures, got %d", report.Failed())
+		}
+	}
+}
+
+func TestWriteReport_Formats(t *testing.T) {
+	report := &Report{
+		Collection: "smoke",
+		Results: []*Result{
+			{Name: "ok", Method: "GET", Path: "/a", Status: 200},
+			{Name: "bad", Method: "GET", Path: "/b", Status: 500, Failures: []string{"status: expected 200, got 500"}},
+		},
+	}
+
+	for format, want := range map[string]string{
+		"tty":   "1 passed, 1 failed",
+		"junit": `<testsuite name="smoke" tests="2" failures="1"`,
+		"json":  `"collection": "smoke"`,
+	} {
+		var buf bytes.Buffer
+		if err := WriteReport(&buf, format, report); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%s report missing %q:\n%s", format, want, buf.String())
+		}
+	}
+
+	if err := WriteReport(&bytes.Buffer{}, "html", report); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
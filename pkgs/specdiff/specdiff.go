@@ -0,0 +1,401 @@
+package specdiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Kind says whether an element was added, removed or changed
+type Kind string
+
+const (
+	Added   Kind = "added"
+	Removed Kind = "removed"
+	Changed Kind = "changed"
+)
+
+// maxDepth bounds recursion through self-referencing schemas
+const maxDepth = 10
+
+// Change is a single difference between two spec versions
+type Change struct {
+	Kind Kind `json:"kind"`
+	// Operation is "METHOD /path" for operation level changes, empty for components
+	Operation string `json:"operation,omitempty"`
+	// Location names the changed element, e.g. "parameter query limit" or "response 200 body.items[].id"
+	Location string `json:"location"`
+	Message  string `json:"message"`
+	Breaking bool   `json:"breaking"`
+}
+
+func (c Change) String() string {
+	tag := "non-breaking"
+	if c.Breaking {
+		tag = "BREAKING"
+	}
+	target := c.Location
+	if c.Operation != "" {
+		target = strings.TrimSpace(c.Operation + " " + c.Location)
+	}
+	return fmt.Sprintf("[%s] %s %s: %s", tag, c.Kind, target, c.Message)
+}
+
+// Result is the full list of changes between two spec versions
+type Result struct {
+	Changes []Change `json:"changes"`
+}
+
+// Breaking returns the number of breaking changes
+func (r *Result) Breaking() int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Breaking {
+			n++
+		}
+	}
+	return n
+}
+
+// OperationStatus summarizes the changes of each operation in the new spec:
+// "added", "breaking" or "changed". Unchanged operations are absent.
+func (r *Result) OperationStatus() map[string]string {
+	status := map[string]string{}
+	for _, c := range r.Changes {
+		if c.Operation == "" {
+			continue
+		}
+		switch {
+		case c.Kind == Added && c.Location == "":
+			status[c.Operation] = "added"
+		case c.Breaking:
+			status[c.Operation] = "breaking"
+		case status[c.Operation] == "":
+			status[c.Operation] = "changed"
+		}
+	}
+	return status
+}
+
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(kind Kind, op, location string, breaking bool, format string, args ...interface{}) {
+	d.changes = append(d.changes, Change{
+		Kind:      kind,
+		Operation: op,
+		Location:  location,
+		Message:   fmt.Sprintf(format, args...),
+		Breaking:  breaking,
+	})
+}
+
+// Compare lists the operation, parameter and schema changes from oldDoc to newDoc
+func Compare(oldDoc, newDoc *openapi3.T) *Result {
+	d := &differ{}
+	oldOps, newOps := operations(oldDoc), operations(newDoc)
+
+	for _, key := range sortedKeys(oldOps, newOps) {
+		oldOp, inOld := oldOps[key]
+		newOp, inNew := newOps[key]
+		switch {
+		case !inNew:
+			d.add(Removed, key, "", true, "operation removed")
+		case !inOld:
+			d.add(Added, key, "", false, "operation added")
+		default:
+			d.compareOperation(key, oldOp, newOp)
+		}
+	}
+
+	oldSchemas, newSchemas := componentSchemas(oldDoc), componentSchemas(newDoc)
+	for _, name := range sortedKeys(oldSchemas, newSchemas) {
+		oldS, inOld := oldSchemas[name]
+		newS, inNew := newSchemas[name]
+		location := "schema " + name
+		switch {
+		case !inNew:
+			// clients may still reference it, e.g. through generated types
+			d.add(Removed, "", location, true, "component schema removed")
+		case !inOld:
+			d.add(Added, "", location, false, "component schema added")
+		default:
+			// component schemas may be used on both sides, so judge them as responses
+			d.compareSchema("", location, oldS, newS, false, 0)
+		}
+	}
+
+	return &Result{Changes: d.changes}
+}
+
+func (d *differ) compareOperation(key string, oldOp, newOp operation) {
+	oldParams, newParams := parameters(oldOp), parameters(newOp)
+	for _, id := range sortedKeys(oldParams, newParams) {
+		oldP, inOld := oldParams[id]
+		newP, inNew := newParams[id]
+		location := "parameter " + id
+		switch {
+		case !inNew:
+			d.add(Removed, key, location, true, "parameter removed")
+		case !inOld:
+			if newP.Required {
+				d.add(Added, key, location, true, "required parameter added")
+			} else {
+				d.add(Added, key, location, false, "optional parameter added")
+			}
+		default:
+			if !oldP.Required && newP.Required {
+				d.add(Changed, key, location, true, "parameter became required")
+			} else if oldP.Required && !newP.Required {
+				d.add(Changed, key, location, false, "parameter became optional")
+			}
+			if oldP.Schema != nil && newP.Schema != nil {
+				d.compareSchema(key, location, oldP.Schema.Value, newP.Schema.Value, true, 0)
+			}
+		}
+	}
+
+	oldBody, newBody := requestSchema(oldOp), requestSchema(newOp)
+	oldRequired := oldOp.RequestBody != nil && oldOp.RequestBody.Value != nil && oldOp.RequestBody.Value.Required
+	newRequired := newOp.RequestBody != nil && newOp.RequestBody.Value != nil && newOp.RequestBody.Value.Required
+	switch {
+	case oldOp.RequestBody == nil && newOp.RequestBody != nil:
+		d.add(Added, key, "request body", newRequired, "request body added")
+	case oldOp.RequestBody != nil && newOp.RequestBody == nil:
+		d.add(Removed, key, "request body", false, "request body removed")
+	default:
+		if !oldRequired && newRequired {
+			d.add(Changed, key, "request body", true, "request body became required")
+		}
+		if oldBody != nil && newBody != nil {
+			d.compareSchema(key, "request body", oldBody, newBody, true, 0)
+		}
+	}
+
+	oldResp, newResp := responses(oldOp), responses(newOp)
+	for _, code := range sortedKeys(oldResp, newResp) {
+		oldR, inOld := oldResp[code]
+		newR, inNew := newResp[code]
+		location := "response " + code
+		switch {
+		case !inNew:
+			d.add(Removed, key, location, true, "response removed")
+		case !inOld:
+			d.add(Added, key, location, false, "response added")
+		default:
+			oldS, newS := jsonSchema(oldR), jsonSchema(newR)
+			if oldS != nil && newS != nil {
+				d.compareSchema(key, location+" body", oldS, newS, false, 0)
+			} else if oldS != nil {
+				d.add(Removed, key, location+" body", true, "JSON body removed")
+			} else if newS != nil {
+				d.add(Added, key, location+" body", false, "JSON body added")
+			}
+		}
+	}
+}
+
+// compareSchema diffs two schemas. In requests, new constraints break clients;
+// in responses, removed fields and changed types do.
+func (d *differ) compareSchema(op, location string, oldS, newS *openapi3.Schema, request bool, depth int) {
+	if oldS == nil || newS == nil || oldS == newS || depth > maxDepth {
+		return
+	}
+
+	oldTypes, newTypes := strings.Join(oldS.Type.Slice(), "|"), strings.Join(newS.Type.Slice(), "|")
+	if oldTypes != newTypes {
+		d.add(Changed, op, location, true, "type changed from %q to %q", oldTypes, newTypes)
+		return
+	}
+	if oldS.Format != newS.Format {
+		d.add(Changed, op, location, true, "format changed from %q to %q", oldS.Format, newS.Format)
+	}
+	if len(oldS.Enum) > 0 || len(newS.Enum) > 0 {
+		removed, added := enumDiff(oldS.Enum, newS.Enum)
+		if len(removed) > 0 {
+			// a request value that was valid is now rejected
+			d.add(Changed, op, location, request, "enum values removed: %s", strings.Join(removed, ", "))
+		}
+		if len(added) > 0 {
+			// a response may now carry values clients don't know
+			d.add(Changed, op, location, !request, "enum values added: %s", strings.Join(added, ", "))
+		}
+	}
+
+	oldReq, newReq := toSet(oldS.Required), toSet(newS.Required)
+	for _, name := range sortedKeys(oldS.Properties, newS.Properties) {
+		oldP, inOld := oldS.Properties[name]
+		newP, inNew := newS.Properties[name]
+		propLocation := location + "." + name
+		switch {
+		case !inNew:
+			d.add(Removed, op, propLocation, !request, "property removed")
+		case !inOld:
+			d.add(Added, op, propLocation, request && newReq[name], "property added")
+		default:
+			if request && !oldReq[name] && newReq[name] {
+				d.add(Changed, op, propLocation, true, "property became required")
+			}
+			if !request && oldReq[name] && !newReq[name] {
+				d.add(Changed, op, propLocation, true, "property is no longer guaranteed")
+			}
+			if oldP != nil && newP != nil {
+				d.compareSchema(op, propLocation, oldP.Value, newP.Value, request, depth+1)
+			}
+		}
+	}
+
+	if oldS.Items != nil && newS.Items != nil {
+		d.compareSchema(op, location+"[]", oldS.Items.Value, newS.Items.Value, request, depth+1)
+	}
+
+	// allOf members all apply, so one more constrains requests and one less
+	// guarantees less in responses; oneOf and anyOf alternatives are the
+	// other way round, like enum values
+	d.compareComposition(op, location, "allOf", "member", oldS.AllOf, newS.AllOf, request, request, !request, depth)
+	d.compareComposition(op, location, "oneOf", "alternative", oldS.OneOf, newS.OneOf, request, !request, request, depth)
+	d.compareComposition(op, location, "anyOf", "alternative", oldS.AnyOf, newS.AnyOf, request, !request, request, depth)
+}
+
+// compareComposition diffs the schemas listed under keyword by position,
+// reporting added and removed ones as breaking according to addBreaks and
+// removeBreaks
+func (d *differ) compareComposition(op, location, keyword, noun string, oldRefs, newRefs openapi3.SchemaRefs, request, addBreaks, removeBreaks bool, depth int) {
+	for i := 0; i < len(oldRefs) || i < len(newRefs); i++ {
+		itemLocation := fmt.Sprintf("%s.%s[%d]", location, keyword, i)
+		switch {
+		case i >= len(newRefs):
+			d.add(Removed, op, itemLocation, removeBreaks, "%s %s removed", keyword, noun)
+		case i >= len(oldRefs):
+			d.add(Added, op, itemLocation, addBreaks, "%s %s added", keyword, noun)
+		case oldRefs[i] != nil && newRefs[i] != nil:
+			d.compareSchema(op, itemLocation, oldRefs[i].Value, newRefs[i].Value, request, depth+1)
+		}
+	}
+}
+
+// operation is an operation with the parameters declared on its path item,
+// which apply to every operation of the path
+type operation struct {
+	*openapi3.Operation
+	pathParams openapi3.Parameters
+}
+
+func operations(doc *openapi3.T) map[string]operation {
+	ops := map[string]operation{}
+	if doc == nil || doc.Paths == nil {
+		return ops
+	}
+	for path, item := range doc.Paths.Map() {
+		for method, op := range item.Operations() {
+			ops[strings.ToUpper(method)+" "+path] = operation{Operation: op, pathParams: item.Parameters}
+		}
+	}
+	return ops
+}
+
+// parameters returns the parameters of op by location and name; those of
+// the operation override those of the path item
+func parameters(op operation) map[string]*openapi3.Parameter {
+	params := map[string]*openapi3.Parameter{}
+	for _, refs := range []openapi3.Parameters{op.pathParams, op.Parameters} {
+		for _, ref := range refs {
+			if ref != nil && ref.Value != nil {
+				params[ref.Value.In+" "+ref.Value.Name] = ref.Value
+			}
+		}
+	}
+	return params
+}
+
+func responses(op operation) map[string]*openapi3.Response {
+	out := map[string]*openapi3.Response{}
+	if op.Responses == nil {
+		return out
+	}
+	for code, ref := range op.Responses.Map() {
+		if ref != nil && ref.Value != nil {
+			out[code] = ref.Value
+		}
+	}
+	return out
+}
+
+func requestSchema(op operation) *openapi3.Schema {
+	if op.RequestBody == nil || op.RequestBody.Value == nil {
+		return nil
+	}
+	for mediaType, media := range op.RequestBody.Value.Content {
+		if strings.Contains(mediaType, "json") && media.Schema != nil {
+			return media.Schema.Value
+		}
+	}
+	return nil
+}
+
+func jsonSchema(resp *openapi3.Response) *openapi3.Schema {
+	for mediaType, media := range resp.Content {
+		if strings.Contains(mediaType, "json") && media.Schema != nil {
+			return media.Schema.Value
+		}
+	}
+	return nil
+}
+
+func componentSchemas(doc *openapi3.T) map[string]*openapi3.Schema {
+	out := map[string]*openapi3.Schema{}
+	if doc == nil || doc.Components == nil {
+		return out
+	}
+	for name, ref := range doc.Components.Schemas {
+		if ref != nil && ref.Value != nil {
+			out[name] = ref.Value
+		}
+	}
+	return out
+}
+
+func enumDiff(oldEnum, newEnum []interface{}) (removed, added []string) {
+	oldSet, newSet := map[string]bool{}, map[string]bool{}
+	for _, v := range oldEnum {
+		oldSet[fmt.Sprint(v)] = true
+	}
+	for _, v := range newEnum {
+		newSet[fmt.Sprint(v)] = true
+	}
+	for _, v := range sortedKeys(oldSet, newSet) {
+		if !newSet[v] {
+			removed = append(removed, v)
+		} else if !oldSet[v] {
+			added = append(added, v)
+		}
+	}
+	return removed, added
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// sortedKeys returns the union of the keys of a and b in order
+func sortedKeys[V any](a, b map[string]V) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, m := range []map[string]V{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,230 @@
+package specdiff
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const oldSpec = `openapi: 3.0.0
+info:
+  title: Sample API
+  version: "1"
+paths:
+  /models:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+      responses:
+        '200':
+          description: Models
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    id:
+                      type: string
+                    name:
+                      type: string
+        '404':
+          description: Not found
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name:
+                  type: string
+      responses:
+        '201':
+          description: Created
+  /legacy:
+    get:
+      responses:
+        '200':
+          description: OK
+components:
+  schemas:
+    Legacy:
+      type: object
+`
+
+const newSpec = `openapi: 3.0.0
+info:
+  title: Sample API
+  version: "2"
+paths:
+  /models:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: string
+        - name: cursor
+          in: query
+          schema:
+            type: string
+      responses:
+        '200':
+          description: Models
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    id:
+                      type: string
+                    status:
+                      type: string
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [type]
+              properties:
+                name:
+                  type: string
+                type:
+                  type: string
+      responses:
+        '201':
+          description: Created
+  /models/{model_id}:
+    get:
+      parameters:
+        - name: model_id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        '200':
+          description: OK
+`
+
+func load(t *testing.T, spec string) *openapi3.T {
+	t.Helper()
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+	if err != nil {
+		t.Fatalf("load spec: %v", err)
+	}
+	return doc
+}
+
+func TestCompare(t *testing.T) {
+	result := Compare(load(t, oldSpec), load(t, newSpec))
+
+	type key struct {
+		op, location string
+	}
+	got := map[key]Change{}
+	for _, c := range result.Changes {
+		got[key{c.Operation, c.Location}] = c
+	}
+
+	for _, want := range []Change{
+		{Kind: Removed, Operation: "GET /legacy", Breaking: true},
+		{Kind: Added, Operation: "GET /models/{model_id}", Breaking: false},
+		{Kind: Changed, Operation: "GET /models", Location: "parameter query limit", Breaking: true},
+		{Kind: Added, Operation: "GET /models", Location: "parameter query cursor", Breaking: false},
+		{Kind: Removed, Operation: "GET /models", Location: "response 200 body[].name", Breaking: true},
+		{Kind: Added, Operation: "GET /models", Location: "response 200 body[].status", Breaking: false},
+		{Kind: Removed, Operation: "GET /models", Location: "response 404", Breaking: true},
+		{Kind: Added, Operation: "POST /models", Location: "request body.type", Breaking: true},
+		{Kind: Removed, Location: "schema Legacy", Breaking: true},
+	} {
+		c, ok := got[key{want.Operation, want.Location}]
+		if !ok {
+			t.Errorf("missing change %s %q", want.Operation, want.Location)
+			continue
+		}
+		if c.Kind != want.Kind || c.Breaking != want.Breaking {
+			t.Errorf("%s %q: expected %s breaking=%v, got %s breaking=%v", want.Operation, want.Location, want.Kind, want.Breaking, c.Kind, c.Breaking)
+		}
+	}
+	if len(result.Changes) != 9 {
+		t.Errorf("expected 9 changes, got %d: %v", len(result.Changes), result.Changes)
+	}
+
+	status := result.OperationStatus()
+	if status["GET /models/{model_id}"] != "added" || status["GET /models"] != "breaking" || status["POST /models"] != "breaking" {
+		t.Errorf("unexpected operation status: %v", status)
+	}
+}
+
+func TestComparePathParametersAndComposition(t *testing.T) {
+	oldDoc := load(t, `openapi: 3.0.0
+info: {title: pets, version: "1"}
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: Pets
+          content:
+            application/json:
+              schema:
+                allOf:
+                  - type: object
+                    properties:
+                      id: {type: string}
+                      name: {type: string}
+                oneOf:
+                  - {type: object, properties: {bark: {type: boolean}}}
+`)
+	newDoc := load(t, `openapi: 3.0.0
+info: {title: pets, version: "2"}
+paths:
+  /pets:
+    parameters:
+      - {name: X-Tenant, in: header, required: true, schema: {type: string}}
+    get:
+      responses:
+        '200':
+          description: Pets
+          content:
+            application/json:
+              schema:
+                allOf:
+                  - type: object
+                    properties:
+                      id: {type: string}
+                oneOf:
+                  - {type: object, properties: {bark: {type: boolean}}}
+                  - {type: object, properties: {meow: {type: boolean}}}
+`)
+	got := map[string]Change{}
+	for _, c := range Compare(oldDoc, newDoc).Changes {
+		got[c.Location] = c
+	}
+	for _, want := range []Change{
+		{Kind: Added, Location: "parameter header X-Tenant", Breaking: true},
+		{Kind: Removed, Location: "response 200 body.allOf[0].name", Breaking: true},
+		{Kind: Added, Location: "response 200 body.oneOf[1]", Breaking: true},
+	} {
+		c, ok := got[want.Location]
+		if !ok {
+			t.Errorf("missing change %q in %v", want.Location, got)
+			continue
+		}
+		if c.Kind != want.Kind || c.Breaking != want.Breaking || c.Operation != "GET /pets" {
+			t.Errorf("%q: expected %s breaking=%v, got %+v", want.Location, want.Kind, want.Breaking, c)
+		}
+	}
+	if len(got) != 3 {
+		t.Errorf("expected 3 changes, got %v", got)
+	}
+}
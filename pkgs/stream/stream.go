@@ -0,0 +1,129 @@
+// Package stream reads streaming response bodies event by event: Server-Sent
+// Events, newline-delimited JSON, or any body line by line as it arrives.
+package stream
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// Event is one server-sent event, or one line of a line-delimited stream
+type Event struct {
+	// Type is the SSE event field, or the type or event member of an NDJSON
+	// object; empty for plain lines and unnamed events
+	Type string
+	ID   string
+	Data string
+	// Retry is the reconnection time in milliseconds the server asked for
+	Retry int
+}
+
+// maxLine bounds a single line of a stream
+const maxLine = 4 << 20
+
+// IsEventStream reports whether contentType is text/event-stream
+func IsEventStream(contentType string) bool {
+	media, _, _ := mime.ParseMediaType(contentType)
+	return media == "text/event-stream"
+}
+
+// IsStream reports whether responses of contentType are meant to be read
+// as they arrive
+func IsStream(contentType string) bool {
+	media, _, _ := mime.ParseMediaType(contentType)
+	switch media {
+	case "text/event-stream", "application/x-ndjson", "application/ndjson", "application/jsonl",
+		"application/x-jsonlines", "application/json-seq", "application/stream+json":
+		return true
+	}
+	return false
+}
+
+// Read reads events from r until it ends or fails, calling emit for each.
+// Event streams are parsed as SSE; anything else yields an event per line.
+func Read(r io.Reader, contentType string, emit func(Event)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+	if IsEventStream(contentType) {
+		return readEvents(scanner, emit)
+	}
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		// json-seq records start with an RS character
+		line = strings.TrimPrefix(line, "\x1e")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		emit(Event{Type: lineType(line), Data: line})
+	}
+	return scanner.Err()
+}
+
+// readEvents parses the SSE format: field lines make up an event, which a
+// blank line dispatches, and lines starting with a colon are comments
+func readEvents(scanner *bufio.Scanner, emit func(Event)) error {
+	var ev Event
+	var data []string
+	hasData := false
+	lastID := ""
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			if hasData {
+				ev.ID = lastID
+				ev.Data = strings.Join(data, "\n")
+				emit(ev)
+			}
+			ev, data, hasData = Event{}, nil, false
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.Type = value
+		case "data":
+			data = append(data, value)
+			hasData = true
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				lastID = value
+			}
+		case "retry":
+			if n, err := strconv.Atoi(value); err == nil {
+				ev.Retry = n
+			}
+		}
+	}
+	// an event left without its blank line is dispatched when the stream ends
+	if hasData {
+		ev.ID = lastID
+		ev.Data = strings.Join(data, "\n")
+		emit(ev)
+	}
+	return scanner.Err()
+}
+
+// lineType returns the type or event member of a JSON object line
+func lineType(line string) string {
+	if !strings.HasPrefix(strings.TrimSpace(line), "{") {
+		return ""
+	}
+	var obj map[string]interface{}
+	if json.Unmarshal([]byte(line), &obj) != nil {
+		return ""
+	}
+	for _, key := range []string{"type", "event"} {
+		if s, ok := obj[key].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
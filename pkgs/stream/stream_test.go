@@ -0,0 +1,57 @@
+package stream
+
+import (
+	"strings"
+	"testing"
+)
+
+func collect(t *testing.T, body, contentType string) []Event {
+	t.Helper()
+	var events []Event
+	if err := Read(strings.NewReader(body), contentType, func(ev Event) { events = append(events, ev) }); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestEventStream(t *testing.T) {
+	body := ": keep-alive\r\n" +
+		"event: status\r\nid: 1\r\ndata: queued\r\n\r\n" +
+		"data: {\"line\":1}\ndata: {\"line\":2}\nretry: 3000\n\n" +
+		"event: done\nid: 7\ndata:\n\n" +
+		"event: ignored\n\n" +
+		"data: tail"
+	events := collect(t, body, "text/event-stream; charset=utf-8")
+	want := []Event{
+		{Type: "status", ID: "1", Data: "queued"},
+		{ID: "1", Data: "{\"line\":1}\n{\"line\":2}", Retry: 3000},
+		{Type: "done", ID: "7", Data: ""},
+		{ID: "7", Data: "tail"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	body := `{"type":"progress","pct":50}` + "\n\n" + `{"event":"done"}` + "\r\n" + "plain text\n" + "\x1e[1,2]\n"
+	events := collect(t, body, "application/x-ndjson")
+	if len(events) != 4 {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if events[0].Type != "progress" || events[1].Type != "done" || events[1].Data != `{"event":"done"}` {
+		t.Errorf("unexpected NDJSON events %+v", events[:2])
+	}
+	if events[2].Type != "" || events[2].Data != "plain text" || events[3].Data != "[1,2]" {
+		t.Errorf("unexpected line events %+v", events[2:])
+	}
+
+	if !IsStream("application/x-ndjson") || !IsStream("text/event-stream;charset=utf-8") || IsStream("application/json") {
+		t.Error("unexpected stream content types")
+	}
+}
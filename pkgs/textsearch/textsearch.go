@@ -0,0 +1,78 @@
+// Package textsearch finds and highlights regex matches in rows of termui
+// text, which may already carry [text](fg:color) style markup.
+package textsearch
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// HighlightStyle is the termui style applied to every match
+const HighlightStyle = "fg:black,bg:yellow"
+
+// Match is one occurrence of the pattern. Start and End are byte offsets in
+// the row with its markup stripped.
+type Match struct {
+	Row, Start, End int
+}
+
+var markup = regexp.MustCompile(`\[([^\[\]]*)\]\(((?:fg|bg|mod):[a-z]+(?:,(?:fg|bg|mod):[a-z]+)*)\)`)
+
+// Compile compiles pattern with smart case: it matches case-insensitively
+// unless the pattern contains an upper-case letter
+func Compile(pattern string) (*regexp.Regexp, error) {
+	if !strings.ContainsFunc(pattern, unicode.IsUpper) {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// StripMarkup removes termui style markup, keeping the styled text
+func StripMarkup(row string) string {
+	return markup.ReplaceAllString(row, "$1")
+}
+
+// Find returns every match in rows that Highlight marks, in order
+func Find(re *regexp.Regexp, rows []string) []Match {
+	var matches []Match
+	for i, row := range rows {
+		plain := StripMarkup(row)
+		for _, loc := range re.FindAllStringIndex(plain, -1) {
+			if highlightable(plain[loc[0]:loc[1]]) {
+				matches = append(matches, Match{Row: i, Start: loc[0], End: loc[1]})
+			}
+		}
+	}
+	return matches
+}
+
+// highlightable reports whether matched text can be wrapped in markup: it
+// must not be empty or contain brackets, which termui would misread
+func highlightable(text string) bool {
+	return text != "" && !strings.ContainsAny(text, "[]()")
+}
+
+// Highlight wraps every match in row with HighlightStyle. Rows without a
+// match are returned unchanged; rows with one lose their own markup. Matched
+// text containing brackets is left plain, and Find skips it as well.
+func Highlight(re *regexp.Regexp, row string) string {
+	plain := StripMarkup(row)
+	locs := re.FindAllStringIndex(plain, -1)
+	var b strings.Builder
+	last := 0
+	for _, loc := range locs {
+		text := plain[loc[0]:loc[1]]
+		if !highlightable(text) {
+			continue
+		}
+		b.WriteString(plain[last:loc[0]])
+		b.WriteString("[" + text + "](" + HighlightStyle + ")")
+		last = loc[1]
+	}
+	if last == 0 {
+		return row
+	}
+	b.WriteString(plain[last:])
+	return b.String()
+}
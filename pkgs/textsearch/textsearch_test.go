@@ -0,0 +1,55 @@
+package textsearch
+
+import (
+	"testing"
+)
+
+func TestFindAndHighlight(t *testing.T) {
+	rows := []string{
+		"[Status: 200](fg:green)",
+		"",
+		`  "id": "u-42",`,
+		`  ▸ owner: [object · 2 keys](fg:blue)`,
+		`  "ids": ["u-1", "U-2"]`,
+	}
+
+	re, err := Compile(`u-\d`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := Find(re, rows)
+	if len(matches) != 3 || matches[0] != (Match{Row: 2, Start: 9, End: 12}) || matches[2].Row != 4 {
+		t.Errorf("unexpected matches %+v", matches)
+	}
+	if got := Highlight(re, rows[2]); got != `  "id": "[u-4](fg:black,bg:yellow)2",` {
+		t.Errorf("unexpected highlight %q", got)
+	}
+	if got := Highlight(re, rows[0]); got != rows[0] {
+		t.Errorf("row without a match changed: %q", got)
+	}
+
+	// an upper-case letter makes the search case-sensitive
+	re, _ = Compile(`U-\d`)
+	if n := len(Find(re, rows)); n != 1 {
+		t.Errorf("expected one case-sensitive match, got %d", n)
+	}
+
+	// matches inside markup are found in the styled text
+	re, _ = Compile(`object`)
+	if got := Highlight(re, rows[3]); got != "  ▸ owner: [object](fg:black,bg:yellow) · 2 keys" {
+		t.Errorf("unexpected highlight in styled row %q", got)
+	}
+	if got := StripMarkup(rows[0]); got != "Status: 200" {
+		t.Errorf("unexpected stripped row %q", got)
+	}
+
+	// matches Highlight can't mark are not counted
+	re, _ = Compile(`\["u-1`)
+	if got := Find(re, rows); len(got) != 0 || Highlight(re, rows[4]) != rows[4] {
+		t.Errorf("a match containing a bracket was counted: %+v", got)
+	}
+
+	if _, err := Compile("("); err == nil {
+		t.Error("expected an invalid pattern error")
+	}
+}
@@ -0,0 +1,211 @@
+// Package wsclient runs an interactive WebSocket session and keeps a
+// timestamped log of the frames sent and received.
+package wsclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Directions of a log entry
+const (
+	Inbound  = "in"
+	Outbound = "out"
+	// Status entries record the connection opening and closing
+	Status = "status"
+)
+
+// Message is one entry of the session log
+type Message struct {
+	Time      time.Time
+	Direction string
+	// Kind is text, binary, ping, pong, close or a status such as connected
+	Kind string
+	Data string
+}
+
+// Session is an open WebSocket connection
+type Session struct {
+	URL string
+
+	conn      *websocket.Conn
+	writeMu   sync.Mutex
+	mu        sync.Mutex
+	log       []Message
+	onMessage func(Message)
+	done      chan struct{}
+}
+
+// controlTimeout bounds writing control frames and waiting for the close
+// handshake
+const controlTimeout = 5 * time.Second
+
+// URL turns an http(s) URL into the matching ws(s) URL
+func URL(httpURL string) string {
+	switch {
+	case strings.HasPrefix(httpURL, "https://"):
+		return "wss://" + strings.TrimPrefix(httpURL, "https://")
+	case strings.HasPrefix(httpURL, "http://"):
+		return "ws://" + strings.TrimPrefix(httpURL, "http://")
+	}
+	return httpURL
+}
+
+// Dial connects to url with the given request headers. onMessage is called
+// from the session's reader for every log entry, including the ones the
+// caller sends.
+func Dial(ctx context.Context, url string, headers map[string]string, onMessage func(Message)) (*Session, error) {
+	header := http.Header{}
+	for k, v := range headers {
+		if k != "" {
+			header.Set(k, v)
+		}
+	}
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, URL(url), header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("handshake failed with status %d: %v", resp.StatusCode, err)
+		}
+		return nil, err
+	}
+	s := &Session{URL: URL(url), conn: conn, onMessage: onMessage, done: make(chan struct{})}
+	conn.SetPingHandler(func(data string) error {
+		s.add(Inbound, "ping", data)
+		err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(controlTimeout))
+		if err == websocket.ErrCloseSent {
+			return nil
+		}
+		return err
+	})
+	conn.SetPongHandler(func(data string) error {
+		s.add(Inbound, "pong", data)
+		return nil
+	})
+	conn.SetCloseHandler(func(code int, text string) error {
+		s.add(Inbound, "close", CloseText(code, text))
+		message := websocket.FormatCloseMessage(code, "")
+		conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(controlTimeout))
+		return nil
+	})
+	s.add(Status, "connected", resp.Header.Get("Sec-WebSocket-Protocol"))
+	go s.read()
+	return s, nil
+}
+
+// read logs inbound frames until the connection closes
+func (s *Session) read() {
+	defer close(s.done)
+	defer s.conn.Close()
+	for {
+		kind, data, err := s.conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				s.add(Status, "closed", CloseText(closeErr.Code, closeErr.Text))
+			} else {
+				s.add(Status, "closed", err.Error())
+			}
+			return
+		}
+		if kind == websocket.BinaryMessage {
+			s.add(Inbound, "binary", fmt.Sprintf("% x", data))
+		} else {
+			s.add(Inbound, "text", string(data))
+		}
+	}
+}
+
+func (s *Session) add(direction, kind, data string) {
+	m := Message{Time: time.Now(), Direction: direction, Kind: kind, Data: data}
+	s.mu.Lock()
+	s.log = append(s.log, m)
+	s.mu.Unlock()
+	if s.onMessage != nil {
+		s.onMessage(m)
+	}
+}
+
+// Send sends a text frame
+func (s *Session) Send(text string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.conn.WriteMessage(websocket.TextMessage, []byte(text)); err != nil {
+		return err
+	}
+	s.add(Outbound, "text", text)
+	return nil
+}
+
+// Ping sends a ping frame; the pong shows up in the log
+func (s *Session) Ping(data string) error {
+	if err := s.conn.WriteControl(websocket.PingMessage, []byte(data), time.Now().Add(controlTimeout)); err != nil {
+		return err
+	}
+	s.add(Outbound, "ping", data)
+	return nil
+}
+
+// Close starts the close handshake with code and reason and waits for the
+// server to close the connection
+func (s *Session) Close(code int, reason string) error {
+	message := websocket.FormatCloseMessage(code, reason)
+	err := s.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(controlTimeout))
+	if err != nil && err != websocket.ErrCloseSent {
+		s.conn.Close()
+		return err
+	}
+	s.add(Outbound, "close", CloseText(code, reason))
+	select {
+	case <-s.done:
+	case <-time.After(controlTimeout):
+		s.conn.Close()
+	}
+	return nil
+}
+
+// Done is closed once the connection is closed
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Log returns a copy of the session log
+func (s *Session) Log() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.log...)
+}
+
+var closeNames = map[int]string{
+	websocket.CloseNormalClosure:           "normal closure",
+	websocket.CloseGoingAway:               "going away",
+	websocket.CloseProtocolError:           "protocol error",
+	websocket.CloseUnsupportedData:         "unsupported data",
+	websocket.CloseNoStatusReceived:        "no status",
+	websocket.CloseAbnormalClosure:         "abnormal closure",
+	websocket.CloseInvalidFramePayloadData: "invalid payload",
+	websocket.ClosePolicyViolation:         "policy violation",
+	websocket.CloseMessageTooBig:           "message too big",
+	websocket.CloseMandatoryExtension:      "mandatory extension",
+	websocket.CloseInternalServerErr:       "internal error",
+	websocket.CloseServiceRestart:          "service restart",
+	websocket.CloseTryAgainLater:           "try again later",
+}
+
+// CloseText describes a close code and reason, like "1000 normal closure: bye"
+func CloseText(code int, reason string) string {
+	text := fmt.Sprint(code)
+	if name, ok := closeNames[code]; ok {
+		text += " " + name
+	}
+	if reason != "" {
+		text += ": " + reason
+	}
+	return text
+}
@@ -0,0 +1,113 @@
+package wsclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// echoServer echoes text frames and answers "close" with close code 4001
+func echoServer(t *testing.T) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		for {
+			kind, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(data) == "close" {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4001, "asked to"))
+				continue
+			}
+			conn.WriteMessage(kind, data)
+		}
+	}))
+}
+
+func waitFor(t *testing.T, s *Session, kind string) Message {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		log := s.Log()
+		for i := len(log) - 1; i >= 0; i-- {
+			if log[i].Direction != Outbound && log[i].Kind == kind {
+				return log[i]
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no %s message in %+v", kind, s.Log())
+	return Message{}
+}
+
+func TestSession(t *testing.T) {
+	server := echoServer(t)
+	defer server.Close()
+
+	if _, err := Dial(context.Background(), server.URL+"/ws", nil, nil); err == nil {
+		t.Fatal("expected the handshake to fail without credentials")
+	}
+
+	s, err := Dial(context.Background(), server.URL+"/ws", map[string]string{"Authorization": "Bearer token"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.URL[:5] != "ws://" {
+		t.Errorf("unexpected session URL %s", s.URL)
+	}
+	if err := s.Send(`{"op":"subscribe"}`); err != nil {
+		t.Fatal(err)
+	}
+	if m := waitFor(t, s, "text"); m.Direction != Inbound || m.Data != `{"op":"subscribe"}` {
+		t.Errorf("unexpected echo %+v", m)
+	}
+	if err := s.Ping("hi"); err != nil {
+		t.Fatal(err)
+	}
+	if m := waitFor(t, s, "pong"); m.Data != "hi" {
+		t.Errorf("unexpected pong %+v", m)
+	}
+	if err := s.Close(websocket.CloseNormalClosure, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-s.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("session not closed")
+	}
+	log := s.Log()
+	if last := log[len(log)-1]; last.Direction != Status || last.Kind != "closed" {
+		t.Errorf("unexpected last entry %+v", last)
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	server := echoServer(t)
+	defer server.Close()
+	s, err := Dial(context.Background(), server.URL, map[string]string{"Authorization": "Bearer token"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Send("close")
+	if m := waitFor(t, s, "close"); m.Data != "4001: asked to" {
+		t.Errorf("unexpected close %+v", m)
+	}
+	<-s.Done()
+	if got := CloseText(1001, ""); got != "1001 going away" {
+		t.Errorf("unexpected close text %q", got)
+	}
+}